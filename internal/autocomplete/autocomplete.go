@@ -0,0 +1,60 @@
+package autocomplete
+
+// names of the field, token filter and analyzers to use in index settings
+const (
+	Field          = "suggest"
+	Filter         = "suggest_edge_ngram"
+	Analyzer       = "suggest_analyzer"
+	SearchAnalyzer = "suggest_search_analyzer"
+)
+
+// FilterConfig splits each word into its prefixes, so that a partly typed
+// word matches without needing a prefix query
+func FilterConfig() map[string]interface{} {
+	return map[string]interface{}{
+		"type":     "edge_ngram",
+		"min_gram": 1,
+		"max_gram": 20,
+	}
+}
+
+func AnalyzerConfig() map[string]interface{} {
+	return map[string]interface{}{
+		"tokenizer": "whitespace",
+		"filter":    []string{"asciifolding", "lowercase", Filter},
+	}
+}
+
+func SearchAnalyzerConfig() map[string]interface{} {
+	return map[string]interface{}{
+		"tokenizer": "whitespace",
+		"filter":    []string{"asciifolding", "lowercase"},
+	}
+}
+
+func FieldConfig() map[string]interface{} {
+	return map[string]interface{}{
+		"type":            "text",
+		"analyzer":        Analyzer,
+		"search_analyzer": SearchAnalyzer,
+	}
+}
+
+// CopyTo returns a copy of a field mapping that is also copied to Field
+func CopyTo(field map[string]interface{}) map[string]interface{} {
+	result := map[string]interface{}{}
+	for k, v := range field {
+		result[k] = v
+	}
+
+	switch copyTo := field["copy_to"].(type) {
+	case string:
+		result["copy_to"] = []string{copyTo, Field}
+	case []string:
+		result["copy_to"] = append(append([]string{}, copyTo...), Field)
+	default:
+		result["copy_to"] = Field
+	}
+
+	return result
+}
@@ -0,0 +1,39 @@
+package autocomplete
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestCopyTo(t *testing.T) {
+	testCases := map[string]struct {
+		field    map[string]interface{}
+		expected map[string]interface{}
+	}{
+		"none": {
+			field:    map[string]interface{}{"type": "text"},
+			expected: map[string]interface{}{"type": "text", "copy_to": "suggest"},
+		},
+		"string": {
+			field:    map[string]interface{}{"type": "text", "copy_to": "searchable"},
+			expected: map[string]interface{}{"type": "text", "copy_to": []string{"searchable", "suggest"}},
+		},
+		"slice": {
+			field:    map[string]interface{}{"type": "text", "copy_to": []string{"searchable", "names"}},
+			expected: map[string]interface{}{"type": "text", "copy_to": []string{"searchable", "names", "suggest"}},
+		},
+	}
+
+	for name, tc := range testCases {
+		t.Run(name, func(t *testing.T) {
+			original := map[string]interface{}{}
+			for k, v := range tc.field {
+				original[k] = v
+			}
+
+			assert.Equal(t, tc.expected, CopyTo(tc.field))
+			assert.Equal(t, original, tc.field)
+		})
+	}
+}
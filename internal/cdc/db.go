@@ -0,0 +1,75 @@
+package cdc
+
+import (
+	"context"
+	"errors"
+	"time"
+
+	"github.com/jackc/pgx/v5"
+)
+
+// Channel is notified by the search_record_change trigger for each change
+const Channel = "search_changes"
+
+type Change struct {
+	ID       int64
+	Entity   string
+	EntityID int
+	Deleted  bool
+}
+
+func NewDB(conn *pgx.Conn) *DB {
+	return &DB{conn: conn}
+}
+
+// DB reads the changes recorded in the search_changes table by the triggers in
+// scripts/postgres/search_changes.sql. It needs its own connection, as
+// notifications are only delivered to the connection that is listening.
+type DB struct {
+	conn *pgx.Conn
+}
+
+func (db *DB) Listen(ctx context.Context) error {
+	_, err := db.conn.Exec(ctx, "LISTEN "+Channel)
+	return err
+}
+
+// Wait blocks until a change is notified or timeout passes, whichever is
+// first
+func (db *DB) Wait(ctx context.Context, timeout time.Duration) error {
+	waitCtx, cancel := context.WithTimeout(ctx, timeout)
+	defer cancel()
+
+	_, err := db.conn.WaitForNotification(waitCtx)
+	if errors.Is(err, context.DeadlineExceeded) && ctx.Err() == nil {
+		return nil
+	}
+
+	return err
+}
+
+// Changes returns the oldest changes that have not been done. Rows are read in
+// id order, but a transaction that commits late may add a change with a lower
+// id than one already read, which is found by the next call.
+func (db *DB) Changes(ctx context.Context, limit int) ([]Change, error) {
+	rows, err := db.conn.Query(ctx, `SELECT id, entity, entity_id, deleted
+FROM public.search_changes
+ORDER BY id
+LIMIT $1`, limit)
+	if err != nil {
+		return nil, err
+	}
+
+	return pgx.CollectRows(rows, func(row pgx.CollectableRow) (Change, error) {
+		var c Change
+		err := row.Scan(&c.ID, &c.Entity, &c.EntityID, &c.Deleted)
+		return c, err
+	})
+}
+
+// Done removes the changes that have been indexed, so that the table only holds
+// the changes still to do
+func (db *DB) Done(ctx context.Context, ids []int64) error {
+	_, err := db.conn.Exec(ctx, "DELETE FROM public.search_changes WHERE id = ANY($1)", ids)
+	return err
+}
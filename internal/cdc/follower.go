@@ -0,0 +1,200 @@
+package cdc
+
+import (
+	"context"
+	"fmt"
+	"slices"
+	"strconv"
+	"time"
+
+	"github.com/ministryofjustice/opg-search-service/internal/index"
+)
+
+type Source interface {
+	Listen(ctx context.Context) error
+	Wait(ctx context.Context, timeout time.Duration) error
+	Changes(ctx context.Context, limit int) ([]Change, error)
+	Done(ctx context.Context, ids []int64) error
+}
+
+type Indexer interface {
+	ByIDs(ctx context.Context, ids []int) (*index.Result, error)
+	Delete(ctx context.Context, ids []string) (*index.Result, error)
+}
+
+type Logger interface {
+	Printf(string, ...interface{})
+}
+
+// Follower indexes the records that have changed, in batches, marking each
+// batch as done once it has been indexed. Changes are read when they are
+// notified, and every PollInterval in case a notification was missed.
+type Follower struct {
+	log      Logger
+	source   Source
+	indexers map[string]Indexer
+	sleep    func(context.Context, time.Duration) error
+
+	BatchSize    int
+	PollInterval time.Duration
+}
+
+// NewFollower creates a Follower, indexers are keyed by the entity they index
+// as it is recorded in the search_changes table
+func NewFollower(logger Logger, source Source, indexers map[string]Indexer) *Follower {
+	return &Follower{
+		log:          logger,
+		source:       source,
+		indexers:     indexers,
+		sleep:        sleepContext,
+		BatchSize:    1000,
+		PollInterval: 30 * time.Second,
+	}
+}
+
+// Run follows the changes until ctx is cancelled. A batch that fails is
+// retried after PollInterval, as its changes are not marked as done.
+func (f *Follower) Run(ctx context.Context) error {
+	if err := f.source.Listen(ctx); err != nil {
+		return err
+	}
+
+	f.log.Printf("following changes")
+
+	for {
+		n, err := f.next(ctx)
+		if ctx.Err() != nil {
+			return nil
+		}
+
+		if err != nil {
+			f.log.Printf("indexing changes failed, retrying in %s: %s", f.PollInterval, err.Error())
+			if f.sleep(ctx, f.PollInterval) != nil {
+				return nil
+			}
+			continue
+		}
+
+		// a full batch means there are probably more changes waiting
+		if n == f.BatchSize {
+			continue
+		}
+
+		if err := f.source.Wait(ctx, f.PollInterval); err != nil {
+			if ctx.Err() != nil {
+				return nil
+			}
+			return err
+		}
+	}
+}
+
+// next indexes the oldest batch of changes, returning how many changes there
+// were
+func (f *Follower) next(ctx context.Context) (int, error) {
+	changes, err := f.source.Changes(ctx, f.BatchSize)
+	if err != nil {
+		return 0, err
+	}
+	if len(changes) == 0 {
+		return 0, nil
+	}
+
+	// the last change to a record decides whether it is indexed or deleted
+	deleted := map[string]map[int]bool{}
+	for _, change := range changes {
+		if _, ok := f.indexers[change.Entity]; !ok {
+			continue
+		}
+
+		if deleted[change.Entity] == nil {
+			deleted[change.Entity] = map[int]bool{}
+		}
+		deleted[change.Entity][change.EntityID] = change.Deleted
+	}
+
+	for entity, records := range deleted {
+		if err := f.indexChanges(ctx, entity, records); err != nil {
+			return 0, err
+		}
+	}
+
+	ids := make([]int64, len(changes))
+	for i, change := range changes {
+		ids[i] = change.ID
+	}
+
+	if err := f.source.Done(ctx, ids); err != nil {
+		return 0, err
+	}
+
+	f.log.Printf("indexed changes=%d", len(changes))
+	return len(changes), nil
+}
+
+func (f *Follower) indexChanges(ctx context.Context, entity string, records map[int]bool) error {
+	var changed []int
+	var removed []string
+	for id, isDeleted := range records {
+		if isDeleted {
+			removed = append(removed, strconv.Itoa(id))
+		} else {
+			changed = append(changed, id)
+		}
+	}
+	slices.Sort(changed)
+	slices.Sort(removed)
+
+	indexer := f.indexers[entity]
+
+	if len(changed) > 0 {
+		result, err := indexer.ByIDs(ctx, changed)
+		if err := f.check(entity, "indexing", result, err); err != nil {
+			return err
+		}
+	}
+
+	if len(removed) > 0 {
+		result, err := indexer.Delete(ctx, removed)
+		if err := f.check(entity, "deleting", result, err); err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+// check returns an error when a bulk request failed, so that the changes are
+// retried. Documents that were rejected are logged, as retrying would not
+// change the outcome.
+func (f *Follower) check(entity, action string, result *index.Result, err error) error {
+	if err != nil {
+		return fmt.Errorf("%s %s: %w", action, entity, err)
+	}
+
+	if len(result.Errors) > 0 {
+		return fmt.Errorf("%s %s: %s", action, entity, result.Errors[0])
+	}
+
+	for _, failure := range result.Failures {
+		f.log.Printf("failed %s %s id=%s status=%d %s", action, entity, failure.Id, failure.StatusCode, failure.Message)
+	}
+
+	if result.Conflicts > 0 {
+		f.log.Printf("skipped %d %s already indexed with a newer version", result.Conflicts, entity)
+	}
+
+	return nil
+}
+
+func sleepContext(ctx context.Context, d time.Duration) error {
+	timer := time.NewTimer(d)
+	defer timer.Stop()
+
+	select {
+	case <-ctx.Done():
+		return ctx.Err()
+	case <-timer.C:
+		return nil
+	}
+}
@@ -0,0 +1,176 @@
+package cdc
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/ministryofjustice/opg-search-service/internal/elasticsearch"
+	"github.com/ministryofjustice/opg-search-service/internal/index"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+)
+
+type mockLogger struct{}
+
+func (*mockLogger) Printf(s string, args ...interface{}) {}
+
+type mockSource struct {
+	mock.Mock
+}
+
+func (m *mockSource) Listen(ctx context.Context) error {
+	return m.Called(ctx).Error(0)
+}
+
+func (m *mockSource) Wait(ctx context.Context, timeout time.Duration) error {
+	return m.Called(ctx, timeout).Error(0)
+}
+
+func (m *mockSource) Changes(ctx context.Context, limit int) ([]Change, error) {
+	args := m.Called(ctx, limit)
+	changes, _ := args.Get(0).([]Change)
+	return changes, args.Error(1)
+}
+
+func (m *mockSource) Done(ctx context.Context, ids []int64) error {
+	return m.Called(ctx, ids).Error(0)
+}
+
+type mockIndexer struct {
+	mock.Mock
+}
+
+func (m *mockIndexer) ByIDs(ctx context.Context, ids []int) (*index.Result, error) {
+	args := m.Called(ctx, ids)
+	result, _ := args.Get(0).(*index.Result)
+	return result, args.Error(1)
+}
+
+func (m *mockIndexer) Delete(ctx context.Context, ids []string) (*index.Result, error) {
+	args := m.Called(ctx, ids)
+	result, _ := args.Get(0).(*index.Result)
+	return result, args.Error(1)
+}
+
+func TestFollowerNext(t *testing.T) {
+	ctx := context.Background()
+
+	source := &mockSource{}
+	source.
+		On("Changes", ctx, 1000).
+		Return([]Change{
+			{ID: 11, Entity: "person", EntityID: 3},
+			{ID: 12, Entity: "person", EntityID: 1},
+			{ID: 13, Entity: "firm", EntityID: 7, Deleted: true},
+			{ID: 14, Entity: "person", EntityID: 3},
+			{ID: 15, Entity: "person", EntityID: 5, Deleted: true},
+			{ID: 16, Entity: "unknown", EntityID: 1},
+		}, nil)
+	source.
+		On("Done", ctx, []int64{11, 12, 13, 14, 15, 16}).
+		Return(nil)
+
+	persons := &mockIndexer{}
+	persons.
+		On("ByIDs", ctx, []int{1, 3}).
+		Return(&index.Result{Successful: 2}, nil)
+	persons.
+		On("Delete", ctx, []string{"5"}).
+		Return(&index.Result{Successful: 1}, nil)
+
+	firms := &mockIndexer{}
+	firms.
+		On("Delete", ctx, []string{"7"}).
+		Return(&index.Result{Failed: 1, Failures: []elasticsearch.IndexResult{{Id: "7", StatusCode: 404, Message: "not_found"}}}, nil)
+
+	follower := NewFollower(&mockLogger{}, source, map[string]Indexer{"person": persons, "firm": firms})
+
+	n, err := follower.next(ctx)
+	assert.Nil(t, err)
+	assert.Equal(t, 6, n)
+
+	mock.AssertExpectationsForObjects(t, source, persons, firms)
+}
+
+func TestFollowerNextKeepsChangesWhenBulkRequestFails(t *testing.T) {
+	ctx := context.Background()
+
+	source := &mockSource{}
+	source.
+		On("Changes", ctx, 1000).
+		Return([]Change{{ID: 1, Entity: "person", EntityID: 1}}, nil)
+
+	persons := &mockIndexer{}
+	persons.
+		On("ByIDs", ctx, []int{1}).
+		Return(&index.Result{Errors: []string{"opensearch returned a 503"}}, nil)
+
+	follower := NewFollower(&mockLogger{}, source, map[string]Indexer{"person": persons})
+
+	_, err := follower.next(ctx)
+	assert.Equal(t, "indexing person: opensearch returned a 503", err.Error())
+	source.AssertNotCalled(t, "Done", mock.Anything, mock.Anything)
+}
+
+func TestFollowerRun(t *testing.T) {
+	ctx, cancel := context.WithCancel(context.Background())
+
+	source := &mockSource{}
+	source.
+		On("Listen", ctx).
+		Return(nil)
+	source.
+		On("Changes", ctx, 2).
+		Return(nil, errors.New("hmm")).
+		Once()
+	source.
+		On("Changes", ctx, 2).
+		Return([]Change{{ID: 1, Entity: "person", EntityID: 1}, {ID: 2, Entity: "person", EntityID: 2}}, nil).
+		Once()
+	source.
+		On("Done", ctx, []int64{1, 2}).
+		Return(nil).
+		Once()
+	source.
+		On("Changes", ctx, 2).
+		Return([]Change{}, nil).
+		Once()
+	source.
+		On("Wait", ctx, time.Minute).
+		Run(func(mock.Arguments) { cancel() }).
+		Return(context.Canceled)
+
+	persons := &mockIndexer{}
+	persons.
+		On("ByIDs", ctx, mock.Anything).
+		Return(&index.Result{Successful: 2}, nil)
+
+	follower := NewFollower(&mockLogger{}, source, map[string]Indexer{"person": persons})
+	follower.BatchSize = 2
+	follower.PollInterval = time.Minute
+
+	var slept []time.Duration
+	follower.sleep = func(ctx context.Context, d time.Duration) error {
+		slept = append(slept, d)
+		return nil
+	}
+
+	assert.Nil(t, follower.Run(ctx))
+	assert.Equal(t, []time.Duration{time.Minute}, slept)
+
+	mock.AssertExpectationsForObjects(t, source, persons)
+}
+
+func TestFollowerRunListenError(t *testing.T) {
+	ctx := context.Background()
+
+	source := &mockSource{}
+	source.
+		On("Listen", ctx).
+		Return(errors.New("hmm"))
+
+	follower := NewFollower(&mockLogger{}, source, map[string]Indexer{})
+	assert.Equal(t, errors.New("hmm"), follower.Run(ctx))
+}
@@ -0,0 +1,90 @@
+package cmd
+
+import (
+	"context"
+	"flag"
+	"os/signal"
+	"strings"
+	"syscall"
+	"time"
+
+	"github.com/jackc/pgx/v5"
+	"github.com/ministryofjustice/opg-search-service/internal/cdc"
+	"github.com/ministryofjustice/opg-search-service/internal/firm"
+	"github.com/ministryofjustice/opg-search-service/internal/index"
+	"github.com/ministryofjustice/opg-search-service/internal/person"
+	"github.com/sirupsen/logrus"
+)
+
+type FollowCommand struct {
+	logger            *logrus.Logger
+	esClient          index.BulkClient
+	secrets           Secrets
+	currentIndexNames []string
+}
+
+func NewFollow(logger *logrus.Logger, esClient index.BulkClient, secrets Secrets, indexes []IndexConfig) *FollowCommand {
+	var indexNames []string
+	for _, indexConfig := range indexes {
+		indexNames = append(indexNames, indexConfig.Name)
+	}
+	return &FollowCommand{
+		logger:            logger,
+		esClient:          esClient,
+		secrets:           secrets,
+		currentIndexNames: indexNames,
+	}
+}
+
+func (c *FollowCommand) Info() (name, description string) {
+	return "follow", "index person and firm changes as they are made in the database"
+}
+
+func (c *FollowCommand) Run(args []string) error {
+	flagset := flag.NewFlagSet("follow", flag.ExitOnError)
+
+	batchSize := flagset.Int("batch-size", 1000, "number of changes to index at once")
+	pollInterval := flagset.Duration("poll-interval", 30*time.Second, "how often to check for changes that were not notified")
+
+	if err := flagset.Parse(args); err != nil {
+		return err
+	}
+
+	ctx, stop := signal.NotifyContext(context.Background(), syscall.SIGINT, syscall.SIGTERM)
+	defer stop()
+
+	connString, err := dbConnectionString(c.secrets)
+	if err != nil {
+		return err
+	}
+
+	// changes are listened for on a connection of their own, records are read
+	// from the pool
+	listenConn, err := pgx.Connect(ctx, connString)
+	if err != nil {
+		return err
+	}
+	defer listenConn.Close(context.Background()) //nolint:errcheck // no need to check error when closing DB connection
+
+	pool, err := dbPool(ctx, c.secrets, 1)
+	if err != nil {
+		return err
+	}
+	defer pool.Close()
+
+	indexers := map[string]cdc.Indexer{}
+	for _, indexName := range c.currentIndexNames {
+		switch {
+		case strings.HasPrefix(indexName, person.AliasName+"_"):
+			indexers[person.AliasName] = index.New(c.esClient, c.logger, person.NewDB(pool), indexName)
+		case strings.HasPrefix(indexName, firm.AliasName+"_"):
+			indexers[firm.AliasName] = index.New(c.esClient, c.logger, firm.NewDB(pool), indexName)
+		}
+	}
+
+	follower := cdc.NewFollower(c.logger, cdc.NewDB(listenConn), indexers)
+	follower.BatchSize = *batchSize
+	follower.PollInterval = *pollInterval
+
+	return follower.Run(ctx)
+}
@@ -8,10 +8,12 @@ import (
 	"fmt"
 	"net/url"
 	"os"
+	"path/filepath"
 	"strings"
 	"time"
 
-	"github.com/jackc/pgx/v5"
+	"github.com/jackc/pgx/v5/pgxpool"
+	"github.com/ministryofjustice/opg-search-service/internal/digitallpa"
 	"github.com/ministryofjustice/opg-search-service/internal/firm"
 	"github.com/ministryofjustice/opg-search-service/internal/index"
 	"github.com/ministryofjustice/opg-search-service/internal/person"
@@ -79,47 +81,59 @@ func (c *IndexCommand) Run(args []string) error {
 	all := flagset.Bool("all", false, "index all records for chosen indices")
 	firmOnly := flagset.Bool("firm", false, "index records to the firm index")
 	personOnly := flagset.Bool("person", false, "index records to the person index")
+	digitalLpaOnly := flagset.Bool("digital-lpa", false, "index records to the digital lpa index from the lpa_store tables, which are unverified against the LPA store so are only read when this is given")
 	from := flagset.Int("from", 0, "index an id range starting from (use with -to)")
 	to := flagset.Int("to", 100, "index an id range ending at (use with -from)")
 	batchSize := flagset.Int("batch-size", 10000, "batch size to read from db")
-	fromDate := flagset.String("from-date", "", "index records updated from this date")
+	fromDate := flagset.String("from-date", "", "index records updated from this date, for persons and firms unless -firm, -person or -digital-lpa are given")
+	concurrency := flagset.Int("concurrency", 1, "number of bulk requests to send at once")
+	workers := flagset.Int("workers", 4, "number of id ranges to read and index at once")
+	resume := flagset.Bool("resume", false, "continue an id range or -all run from its last checkpoint")
+	checkpointDir := flagset.String("checkpoint-dir", "", "directory on durable storage to keep checkpoints of id range runs in, so that they can be continued with -resume")
 
 	if err := flagset.Parse(args); err != nil {
 		return err
 	}
 
-	ctx := context.Background()
-
-	connString, err := c.dbConnectionString()
-	if err != nil {
-		return err
+	if *resume && *checkpointDir == "" {
+		return errors.New("-resume requires -checkpoint-dir")
 	}
 
-	conn, err := pgx.Connect(ctx, connString)
+	ctx := context.Background()
+
+	// each worker holds a connection while it reads its range
+	conn, err := dbPool(ctx, c.secrets, *workers)
 	if err != nil {
 		return err
 	}
-	defer conn.Close(ctx) //nolint:errcheck // no need to check error when closing DB connection
+	defer conn.Close()
 
-	if err := conn.Ping(ctx); err != nil {
-		return err
+	noneSet := !*firmOnly && !*personOnly && !*digitalLpaOnly
+	entities := []struct {
+		name     string
+		selected bool
+		// explicit entities are only indexed when chosen, digital lpas are
+		// as the lpa_store tables they are read from are unverified
+		explicit bool
+		prefix   string
+		db       index.DB
+	}{
+		{name: "firm", selected: *firmOnly, prefix: firm.AliasName + "_", db: firm.NewDB(conn)},
+		{name: "person", selected: *personOnly, prefix: person.AliasName + "_", db: person.NewDB(conn)},
+		{name: "digital-lpa", selected: *digitalLpaOnly, explicit: true, prefix: digitallpa.AliasName + "_", db: digitallpa.NewDB(conn)},
 	}
 
-	indexers := map[string]*index.Indexer{}
-	noneSet := !*firmOnly && !*personOnly
-
-	if *firmOnly || noneSet {
-		for _, indexName := range c.currentIndexNames {
-			if strings.HasPrefix(indexName, "firm_") {
-				indexers["firm"] = index.New(c.esClient, c.logger, firm.NewDB(conn), indexName)
-				break
-			}
+	// indexers are run in a fixed order, so that a combined run logs the same
+	// way each time
+	var indexers []namedIndexer
+	for _, entity := range entities {
+		if !entity.selected && (!noneSet || entity.explicit) {
+			continue
 		}
-	}
-	if *personOnly || noneSet {
+
 		for _, indexName := range c.currentIndexNames {
-			if strings.HasPrefix(indexName, "person_") {
-				indexers["person"] = index.New(c.esClient, c.logger, person.NewDB(conn), indexName)
+			if strings.HasPrefix(indexName, entity.prefix) {
+				indexers = append(indexers, namedIndexer{name: entity.name, indexer: index.New(c.esClient, c.logger, entity.db, indexName)})
 				break
 			}
 		}
@@ -131,8 +145,29 @@ func (c *IndexCommand) Run(args []string) error {
 		return fmt.Errorf("-from-date: %w", err)
 	}
 
-	for indexerName, indexer := range indexers {
+	var errs []error
+	for _, named := range indexers {
+		indexerName, indexer := named.name, named.indexer
 		var result *index.Result
+		indexer.SetConcurrency(*concurrency)
+		indexer.SetWorkers(*workers)
+
+		var checkpoint *index.Checkpoint
+		if fromTime.IsZero() && *checkpointDir != "" {
+			checkpointPath := filepath.Join(*checkpointDir, fmt.Sprintf("index-%s.checkpoint.json", indexerName))
+
+			if *resume {
+				checkpoint, err = index.LoadCheckpoint(checkpointPath)
+				if err != nil {
+					return err
+				}
+			} else {
+				checkpoint = index.NewCheckpoint(checkpointPath)
+			}
+
+			c.logger.Printf("checkpointing %s to %s", indexerName, checkpointPath)
+			indexer.SetCheckpoint(checkpoint)
+		}
 
 		if !fromTime.IsZero() {
 			c.logger.Printf("indexing %s by date from=%v batchSize=%d", indexerName, fromTime, *batchSize)
@@ -145,24 +180,77 @@ func (c *IndexCommand) Run(args []string) error {
 			result, err = indexer.ByID(ctx, *from, *to, *batchSize)
 		}
 
+		// when indexing more than one entity type, a failure for one does not
+		// stop the others from being brought up to date
 		if err != nil {
-			return err
+			c.logger.Printf("indexing %s failed: %s", indexerName, err.Error())
+			errs = append(errs, fmt.Errorf("%s: %w", indexerName, err))
+			continue
+		}
+
+		// a run with failed bulk requests or documents keeps its checkpoint,
+		// so that the ranges they were for can be retried with -resume
+		if checkpoint != nil && len(result.Errors) == 0 && result.Failed == 0 {
+			if err := checkpoint.Remove(); err != nil {
+				return err
+			}
 		}
 
-		c.logger.Printf("indexing done successful=%d failed=%d", result.Successful, result.Failed)
+		c.logger.Printf("indexing done successful=%d failed=%d conflicts=%d", result.Successful, result.Failed, result.Conflicts)
 		for _, e := range result.Errors {
 			c.logger.Println(e)
 		}
+		for _, f := range result.Failures {
+			c.logger.Printf("failed to index id=%s status=%d %s", f.Id, f.StatusCode, f.Message)
+		}
+	}
+
+	return errors.Join(errs...)
+}
+
+type namedIndexer struct {
+	name    string
+	indexer *index.Indexer
+}
+
+// dbPool connects to the database, with a pool that allows at least minConns
+// connections
+func dbPool(ctx context.Context, secrets Secrets, minConns int) (*pgxpool.Pool, error) {
+	connString, err := dbConnectionString(secrets)
+	if err != nil {
+		return nil, err
+	}
+
+	poolConfig, err := pgxpool.ParseConfig(connString)
+	if err != nil {
+		return nil, err
+	}
+	if int32(minConns) > poolConfig.MaxConns {
+		poolConfig.MaxConns = int32(minConns)
+	}
+
+	pool, err := pgxpool.NewWithConfig(ctx, poolConfig)
+	if err != nil {
+		return nil, err
+	}
+
+	if err := pool.Ping(ctx); err != nil {
+		pool.Close()
+		return nil, err
 	}
 
-	return nil
+	return pool, nil
 }
 
 func (c *IndexCommand) dbConnectionString() (string, error) {
+	return dbConnectionString(c.secrets)
+}
+
+func dbConnectionString(secrets Secrets) (string, error) {
 	pass := os.Getenv("SEARCH_SERVICE_DB_PASS")
 	if passSecret := os.Getenv("SEARCH_SERVICE_DB_PASS_SECRET"); passSecret != "" {
 		var err error
-		pass, err = c.secrets.GetGlobalSecretString(passSecret)
+		pass, err = secrets.GetGlobalSecretString(passSecret)
 		if err != nil {
 			return "", err
 		}
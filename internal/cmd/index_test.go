@@ -56,8 +56,9 @@ func TestIndexPerson(t *testing.T) {
 	defer conn.Close(ctx) //nolint:errcheck // no need to check DB close error in tests
 
 	schemaSql, _ := os.ReadFile("../testdata/schema.sql")
+	changesSql, _ := os.ReadFile("../../scripts/postgres/search_changes.sql")
 
-	_, err = conn.Exec(ctx, string(schemaSql))
+	_, err = conn.Exec(ctx, string(schemaSql)+string(changesSql))
 	if !assert.Nil(err) {
 		return
 	}
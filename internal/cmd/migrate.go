@@ -0,0 +1,132 @@
+package cmd
+
+import (
+	"context"
+	"encoding/json"
+	"flag"
+	"strings"
+
+	"github.com/ministryofjustice/opg-search-service/internal/digitallpa"
+	"github.com/ministryofjustice/opg-search-service/internal/firm"
+	"github.com/ministryofjustice/opg-search-service/internal/index"
+	"github.com/ministryofjustice/opg-search-service/internal/migrate"
+	"github.com/ministryofjustice/opg-search-service/internal/person"
+	"github.com/ministryofjustice/opg-search-service/internal/search"
+	"github.com/sirupsen/logrus"
+)
+
+type MigrateCommand struct {
+	logger         *logrus.Logger
+	client         migrate.Client
+	secrets        Secrets
+	currentIndices []IndexConfig
+}
+
+func NewMigrate(logger *logrus.Logger, client migrate.Client, secrets Secrets, currentIndices []IndexConfig) *MigrateCommand {
+	return &MigrateCommand{
+		logger:         logger,
+		client:         client,
+		secrets:        secrets,
+		currentIndices: currentIndices,
+	}
+}
+
+func (c *MigrateCommand) Info() (name, description string) {
+	return "migrate", "build, check and move aliases to the current indices"
+}
+
+func (c *MigrateCommand) Run(args []string) error {
+	flagset := flag.NewFlagSet("migrate", flag.ExitOnError)
+
+	source := flagset.String("source", migrate.SourceDatabase, "build the new indices from the \"database\" or by \"reindex\" of the old indices")
+	batchSize := flagset.Int("batch-size", 10000, "batch size to read from db")
+	workers := flagset.Int("workers", 4, "number of id ranges to read and index at once")
+	samples := flagset.Int("samples", 100, "number of documents in the old index to check are in the new index")
+	countTolerance := flagset.Float64("count-tolerance", 0.01, "fraction by which the number of documents in the new index may differ")
+	queries := flagset.Int("queries", 5, "number of sampled documents to search for in the old and new indices, comparing the top hits, 0 to skip")
+	topHits := flagset.Int("top-hits", 10, "number of top hits that must be the same in the old and new indices")
+	cleanup := flagset.Bool("cleanup", false, "delete the old indices once every alias has been moved")
+	digitalLpaDB := flagset.Bool("digital-lpa-db", false, "build the digital lpa index from the lpa_store tables, which are unverified against the LPA store, rather than by copying the old index")
+
+	if err := flagset.Parse(args); err != nil {
+		return err
+	}
+
+	ctx := context.Background()
+
+	conn, err := dbPool(ctx, c.secrets, *workers)
+	if err != nil {
+		return err
+	}
+	defer conn.Close()
+
+	dbs := map[string]index.DB{
+		person.AliasName: person.NewDB(conn),
+		firm.AliasName:   firm.NewDB(conn),
+	}
+	if *digitalLpaDB {
+		dbs[digitallpa.AliasName] = digitallpa.NewDB(conn)
+	}
+
+	var indices []migrate.Index
+	for _, indexConfig := range c.currentIndices {
+		indices = append(indices, migrate.Index{
+			Alias:  indexConfig.Alias,
+			Name:   indexConfig.Name,
+			Config: indexConfig.Config,
+			DB:     dbs[indexConfig.Alias],
+			Query:  sampleQueries[indexConfig.Alias],
+		})
+	}
+
+	return migrate.New(c.client, c.logger, migrate.Options{
+		Source:         *source,
+		BatchSize:      *batchSize,
+		Workers:        *workers,
+		Samples:        *samples,
+		CountTolerance: *countTolerance,
+		Queries:        *queries,
+		TopHits:        *topHits,
+		Cleanup:        *cleanup,
+	}).Run(ctx, indices)
+}
+
+// sampleQueries build the search the service runs for a document sampled from
+// the old index, by the name it is most likely to be searched for
+var sampleQueries = map[string]func(doc json.RawMessage) (map[string]interface{}, bool){
+	person.AliasName: func(doc json.RawMessage) (map[string]interface{}, bool) {
+		var v struct {
+			Firstname string `json:"firstname"`
+			Surname   string `json:"surname"`
+		}
+		_ = json.Unmarshal(doc, &v)
+		return sampleQuery(search.PrepareQueryForPerson, v.Firstname, v.Surname)
+	},
+	firm.AliasName: func(doc json.RawMessage) (map[string]interface{}, bool) {
+		var v struct {
+			FirmName string `json:"firmName"`
+		}
+		_ = json.Unmarshal(doc, &v)
+		return sampleQuery(search.PrepareQueryForFirm, v.FirmName)
+	},
+	digitallpa.AliasName: func(doc json.RawMessage) (map[string]interface{}, bool) {
+		var v struct {
+			Donor struct {
+				Firstnames string `json:"firstNames"`
+				Surname    string `json:"surname"`
+			} `json:"donor"`
+		}
+		_ = json.Unmarshal(doc, &v)
+		return sampleQuery(search.PrepareQueryForDigitalLpa, v.Donor.Firstnames, v.Donor.Surname)
+	},
+}
+
+func sampleQuery(prepare func(*search.Request) ([]string, map[string]interface{}), terms ...string) (map[string]interface{}, bool) {
+	term := strings.TrimSpace(strings.Join(terms, " "))
+	if term == "" {
+		return nil, false
+	}
+
+	_, body := prepare(&search.Request{Term: term})
+	return body, true
+}
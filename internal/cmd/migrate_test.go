@@ -0,0 +1,61 @@
+package cmd
+
+import (
+	"encoding/json"
+	"testing"
+
+	"github.com/ministryofjustice/opg-search-service/internal/digitallpa"
+	"github.com/ministryofjustice/opg-search-service/internal/firm"
+	"github.com/ministryofjustice/opg-search-service/internal/person"
+	"github.com/ministryofjustice/opg-search-service/internal/search"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestSampleQueries(t *testing.T) {
+	testCases := map[string]struct {
+		alias   string
+		doc     string
+		prepare func(*search.Request) ([]string, map[string]interface{})
+		term    string
+	}{
+		"person": {
+			alias:   person.AliasName,
+			doc:     `{"id":1,"firstname":"John","surname":"Doe"}`,
+			prepare: search.PrepareQueryForPerson,
+			term:    "John Doe",
+		},
+		"person surname only": {
+			alias:   person.AliasName,
+			doc:     `{"id":1,"surname":"Doe"}`,
+			prepare: search.PrepareQueryForPerson,
+			term:    "Doe",
+		},
+		"firm": {
+			alias:   firm.AliasName,
+			doc:     `{"id":1,"firmName":"Firm Co"}`,
+			prepare: search.PrepareQueryForFirm,
+			term:    "Firm Co",
+		},
+		"digital lpa": {
+			alias:   digitallpa.AliasName,
+			doc:     `{"uId":"M-1234-5678-9012","donor":{"firstNames":"Jane","surname":"Smith"}}`,
+			prepare: search.PrepareQueryForDigitalLpa,
+			term:    "Jane Smith",
+		},
+	}
+
+	for name, tc := range testCases {
+		t.Run(name, func(t *testing.T) {
+			body, ok := sampleQueries[tc.alias](json.RawMessage(tc.doc))
+			assert.True(t, ok)
+
+			_, expected := tc.prepare(&search.Request{Term: tc.term})
+			assert.Equal(t, expected, body)
+		})
+	}
+}
+
+func TestSampleQueriesWithoutName(t *testing.T) {
+	_, ok := sampleQueries[person.AliasName](json.RawMessage(`{"id":1,"companyName":"Company"}`))
+	assert.False(t, ok)
+}
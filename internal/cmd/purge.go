@@ -0,0 +1,65 @@
+package cmd
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"flag"
+	"fmt"
+
+	"github.com/ministryofjustice/opg-search-service/internal/purge"
+	"github.com/sirupsen/logrus"
+)
+
+type PurgeService interface {
+	Purge(ctx context.Context, uid string, explain bool) (*purge.Report, error)
+}
+
+type PurgeCommand struct {
+	logger *logrus.Logger
+	purger PurgeService
+}
+
+func NewPurge(logger *logrus.Logger, purger PurgeService) *PurgeCommand {
+	return &PurgeCommand{
+		logger: logger,
+		purger: purger,
+	}
+}
+
+func (c *PurgeCommand) Info() (name, description string) {
+	return "purge", "erase a person from every index"
+}
+
+func (c *PurgeCommand) Run(args []string) error {
+	ctx := context.Background()
+	flagset := flag.NewFlagSet("purge", flag.ExitOnError)
+
+	uid := flagset.String("uid", "", "uid of the person to erase")
+	explain := flagset.Bool("explain", false, "explain the changes that will be made")
+
+	if err := flagset.Parse(args); err != nil {
+		return err
+	}
+
+	if *uid == "" {
+		return errors.New("-uid is required")
+	}
+
+	report, err := c.purger.Purge(ctx, *uid, *explain)
+	if err != nil {
+		return err
+	}
+
+	data, err := json.Marshal(report)
+	if err != nil {
+		return err
+	}
+	c.logger.Println(string(data))
+
+	if report.Failed > 0 {
+		return fmt.Errorf("failed to purge %d documents", report.Failed)
+	}
+
+	return nil
+}
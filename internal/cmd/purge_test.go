@@ -0,0 +1,54 @@
+package cmd
+
+import (
+	"context"
+	"errors"
+	"testing"
+
+	"github.com/ministryofjustice/opg-search-service/internal/purge"
+	"github.com/sirupsen/logrus/hooks/test"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+)
+
+type mockPurgeService struct {
+	mock.Mock
+}
+
+func (m *mockPurgeService) Purge(ctx context.Context, uid string, explain bool) (*purge.Report, error) {
+	args := m.Called(ctx, uid, explain)
+	report, _ := args.Get(0).(*purge.Report)
+	return report, args.Error(1)
+}
+
+func TestPurge(t *testing.T) {
+	l, hook := test.NewNullLogger()
+	purger := &mockPurgeService{}
+
+	purger.
+		On("Purge", mock.Anything, "7000-0000-0001", true).
+		Return(&purge.Report{UID: "7000-0000-0001", Explain: true, Deleted: []*purge.Document{{Index: "person_a", ID: "1"}}, Scrubbed: []*purge.Document{}}, nil)
+
+	command := NewPurge(l, purger)
+	assert.Nil(t, command.Run([]string{"-uid", "7000-0000-0001", "-explain"}))
+	assert.Equal(t, `{"uId":"7000-0000-0001","explain":true,"deleted":[{"index":"person_a","id":"1"}],"scrubbed":[],"failed":0}`, hook.LastEntry().Message)
+}
+
+func TestPurgeFailed(t *testing.T) {
+	l, _ := test.NewNullLogger()
+	purger := &mockPurgeService{}
+
+	purger.
+		On("Purge", mock.Anything, "7000-0000-0001", false).
+		Return(&purge.Report{UID: "7000-0000-0001", Failed: 2}, nil)
+
+	command := NewPurge(l, purger)
+	assert.Equal(t, errors.New("failed to purge 2 documents"), command.Run([]string{"-uid", "7000-0000-0001"}))
+}
+
+func TestPurgeRequiresUID(t *testing.T) {
+	l, _ := test.NewNullLogger()
+
+	command := NewPurge(l, &mockPurgeService{})
+	assert.Equal(t, errors.New("-uid is required"), command.Run([]string{}))
+}
@@ -0,0 +1,116 @@
+package cmd
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"flag"
+
+	"github.com/ministryofjustice/opg-search-service/internal/firm"
+	"github.com/ministryofjustice/opg-search-service/internal/index"
+	"github.com/ministryofjustice/opg-search-service/internal/person"
+	"github.com/ministryofjustice/opg-search-service/internal/reconcile"
+	"github.com/sirupsen/logrus"
+)
+
+type ReconcileClient interface {
+	index.BulkClient
+	reconcile.Client
+	ResolveAlias(ctx context.Context, alias string) (string, error)
+}
+
+type ReconcileCommand struct {
+	logger  *logrus.Logger
+	client  ReconcileClient
+	secrets Secrets
+}
+
+func NewReconcile(logger *logrus.Logger, client ReconcileClient, secrets Secrets) *ReconcileCommand {
+	return &ReconcileCommand{
+		logger:  logger,
+		client:  client,
+		secrets: secrets,
+	}
+}
+
+func (c *ReconcileCommand) Info() (name, description string) {
+	return "reconcile", "compare the database with the aliased indices"
+}
+
+func (c *ReconcileCommand) Run(args []string) error {
+	flagset := flag.NewFlagSet("reconcile", flag.ExitOnError)
+
+	all := flagset.Bool("all", false, "reconcile all records for chosen indices")
+	firmOnly := flagset.Bool("firm", false, "reconcile the firm index")
+	personOnly := flagset.Bool("person", false, "reconcile the person index")
+	from := flagset.Int("from", 0, "reconcile an id range starting from (use with -to)")
+	to := flagset.Int("to", 100, "reconcile an id range ending at (use with -from)")
+	batchSize := flagset.Int("batch-size", 10000, "number of ids to compare at once")
+	fix := flagset.Bool("fix", false, "reindex missing and stale documents, and delete orphaned documents")
+
+	if err := flagset.Parse(args); err != nil {
+		return err
+	}
+
+	if *batchSize <= 0 {
+		return errors.New("-batch-size must be greater than 0")
+	}
+
+	ctx := context.Background()
+
+	conn, err := dbPool(ctx, c.secrets, 1)
+	if err != nil {
+		return err
+	}
+	defer conn.Close()
+
+	noneSet := !*firmOnly && !*personOnly
+	entities := []struct {
+		alias    string
+		selected bool
+		db       index.DB
+	}{
+		{alias: firm.AliasName, selected: *firmOnly, db: firm.NewDB(conn)},
+		{alias: person.AliasName, selected: *personOnly, db: person.NewDB(conn)},
+	}
+
+	for _, entity := range entities {
+		if !entity.selected && !noneSet {
+			continue
+		}
+
+		// the index that is searched is the one that needs to match, rather
+		// than the newest
+		indexName, err := c.client.ResolveAlias(ctx, entity.alias)
+		if err != nil {
+			return err
+		}
+
+		indexer := index.New(c.client, c.logger, entity.db, indexName)
+		reconciler := reconcile.New(c.client, entity.db, indexer, indexName)
+		reconciler.BatchSize = *batchSize
+
+		start, end := *from, *to
+		if *all {
+			if start, end, err = entity.db.QueryIDRange(ctx); err != nil {
+				return err
+			}
+		}
+
+		c.logger.Printf("reconciling %s from=%d to=%d fix=%t", indexName, start, end, *fix)
+
+		report, err := reconciler.Range(ctx, start, end, *fix)
+		if err != nil {
+			return err
+		}
+
+		data, err := json.Marshal(report)
+		if err != nil {
+			return err
+		}
+		c.logger.Println(string(data))
+		c.logger.Printf("reconciled %s checked=%d missing=%d stale=%d orphaned=%d", indexName, report.Checked, len(report.Missing), len(report.Stale), len(report.Orphaned))
+	}
+
+	return nil
+}
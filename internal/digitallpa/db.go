@@ -0,0 +1,148 @@
+package digitallpa
+
+import (
+	"context"
+	"time"
+
+	"github.com/jackc/pgx/v5"
+	"github.com/ministryofjustice/opg-search-service/internal/index"
+)
+
+func NewDB(conn index.Conn) *DB {
+	return &DB{conn: conn}
+}
+
+// DB reads digital LPAs from the LPA store tables, so that the index can be
+// rebuilt rather than only filled by requests to the index endpoint. The
+// tables in testdata/schema.sql have not been checked against the real LPA
+// store, so it is only used when asked for.
+type DB struct {
+	conn index.Conn
+}
+
+func (db *DB) QueryIDRange(ctx context.Context) (min int, max int, err error) {
+	err = db.conn.QueryRow(ctx, "SELECT COALESCE(MIN(id), 0), COALESCE(MAX(id), 0) FROM lpa_store.lpas").Scan(&min, &max)
+
+	return min, max, err
+}
+
+func (db *DB) QueryByID(ctx context.Context, results chan<- index.Indexable, from, to int) error {
+	rows, err := db.conn.Query(ctx, makeQueryDigitalLpa(`l.id >= $1 AND l.id <= $2`), from, to)
+	if err != nil {
+		return err
+	}
+
+	return scan(ctx, rows, results)
+}
+
+func (db *DB) QueryFromDate(ctx context.Context, results chan<- index.Indexable, from time.Time) error {
+	rows, err := db.conn.Query(ctx, makeQueryDigitalLpa(`l.updated_at >= $1`), from)
+	if err != nil {
+		return err
+	}
+
+	return scan(ctx, rows, results)
+}
+
+func makeQueryDigitalLpa(whereClause string) string {
+	return `SELECT l.id, l.uid, coalesce(l.lpa_type, ''),
+		coalesce(l.donor_first_names, ''), coalesce(l.donor_last_name, ''), coalesce(to_char(l.donor_date_of_birth, 'DD/MM/YYYY'), ''),
+		coalesce(l.donor_address_line1, ''), coalesce(l.donor_address_line2, ''), coalesce(l.donor_address_line3, ''), coalesce(l.donor_postcode, ''),
+		coalesce(l.certificate_provider_first_names, ''), coalesce(l.certificate_provider_last_name, ''),
+		coalesce(l.certificate_provider_address_line1, ''), coalesce(l.certificate_provider_address_line2, ''), coalesce(l.certificate_provider_address_line3, ''), coalesce(l.certificate_provider_postcode, ''),
+		a.id, coalesce(a.first_names, ''), coalesce(a.last_name, ''), coalesce(to_char(a.date_of_birth, 'DD/MM/YYYY'), ''),
+		coalesce(a.address_line1, ''), coalesce(a.address_line2, ''), coalesce(a.address_line3, ''), coalesce(a.postcode, '')
+FROM lpa_store.lpas l
+LEFT JOIN lpa_store.attorneys a ON l.id = a.lpa_id
+WHERE ` + whereClause + `
+ORDER BY l.id, a.id`
+}
+
+type rowResult struct {
+	ID                    int
+	UID                   string
+	LpaType               string
+	DonorFirstNames       string
+	DonorLastName         string
+	DonorDob              string
+	DonorAddress          Address
+	CertificateFirstNames string
+	CertificateLastName   string
+	CertificateAddress    Address
+	AttorneyID            *int
+	AttorneyFirstNames    string
+	AttorneyLastName      string
+	AttorneyDob           string
+	AttorneyAddress       Address
+}
+
+func scan(ctx context.Context, rows pgx.Rows, results chan<- index.Indexable) error {
+	var err error
+	lastID := -1
+
+	var lpa *DigitalLpa
+	for rows.Next() {
+		var v rowResult
+		err = rows.Scan(&v.ID, &v.UID, &v.LpaType,
+			&v.DonorFirstNames, &v.DonorLastName, &v.DonorDob,
+			&v.DonorAddress.Line1, &v.DonorAddress.Line2, &v.DonorAddress.Line3, &v.DonorAddress.Postcode,
+			&v.CertificateFirstNames, &v.CertificateLastName,
+			&v.CertificateAddress.Line1, &v.CertificateAddress.Line2, &v.CertificateAddress.Line3, &v.CertificateAddress.Postcode,
+			&v.AttorneyID, &v.AttorneyFirstNames, &v.AttorneyLastName, &v.AttorneyDob,
+			&v.AttorneyAddress.Line1, &v.AttorneyAddress.Line2, &v.AttorneyAddress.Line3, &v.AttorneyAddress.Postcode)
+
+		if err != nil {
+			break
+		}
+
+		if v.ID != lastID {
+			if lpa != nil {
+				results <- *lpa
+			}
+
+			lpa = newDigitalLpa(v)
+			lastID = v.ID
+		}
+
+		if v.AttorneyID != nil {
+			lpa.Attorneys = append(lpa.Attorneys, Attorney{
+				Person: Person{
+					Firstnames: v.AttorneyFirstNames,
+					Surname:    v.AttorneyLastName,
+					Address:    v.AttorneyAddress,
+				},
+				Dob: v.AttorneyDob,
+			})
+		}
+	}
+
+	if lpa != nil {
+		results <- *lpa
+	}
+
+	if err != nil {
+		return err
+	}
+
+	return rows.Err()
+}
+
+func newDigitalLpa(s rowResult) *DigitalLpa {
+	return &DigitalLpa{
+		Uid:     s.UID,
+		LpaType: s.LpaType,
+		Donor: Donor{
+			Person: Person{
+				Firstnames: s.DonorFirstNames,
+				Surname:    s.DonorLastName,
+				Address:    s.DonorAddress,
+			},
+			Dob: s.DonorDob,
+		},
+		CertificateProvider: Person{
+			Firstnames: s.CertificateFirstNames,
+			Surname:    s.CertificateLastName,
+			Address:    s.CertificateAddress,
+		},
+	}
+}
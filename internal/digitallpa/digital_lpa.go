@@ -2,7 +2,10 @@ package digitallpa
 
 import (
 	"encoding/json"
+
+	"github.com/ministryofjustice/opg-search-service/internal/autocomplete"
 	"github.com/ministryofjustice/opg-search-service/internal/response"
+	"github.com/ministryofjustice/opg-search-service/internal/synonyms"
 )
 
 const AliasName = "digital_lpa"
@@ -59,6 +62,20 @@ func IndexConfig() (config []byte, err error) {
 	textField := map[string]interface{}{"type": "text"}
 	searchableTextField := map[string]interface{}{"type": "text", "copy_to": "searchable"}
 	keywordField := map[string]interface{}{"type": "keyword"}
+	searchableDateField := map[string]interface{}{
+		"type":    "text",
+		"copy_to": "searchable",
+		"fields": map[string]interface{}{
+			"date": map[string]interface{}{"type": "date", "format": "dd/MM/yyyy||yyyy-MM-dd", "ignore_malformed": true},
+		},
+	}
+	searchableFirstNamesField := map[string]interface{}{
+		"type":    "text",
+		"copy_to": "searchable",
+		"fields": map[string]interface{}{
+			"synonym": map[string]interface{}{"type": "text", "analyzer": synonyms.NameAnalyzer},
+		},
+	}
 
 	digitalLpaConfig := map[string]interface{}{
 		"settings": map[string]interface{}{
@@ -72,6 +89,8 @@ func IndexConfig() (config []byte, err error) {
 						"pattern":     " ",
 						"replacement": "",
 					},
+					synonyms.NameFilter: synonyms.NameFilterConfig(),
+					autocomplete.Filter: autocomplete.FilterConfig(),
 				},
 				"analyzer": map[string]interface{}{
 					"default": map[string]interface{}{
@@ -82,19 +101,28 @@ func IndexConfig() (config []byte, err error) {
 						"tokenizer": "keyword",
 						"filter":    []string{"whitespace_remove", "lowercase"},
 					},
+					synonyms.NameAnalyzer:       synonyms.NameAnalyzerConfig(),
+					autocomplete.Analyzer:       autocomplete.AnalyzerConfig(),
+					autocomplete.SearchAnalyzer: autocomplete.SearchAnalyzerConfig(),
 				},
 			},
 		},
 		"mappings": map[string]interface{}{
 			"properties": map[string]interface{}{
-				"searchable": textField,
-				"uId":        searchableTextField,
-				"lpaType":    textField,
+				"searchable":       textField,
+				autocomplete.Field: autocomplete.FieldConfig(),
+				"uId":              autocomplete.CopyTo(searchableTextField),
+				"lpaType": map[string]interface{}{
+					"type": "text",
+					"fields": map[string]interface{}{
+						"keyword": keywordField,
+					},
+				},
 				"donor": map[string]interface{}{
 					"properties": map[string]interface{}{
-						"firstNames": searchableTextField,
-						"surname":    searchableTextField,
-						"dob":        searchableTextField,
+						"firstNames": autocomplete.CopyTo(searchableFirstNamesField),
+						"surname":    autocomplete.CopyTo(searchableTextField),
+						"dob":        searchableDateField,
 						"address": map[string]interface{}{
 							"properties": map[string]interface{}{
 								"line1": searchableTextField,
@@ -135,9 +163,9 @@ func IndexConfig() (config []byte, err error) {
 				},
 				"attorneys": map[string]interface{}{
 					"properties": map[string]interface{}{
-						"firstNames": searchableTextField,
+						"firstNames": searchableFirstNamesField,
 						"surname":    searchableTextField,
-						"dob":        searchableTextField,
+						"dob":        searchableDateField,
 						"address": map[string]interface{}{
 							"properties": map[string]interface{}{
 								"line1": searchableTextField,
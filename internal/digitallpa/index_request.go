@@ -41,3 +41,12 @@ func ParseIndexRequest(body []byte) (index.Validatable, error) {
 	err := json.Unmarshal(body, &req)
 	return &req, err
 }
+
+// ParsePartialIndexRequest reads a request to update only some fields of each
+// item, the items must still have an uId. Attorneys are added to the LPA's
+// attorneys unless it already has them.
+func ParsePartialIndexRequest(body []byte) (index.Validatable, error) {
+	return index.ParsePartial(body, "digitalLpaApplications", "uId", DigitalLpa{}, map[string]string{
+		"attorneys": "",
+	})
+}
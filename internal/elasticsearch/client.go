@@ -18,9 +18,8 @@ import (
 	"time"
 )
 
-const maxPayloadSize = 10485760 // bytes
-const backoff = 6 * time.Second
-const maxRetries = 10
+// MaxPayloadSize is the largest request body a BulkOp can hold, in bytes
+const MaxPayloadSize = 10485760
 
 var ErrAliasMissing = errors.New("alias is missing")
 var ErrOpTooLarge = errors.New("BulkOp exceeds maximum payload size")
@@ -41,6 +40,10 @@ type Client struct {
 	region     string
 	service    string
 	signer     signer.Signer
+	retry      RetryPolicy
+	breaker    *circuitBreaker
+	jitter     func(time.Duration) time.Duration
+	sleep      func(context.Context, time.Duration) error
 }
 
 type elasticSearchResponse struct {
@@ -50,16 +53,67 @@ type elasticSearchResponse struct {
 			Relation string `json:"relation"`
 		} `json:"total"`
 		Hits []struct {
-			Index  string                 `json:"_index"`
-			Source map[string]interface{} `json:"_source"`
+			Index     string                 `json:"_index"`
+			ID        string                 `json:"_id"`
+			Source    map[string]interface{} `json:"_source"`
+			Sort      []interface{}          `json:"sort"`
+			Highlight map[string][]string    `json:"highlight"`
 		} `json:"hits"`
 	} `json:"hits"`
-	Aggregations map[string]struct {
-		Buckets []struct {
-			Key      string `json:"key"`
-			DocCount int    `json:"doc_count"`
-		} `json:"buckets"`
-	} `json:"aggregations"`
+	PitID        string                       `json:"pit_id"`
+	Aggregations map[string]searchAggregation `json:"aggregations"`
+}
+
+type aggregationBucket struct {
+	Key      string `json:"key"`
+	DocCount int    `json:"doc_count"`
+}
+
+// searchAggregation holds the buckets of a terms aggregation, or for a filter
+// aggregation the aggregations inside it
+type searchAggregation struct {
+	Buckets []aggregationBucket
+	Nested  map[string]searchAggregation
+}
+
+func (a *searchAggregation) UnmarshalJSON(data []byte) error {
+	var fields map[string]json.RawMessage
+	if err := json.Unmarshal(data, &fields); err != nil {
+		return err
+	}
+
+	for name, raw := range fields {
+		if name == "buckets" {
+			if err := json.Unmarshal(raw, &a.Buckets); err != nil {
+				return err
+			}
+			continue
+		}
+
+		// doc_count and meta are not aggregations, so fail to decode as one
+		var nested searchAggregation
+		if json.Unmarshal(raw, &nested) == nil {
+			if a.Nested == nil {
+				a.Nested = map[string]searchAggregation{}
+			}
+			a.Nested[name] = nested
+		}
+	}
+
+	return nil
+}
+
+// buckets returns the buckets of the aggregation, a filter aggregation uses
+// those of the aggregation inside it with the same name so that counts can be
+// limited by the same filter as the hits
+func (a searchAggregation) buckets(name string) []aggregationBucket {
+	if a.Buckets == nil {
+		if nested, ok := a.Nested[name]; ok {
+			return nested.Buckets
+		}
+	}
+
+	return a.Buckets
 }
 
 type SearchResult struct {
@@ -67,6 +121,20 @@ type SearchResult struct {
 	Aggregations map[string]map[string]int
 	Total        int
 	TotalExact   bool
+	// PitID is the point-in-time id returned when searching a point-in-time,
+	// it may differ from the id that was requested
+	PitID string
+	// LastSort holds the sort values of the final hit, to be used as
+	// search_after when requesting the next page
+	LastSort []interface{}
+	// Refs identifies each hit by the concrete index it was found in, as the
+	// _index of a hit is replaced by its alias, in the same order as Hits
+	Refs []DocumentRef
+}
+
+type DocumentRef struct {
+	Index string
+	ID    string
 }
 
 type DeleteResult struct {
@@ -87,7 +155,10 @@ func NewClient(httpClient HTTPClient, logger *logrus.Logger, cfg *aws.Config) (*
 		region:     cfg.Region,
 		service:    os.Getenv("AWS_SEARCH_PROVIDER"),
 		signer:     mySigner,
+		jitter:     fullJitter,
+		sleep:      sleepContext,
 	}
+	client.SetRetryPolicy(DefaultRetryPolicy)
 
 	if client.service == "" {
 		client.service = "es"
@@ -96,7 +167,71 @@ func NewClient(httpClient HTTPClient, logger *logrus.Logger, cfg *aws.Config) (*
 	return client, nil
 }
 
+// SetRetryPolicy replaces the policy used for all requests, it resets the
+// circuit breaker so must not be called while requests are being made
+func (c *Client) SetRetryPolicy(policy RetryPolicy) {
+	c.retry = policy
+	c.breaker = newCircuitBreaker(policy.BreakerThreshold, policy.BreakerCooldown)
+}
+
+// doRequest sends the request, retrying when the cluster is busy or
+// unavailable according to the retry policy. The response to the last attempt
+// is returned, so a caller may still receive a response such as a 429.
 func (c *Client) doRequest(ctx context.Context, method, endpoint string, body io.ReadSeeker, contentType string) (*http.Response, error) {
+	return c.doRequestRetrying(ctx, c.retry.MaxRetries, method, endpoint, body, contentType)
+}
+
+// doRequestOnce sends a request that is not safe to repeat, such as starting
+// a reindex or a delete by query, as a failed attempt may still have been
+// carried out by the cluster. It is subject to the circuit breaker.
+func (c *Client) doRequestOnce(ctx context.Context, method, endpoint string, body io.ReadSeeker, contentType string) (*http.Response, error) {
+	return c.doRequestRetrying(ctx, 0, method, endpoint, body, contentType)
+}
+
+func (c *Client) doRequestRetrying(ctx context.Context, maxRetries int, method, endpoint string, body io.ReadSeeker, contentType string) (*http.Response, error) {
+	for retry := 0; ; retry++ {
+		if err := c.breaker.allow(); err != nil {
+			return nil, err
+		}
+
+		if body != nil {
+			if _, err := body.Seek(0, io.SeekStart); err != nil {
+				c.breaker.release()
+				return nil, err
+			}
+		}
+
+		resp, err := c.send(ctx, method, endpoint, body, contentType)
+		if err != nil && ctx.Err() != nil {
+			c.breaker.release()
+			return nil, err
+		}
+
+		c.breaker.record(unhealthy(resp, err))
+
+		if !retryable(resp, err) || retry >= maxRetries {
+			return resp, err
+		}
+
+		delay, ok := retryAfter(resp, time.Now())
+		if !ok {
+			delay = c.retry.backoff(retry, c.jitter)
+		}
+
+		if resp != nil {
+			_, _ = io.Copy(io.Discard, resp.Body)
+			_ = resp.Body.Close()
+		}
+
+		c.logger.Printf("retrying %s %s in %s, attempt %d failed", method, endpoint, delay, retry+1)
+
+		if err := c.sleep(ctx, delay); err != nil {
+			return nil, err
+		}
+	}
+}
+
+func (c *Client) send(ctx context.Context, method, endpoint string, body io.ReadSeeker, contentType string) (*http.Response, error) {
 	url := c.domain + "/" + endpoint
 	req, err := http.NewRequestWithContext(ctx, method, url, body)
 	if err != nil {
@@ -116,28 +251,95 @@ func (c *Client) doRequest(ctx context.Context, method, endpoint string, body io
 
 type bulkResponse struct {
 	Errors bool `json:"errors"`
-	Items  []struct {
-		Index struct {
-			ID     string `json:"_id"`
-			Status int    `json:"status"`
-			Error  *struct {
-				Type   string `json:"type"`
-				Reason string `json:"reason"`
-			} `json:"error"`
-		} `json:"index"`
+	// each item is keyed by the type of action, such as "index" or "update"
+	Items []map[string]struct {
+		Index  string `json:"_index"`
+		ID     string `json:"_id"`
+		Status int    `json:"status"`
+		Result string `json:"result"`
+		Error  *struct {
+			Type   string `json:"type"`
+			Reason string `json:"reason"`
+		} `json:"error"`
 	} `json:"items"`
 }
 
 type bulkOp struct {
-	Index indexOp `json:"index"`
+	Index  *indexOp `json:"index,omitempty"`
+	Update *indexOp `json:"update,omitempty"`
+	Delete *indexOp `json:"delete,omitempty"`
+}
+
+type updateDoc struct {
+	Script updateScript `json:"script"`
+}
+
+type updateScript struct {
+	Source string       `json:"source"`
+	Lang   string       `json:"lang"`
+	Params updateParams `json:"params"`
+}
+
+type updateParams struct {
+	Doc       interface{}       `json:"doc"`
+	ArrayKeys map[string]string `json:"arrayKeys"`
 }
 
+// mergeScript merges params.doc into the document. Objects are merged field by
+// field, arrays named in params.arrayKeys have each element replaced where one
+// with the same key exists and appended otherwise, and other fields are
+// replaced.
+const mergeScript = `def mergeInto(def current, def value, Map keys, String path) {
+  if (current instanceof Map && value instanceof Map) {
+    for (def entry : value.entrySet()) {
+      String field = path == '' ? entry.getKey() : path + '.' + entry.getKey();
+      current.put(entry.getKey(), mergeInto(current.get(entry.getKey()), entry.getValue(), keys, field));
+    }
+    return current;
+  }
+  if (current instanceof List && value instanceof List && keys.containsKey(path)) {
+    String key = keys.get(path);
+    for (def item : value) {
+      boolean found = false;
+      for (int i = 0; i < current.size() && !found; i++) {
+        def existing = current.get(i);
+        if (key == '' ? existing == item : existing instanceof Map && item instanceof Map && existing.get(key) == item.get(key)) {
+          current.set(i, item);
+          found = true;
+        }
+      }
+      if (!found) {
+        current.add(item);
+      }
+    }
+    return current;
+  }
+  return value;
+}
+mergeInto(ctx._source, params.doc, params.arrayKeys, '');`
+
 type indexOp struct {
-	ID string `json:"_id"`
+	ID          string `json:"_id"`
+	Version     *int64 `json:"version,omitempty"`
+	VersionType string `json:"version_type,omitempty"`
+}
+
+// Versioned documents are indexed with an external version, so that a write
+// with an older version than the indexed document is rejected as a conflict
+type Versioned interface {
+	ExternalVersion() (int64, bool)
+}
+
+// Merged documents name the array fields that an update adds to, rather than
+// replaces, by the field identifying an element. An empty key matches elements
+// that are equal.
+type Merged interface {
+	ArrayKeys() map[string]string
 }
 
 type BulkOp struct {
 	index string
+	docs  int
 	buf   bytes.Buffer
 	tmp   bytes.Buffer
 	enc   *json.Encoder
@@ -151,27 +353,77 @@ func NewBulkOp(index string) *BulkOp {
 }
 
 func (op *BulkOp) Index(id string, v interface{}) error {
-	if err := op.enc.Encode(bulkOp{Index: indexOp{ID: id}}); err != nil {
-		return err
+	action := indexOp{ID: id}
+	if versioned, ok := v.(Versioned); ok {
+		if version, ok := versioned.ExternalVersion(); ok {
+			// external_gte so that reindexing the same version is not a conflict
+			action.Version = &version
+			action.VersionType = "external_gte"
+		}
 	}
 
-	if err := op.enc.Encode(v); err != nil {
+	return op.add(bulkOp{Index: &action}, v)
+}
+
+// Update merges the fields of v into the document with the given id, failing
+// with a 404 if it does not exist. Arrays are replaced unless v is Merged.
+// Updates cannot be externally versioned, they add one to the version of the
+// document which the version of a later write is never below.
+func (op *BulkOp) Update(id string, v interface{}) error {
+	params := updateParams{Doc: v, ArrayKeys: map[string]string{}}
+	if merged, ok := v.(Merged); ok && merged.ArrayKeys() != nil {
+		params.ArrayKeys = merged.ArrayKeys()
+	}
+
+	return op.add(bulkOp{Update: &indexOp{ID: id}}, updateDoc{Script: updateScript{Source: mergeScript, Lang: "painless", Params: params}})
+}
+
+// Delete removes the document with the given id
+func (op *BulkOp) Delete(id string) error {
+	return op.add(bulkOp{Delete: &indexOp{ID: id}}, nil)
+}
+
+// add writes the action and document to the op, a nil document is not written
+// as delete actions do not have one
+func (op *BulkOp) add(action bulkOp, v interface{}) error {
+	if err := op.enc.Encode(action); err != nil {
 		return err
 	}
 
-	if op.tmp.Len()+op.buf.Len() > maxPayloadSize {
+	if v != nil {
+		if err := op.enc.Encode(v); err != nil {
+			return err
+		}
+	}
+
+	if op.tmp.Len()+op.buf.Len() > MaxPayloadSize {
 		return ErrOpTooLarge
 	}
 
-	_, err := op.tmp.WriteTo(&op.buf)
-	return err
+	if _, err := op.tmp.WriteTo(&op.buf); err != nil {
+		return err
+	}
+
+	op.docs++
+	return nil
 }
 
 func (op *BulkOp) Empty() bool {
 	return op.buf.Len() == 0
 }
 
+// Len is the number of actions in the op
+func (op *BulkOp) Len() int {
+	return op.docs
+}
+
+// Size is the size of the request body in bytes
+func (op *BulkOp) Size() int {
+	return op.buf.Len()
+}
+
 func (op *BulkOp) Reset() {
+	op.docs = 0
 	op.buf.Reset()
 	op.tmp.Reset()
 }
@@ -179,25 +431,16 @@ func (op *BulkOp) Reset() {
 type BulkResult struct {
 	Successful int
 	Failed     int
-	Error      string
+	// Conflicts is the number of documents not written because a newer
+	// version was already indexed
+	Conflicts int
+	Error     string
+	// Results holds the outcome for each document, in the order they were
+	// added to the BulkOp
+	Results []IndexResult
 }
 
 func (c *Client) DoBulk(ctx context.Context, op *BulkOp) (BulkResult, error) {
-	retries := 0
-
-	for {
-		res, err := c.doBulkOp(ctx, op)
-		if err == errTooManyRequests && retries < maxRetries {
-			retries++
-			time.Sleep(time.Duration(retries) * backoff)
-			continue
-		}
-
-		return res, err
-	}
-}
-
-func (c *Client) doBulkOp(ctx context.Context, op *BulkOp) (BulkResult, error) {
 	body := bytes.NewReader(op.buf.Bytes())
 
 	endpoint := fmt.Sprintf("%s/_bulk", op.index)
@@ -226,23 +469,41 @@ func (c *Client) doBulkOp(ctx context.Context, op *BulkOp) (BulkResult, error) {
 	}
 
 	var result BulkResult
-	for _, d := range v.Items {
-		if d.Index.Status == http.StatusOK || d.Index.Status == http.StatusCreated {
-			result.Successful += 1
-		} else {
-			result.Failed += 1
-			if d.Index.Error != nil && result.Error == "" {
-				result.Error = fmt.Sprintf("%s: %s", d.Index.Error.Type, d.Index.Error.Reason)
+	for _, action := range v.Items {
+		for _, d := range action {
+			item := IndexResult{Id: d.ID, Index: d.Index, StatusCode: d.Status}
+			if d.Error != nil {
+				item.Message = fmt.Sprintf("%s: %s", d.Error.Type, d.Error.Reason)
+			} else if !item.Successful() {
+				// such as "not_found" when deleting
+				item.Message = d.Result
+			}
+
+			if item.Successful() {
+				result.Successful += 1
+			} else if item.Conflict() {
+				result.Conflicts += 1
+			} else {
+				result.Failed += 1
+				if item.Message != "" && result.Error == "" {
+					result.Error = item.Message
+				}
 			}
+
+			result.Results = append(result.Results, item)
 		}
 	}
 
 	return result, nil
 }
 
-// returns an array of JSON encoded results
+// returns an array of JSON encoded results, when no indices are given the
+// request body is expected to specify a point-in-time to search
 func (c *Client) Search(ctx context.Context, indices []string, requestBody map[string]interface{}) (*SearchResult, error) {
-	endpoint := strings.Join(indices, ",") + "/_search"
+	endpoint := "_search"
+	if len(indices) > 0 {
+		endpoint = strings.Join(indices, ",") + "/_search"
+	}
 
 	var buf bytes.Buffer
 	if err := json.NewEncoder(&buf).Encode(requestBody); err != nil {
@@ -267,9 +528,17 @@ func (c *Client) Search(ctx context.Context, indices []string, requestBody map[s
 		return nil, fmt.Errorf("error parsing the response body: %w", err)
 	}
 
+	var lastSort []interface{}
 	hits := make([]json.RawMessage, len(esResponse.Hits.Hits))
+	refs := make([]DocumentRef, len(esResponse.Hits.Hits))
 	for i, hit := range esResponse.Hits.Hits {
+		lastSort = hit.Sort
+		refs[i] = DocumentRef{Index: hit.Index, ID: hit.ID}
+
 		hit.Source["_index"] = indexAliasCleaner.ReplaceAllString(hit.Index, "")
+		if len(hit.Highlight) > 0 {
+			hit.Source["_highlight"] = hit.Highlight
+		}
 
 		result, err := json.Marshal(hit.Source)
 		if err != nil {
@@ -281,7 +550,7 @@ func (c *Client) Search(ctx context.Context, indices []string, requestBody map[s
 
 	aggregations := map[string]map[string]int{}
 	for field, v := range esResponse.Aggregations {
-		for _, bucket := range v.Buckets {
+		for _, bucket := range v.buckets(field) {
 			if m, ok := aggregations[field]; ok {
 				m[bucket.Key] = bucket.DocCount
 			} else {
@@ -295,9 +564,58 @@ func (c *Client) Search(ctx context.Context, indices []string, requestBody map[s
 		Aggregations: aggregations,
 		Total:        esResponse.Hits.Total.Value,
 		TotalExact:   esResponse.Hits.Total.Relation == "eq",
+		PitID:        esResponse.PitID,
+		LastSort:     lastSort,
+		Refs:         refs,
 	}, nil
 }
 
+// OpenPointInTime creates a point-in-time over the indices, so that a search
+// can be paged through consistently while documents are being indexed
+func (c *Client) OpenPointInTime(ctx context.Context, indices []string, keepAlive string) (string, error) {
+	endpoint := fmt.Sprintf("%s/_search/point_in_time?keep_alive=%s", strings.Join(indices, ","), keepAlive)
+
+	resp, err := c.doRequest(ctx, http.MethodPost, endpoint, nil, "")
+	if err != nil {
+		return "", err
+	}
+	defer resp.Body.Close() //nolint:errcheck // no need to check error when closing body
+
+	if resp.StatusCode != http.StatusOK {
+		data, _ := io.ReadAll(resp.Body)
+		return "", fmt.Errorf(`open point in time failed with status code %d and response: "%s"`, resp.StatusCode, string(data))
+	}
+
+	var v struct {
+		PitID string `json:"pit_id"`
+	}
+	if err := json.NewDecoder(resp.Body).Decode(&v); err != nil {
+		return "", fmt.Errorf("error parsing the response body: %w", err)
+	}
+
+	return v.PitID, nil
+}
+
+func (c *Client) ClosePointInTime(ctx context.Context, pitID string) error {
+	request, err := json.Marshal(map[string][]string{"pit_id": {pitID}})
+	if err != nil {
+		return err
+	}
+
+	resp, err := c.doRequest(ctx, http.MethodDelete, "_search/point_in_time", bytes.NewReader(request), "application/json")
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close() //nolint:errcheck // no need to check error when closing body
+
+	if resp.StatusCode != http.StatusOK {
+		data, _ := io.ReadAll(resp.Body)
+		return fmt.Errorf(`close point in time failed with status code %d and response: "%s"`, resp.StatusCode, string(data))
+	}
+
+	return nil
+}
+
 func (c *Client) CreateIndex(ctx context.Context, name string, config []byte, force bool) error {
 	c.logger.Printf("Checking index '%s' exists", name)
 	exists, err := c.IndexExists(ctx, name)
@@ -374,6 +692,132 @@ func (c *Client) DeleteIndex(ctx context.Context, name string) error {
 	return nil
 }
 
+// Refresh makes the documents written to the index visible to searches
+func (c *Client) Refresh(ctx context.Context, name string) error {
+	resp, err := c.doRequest(ctx, http.MethodPost, name+"/_refresh", nil, "")
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close() //nolint:errcheck // no need to check error when closing body
+
+	if resp.StatusCode != http.StatusOK {
+		data, _ := io.ReadAll(resp.Body)
+		return fmt.Errorf(`refresh failed with status code %d and response: "%s"`, resp.StatusCode, string(data))
+	}
+
+	return nil
+}
+
+func (c *Client) Count(ctx context.Context, name string) (int, error) {
+	resp, err := c.doRequest(ctx, http.MethodGet, name+"/_count", nil, "")
+	if err != nil {
+		return 0, err
+	}
+	defer resp.Body.Close() //nolint:errcheck // no need to check error when closing body
+
+	if resp.StatusCode != http.StatusOK {
+		data, _ := io.ReadAll(resp.Body)
+		return 0, fmt.Errorf(`count failed with status code %d and response: "%s"`, resp.StatusCode, string(data))
+	}
+
+	var v struct {
+		Count int `json:"count"`
+	}
+	if err := json.NewDecoder(resp.Body).Decode(&v); err != nil {
+		return 0, fmt.Errorf("error parsing the response body: %w", err)
+	}
+
+	return v.Count, nil
+}
+
+// reindexPollInterval is how often a reindex task is checked for completion
+const reindexPollInterval = 5 * time.Second
+
+// Reindex copies every document from the source index to the dest index,
+// waiting for the task to complete. External versions are kept, so a document
+// that has since been written to dest with a newer version is not replaced.
+func (c *Client) Reindex(ctx context.Context, source, dest string) error {
+	request, err := json.Marshal(map[string]interface{}{
+		"conflicts": "proceed",
+		"source":    map[string]interface{}{"index": source},
+		"dest":      map[string]interface{}{"index": dest, "version_type": "external"},
+	})
+	if err != nil {
+		return err
+	}
+
+	resp, err := c.doRequestOnce(ctx, http.MethodPost, "_reindex?wait_for_completion=false", bytes.NewReader(request), "application/json")
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close() //nolint:errcheck // no need to check error when closing body
+
+	if resp.StatusCode != http.StatusOK {
+		data, _ := io.ReadAll(resp.Body)
+		return fmt.Errorf(`reindex failed with status code %d and response: "%s"`, resp.StatusCode, string(data))
+	}
+
+	var v struct {
+		Task string `json:"task"`
+	}
+	if err := json.NewDecoder(resp.Body).Decode(&v); err != nil {
+		return fmt.Errorf("error parsing the response body: %w", err)
+	}
+
+	c.logger.Printf("reindexing '%s' to '%s' as task '%s'", source, dest, v.Task)
+
+	for {
+		done, err := c.reindexTask(ctx, v.Task)
+		if err != nil || done {
+			return err
+		}
+
+		if err := c.sleep(ctx, reindexPollInterval); err != nil {
+			return err
+		}
+	}
+}
+
+func (c *Client) reindexTask(ctx context.Context, task string) (bool, error) {
+	resp, err := c.doRequest(ctx, http.MethodGet, "_tasks/"+task, nil, "")
+	if err != nil {
+		return false, err
+	}
+	defer resp.Body.Close() //nolint:errcheck // no need to check error when closing body
+
+	if resp.StatusCode != http.StatusOK {
+		data, _ := io.ReadAll(resp.Body)
+		return false, fmt.Errorf(`reindex task check failed with status code %d and response: "%s"`, resp.StatusCode, string(data))
+	}
+
+	var v struct {
+		Completed bool             `json:"completed"`
+		Error     *json.RawMessage `json:"error"`
+		Response  struct {
+			Total    int               `json:"total"`
+			Failures []json.RawMessage `json:"failures"`
+		} `json:"response"`
+	}
+	if err := json.NewDecoder(resp.Body).Decode(&v); err != nil {
+		return false, fmt.Errorf("error parsing the response body: %w", err)
+	}
+
+	if !v.Completed {
+		return false, nil
+	}
+
+	if v.Error != nil {
+		return true, fmt.Errorf("reindex task '%s' failed: %s", task, string(*v.Error))
+	}
+
+	if len(v.Response.Failures) > 0 {
+		return true, fmt.Errorf("reindex task '%s' failed for %d documents, first failure: %s", task, len(v.Response.Failures), string(v.Response.Failures[0]))
+	}
+
+	c.logger.Printf("reindex task '%s' completed total=%d", task, v.Response.Total)
+	return true, nil
+}
+
 func (c *Client) ResolveAlias(ctx context.Context, name string) (string, error) {
 	resp, err := c.doRequest(ctx, http.MethodGet, "_alias/"+name, nil, "")
 	if err != nil {
@@ -496,7 +940,7 @@ func (c *Client) Delete(ctx context.Context, indices []string, requestBody map[s
 	}
 	body := bytes.NewReader(buf.Bytes())
 
-	resp, err := c.doRequest(ctx, http.MethodPost, endpoint, body, "application/json")
+	resp, err := c.doRequestOnce(ctx, http.MethodPost, endpoint, body, "application/json")
 	if err != nil {
 		return nil, err
 	}
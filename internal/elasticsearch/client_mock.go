@@ -38,3 +38,13 @@ func (m *MockESClient) CreateAlias(ctx context.Context, alias string, index stri
 	args := m.Called(ctx, alias, index)
 	return args.Error(0)
 }
+
+func (m *MockESClient) OpenPointInTime(ctx context.Context, indices []string, keepAlive string) (string, error) {
+	args := m.Called(ctx, indices, keepAlive)
+	return args.String(0), args.Error(1)
+}
+
+func (m *MockESClient) ClosePointInTime(ctx context.Context, pitID string) error {
+	args := m.Called(ctx, pitID)
+	return args.Error(0)
+}
@@ -12,6 +12,7 @@ import (
 	"regexp"
 	"strings"
 	"testing"
+	"time"
 
 	logrus_test "github.com/sirupsen/logrus/hooks/test"
 	"github.com/stretchr/testify/assert"
@@ -44,8 +45,11 @@ func TestClient_DoBulkIndex(t *testing.T) {
 			esResponseError:    nil,
 			expectedStatusCode: 200,
 			expectedResponse:   `{"errors":false,"items":[{"index":{"_id":"12","status":200}}]}`,
-			expectedResult:     BulkResult{Successful: 1},
-			expectedLogs:       []string{},
+			expectedResult: BulkResult{
+				Successful: 1,
+				Results:    []IndexResult{{Id: "12", StatusCode: 200}},
+			},
+			expectedLogs: []string{},
 		},
 		{
 			scenario:           "Index request failure",
@@ -62,8 +66,61 @@ func TestClient_DoBulkIndex(t *testing.T) {
 			esResponseError:    nil,
 			expectedStatusCode: 200,
 			expectedResponse:   `{"errors":true,"items":[{"index":{"_id":"12","status":400}}]}`,
-			expectedResult:     BulkResult{Failed: 1},
-			expectedLogs:       []string{},
+			expectedResult: BulkResult{
+				Failed:  1,
+				Results: []IndexResult{{Id: "12", StatusCode: 400}},
+			},
+			expectedLogs: []string{},
+		},
+		{
+			scenario:           "Document updated by update action",
+			esResponseError:    nil,
+			expectedStatusCode: 200,
+			expectedResponse:   `{"errors":false,"items":[{"update":{"_id":"12","status":200}}]}`,
+			expectedResult: BulkResult{
+				Successful: 1,
+				Results:    []IndexResult{{Id: "12", StatusCode: 200}},
+			},
+			expectedLogs: []string{},
+		},
+		{
+			scenario:           "Document not found by delete action",
+			esResponseError:    nil,
+			expectedStatusCode: 200,
+			expectedResponse:   `{"errors":false,"items":[{"delete":{"_id":"12","status":404,"result":"not_found"}}]}`,
+			expectedResult: BulkResult{
+				Failed:  1,
+				Error:   "not_found",
+				Results: []IndexResult{{Id: "12", StatusCode: 404, Message: "not_found"}},
+			},
+			expectedLogs: []string{},
+		},
+		{
+			scenario:           "Document version conflict",
+			esResponseError:    nil,
+			expectedStatusCode: 200,
+			expectedResponse:   `{"errors":true,"items":[{"index":{"_id":"12","status":409,"error":{"type":"version_conflict_engine_exception","reason":"version conflict"}}}]}`,
+			expectedResult: BulkResult{
+				Conflicts: 1,
+				Results:   []IndexResult{{Id: "12", StatusCode: 409, Message: "version_conflict_engine_exception: version conflict"}},
+			},
+			expectedLogs: []string{},
+		},
+		{
+			scenario:           "Document failure with reason",
+			esResponseError:    nil,
+			expectedStatusCode: 200,
+			expectedResponse:   `{"errors":true,"items":[{"index":{"_index":"person_abc","_id":"12","status":201}},{"index":{"_index":"person_abc","_id":"13","status":400,"error":{"type":"mapper_parsing_exception","reason":"failed to parse"}}}]}`,
+			expectedResult: BulkResult{
+				Successful: 1,
+				Failed:     1,
+				Error:      "mapper_parsing_exception: failed to parse",
+				Results: []IndexResult{
+					{Id: "12", Index: "person_abc", StatusCode: 201},
+					{Id: "13", Index: "person_abc", StatusCode: 400, Message: "mapper_parsing_exception: failed to parse"},
+				},
+			},
+			expectedLogs: []string{},
 		},
 	}
 
@@ -134,6 +191,7 @@ func TestClient_DoBulkIndexWithRetry(t *testing.T) {
 
 	assert.IsType(&Client{}, c)
 	assert.Nil(err)
+	c.sleep = func(ctx context.Context, d time.Duration) error { return nil }
 
 	mc.On("Do", mock.AnythingOfType("*http.Request")).
 		Run(func(args mock.Arguments) {
@@ -182,7 +240,7 @@ func TestClient_DoBulkIndexWithRetry(t *testing.T) {
 	result, err := c.DoBulk(context.Background(), op)
 
 	assert.Nil(err)
-	assert.Equal(BulkResult{Successful: 1}, result)
+	assert.Equal(BulkResult{Successful: 1, Results: []IndexResult{{Id: "12", StatusCode: 200}}}, result)
 }
 
 func TestClientCreateIndex(t *testing.T) {
@@ -397,7 +455,7 @@ func TestClient_Search(t *testing.T) {
 			scenario:          "Search returns matches",
 			esResponseError:   nil,
 			esResponseCode:    200,
-			esResponseMessage: `{"hits":{"hits":[{"_index":"person_foo1111","_source":{"id":1,"name":"test1"}},{"_index":"person_foo1111","_source":{"id":2,"name":"test1"}}]},"aggregations":{"personType":{"buckets":[{"key":"donor","doc_count":2}]}}}`,
+			esResponseMessage: `{"hits":{"hits":[{"_index":"person_foo1111","_id":"1","_source":{"id":1,"name":"test1"}},{"_index":"person_foo1111","_id":"2","_source":{"id":2,"name":"test1"}}]},"aggregations":{"personType":{"buckets":[{"key":"donor","doc_count":2}]}}}`,
 			expectedError:     nil,
 			expectedResult: &SearchResult{
 				Hits: []json.RawMessage{
@@ -409,6 +467,39 @@ func TestClient_Search(t *testing.T) {
 						"donor": 2,
 					},
 				},
+				Refs: []DocumentRef{
+					{Index: "person_foo1111", ID: "1"},
+					{Index: "person_foo1111", ID: "2"},
+				},
+			},
+		},
+		{
+			scenario:          "Search returns filtered aggregation",
+			esResponseError:   nil,
+			esResponseCode:    200,
+			esResponseMessage: `{"hits":{"hits":[]},"aggregations":{"personType":{"buckets":[{"key":"donor","doc_count":3}]},"indices":{"doc_count":2,"indices":{"buckets":[{"key":"person_foo1111","doc_count":2}]}}}}`,
+			expectedError:     nil,
+			expectedResult: &SearchResult{
+				Hits: []json.RawMessage{},
+				Aggregations: map[string]map[string]int{
+					"personType": {"donor": 3},
+					"indices":    {"person_foo1111": 2},
+				},
+				Refs: []DocumentRef{},
+			},
+		},
+		{
+			scenario:          "Search returns matches with highlights",
+			esResponseError:   nil,
+			esResponseCode:    200,
+			esResponseMessage: `{"hits":{"hits":[{"_index":"person_foo1111","_id":"1","_source":{"id":1,"name":"test1"},"highlight":{"name":["<em>test1</em>"]}}]}}`,
+			expectedError:     nil,
+			expectedResult: &SearchResult{
+				Hits: []json.RawMessage{
+					[]byte(`{"_highlight":{"name":["\u003cem\u003etest1\u003c/em\u003e"]},"_index":"person","id":1,"name":"test1"}`),
+				},
+				Aggregations: map[string]map[string]int{},
+				Refs:         []DocumentRef{{Index: "person_foo1111", ID: "1"}},
 			},
 		},
 		{
@@ -420,6 +511,7 @@ func TestClient_Search(t *testing.T) {
 			expectedResult: &SearchResult{
 				Hits:         []json.RawMessage{},
 				Aggregations: map[string]map[string]int{},
+				Refs:         []DocumentRef{},
 			},
 		},
 		{
@@ -676,3 +768,250 @@ func TestClientSignRequest(t *testing.T) {
 	_, err = client.doRequest(context.Background(), http.MethodGet, "_healthcheck", bytes.NewReader([]byte{}), "application/json")
 	assert.Nil(err)
 }
+
+func TestClient_SearchPointInTime(t *testing.T) {
+	assert := assert.New(t)
+
+	mc := new(MockHttpClient)
+	l, _ := logrus_test.NewNullLogger()
+
+	_ = os.Setenv("AWS_ACCESS_KEY_ID", "test")
+	_ = os.Setenv("AWS_SECRET_ACCESS_KEY", "test")
+	cfg, _ := config.LoadDefaultConfig(context.Background())
+	c, _ := NewClient(mc, l, &cfg)
+
+	mc.
+		On("Do", mock.MatchedBy(func(req *http.Request) bool {
+			return req.Method == http.MethodPost &&
+				req.URL.String() == os.Getenv("AWS_ELASTICSEARCH_ENDPOINT")+"/_search"
+		})).
+		Return(&http.Response{
+			StatusCode: http.StatusOK,
+			Body:       io.NopCloser(strings.NewReader(`{"pit_id":"pit-2","hits":{"hits":[{"_index":"person_foo1111","_source":{"id":1},"sort":[1.5,"1"]},{"_index":"person_foo1111","_source":{"id":2},"sort":[1.2,"2"]}]}}`)),
+		}, nil).
+		Once()
+
+	result, err := c.Search(context.Background(), nil, map[string]interface{}{"pit": map[string]interface{}{"id": "pit-1"}})
+	assert.Nil(err)
+	assert.Equal("pit-2", result.PitID)
+	assert.Equal([]interface{}{1.2, "2"}, result.LastSort)
+}
+
+func TestClient_OpenPointInTime(t *testing.T) {
+	assert := assert.New(t)
+
+	mc := new(MockHttpClient)
+	l, _ := logrus_test.NewNullLogger()
+
+	_ = os.Setenv("AWS_ACCESS_KEY_ID", "test")
+	_ = os.Setenv("AWS_SECRET_ACCESS_KEY", "test")
+	cfg, _ := config.LoadDefaultConfig(context.Background())
+	c, _ := NewClient(mc, l, &cfg)
+
+	mc.
+		On("Do", mock.MatchedBy(func(req *http.Request) bool {
+			return req.Method == http.MethodPost &&
+				req.URL.String() == os.Getenv("AWS_ELASTICSEARCH_ENDPOINT")+"/person,firm/_search/point_in_time?keep_alive=5m"
+		})).
+		Return(&http.Response{StatusCode: http.StatusOK, Body: io.NopCloser(strings.NewReader(`{"pit_id":"abc"}`))}, nil).
+		Once()
+
+	mc.
+		On("Do", mock.MatchedBy(func(req *http.Request) bool {
+			data, _ := io.ReadAll(req.Body)
+
+			return req.Method == http.MethodDelete &&
+				req.URL.String() == os.Getenv("AWS_ELASTICSEARCH_ENDPOINT")+"/_search/point_in_time" &&
+				string(data) == `{"pit_id":["abc"]}`
+		})).
+		Return(&http.Response{StatusCode: http.StatusOK, Body: io.NopCloser(strings.NewReader(`{}`))}, nil).
+		Once()
+
+	pitID, err := c.OpenPointInTime(context.Background(), []string{"person", "firm"}, "5m")
+	assert.Nil(err)
+	assert.Equal("abc", pitID)
+
+	assert.Nil(c.ClosePointInTime(context.Background(), pitID))
+	mc.AssertExpectations(t)
+}
+
+type versionedDoc struct {
+	A       string `json:"a"`
+	version *int64
+}
+
+func (d versionedDoc) ExternalVersion() (int64, bool) {
+	if d.version == nil {
+		return 0, false
+	}
+
+	return *d.version, true
+}
+
+func TestBulkOpVersioned(t *testing.T) {
+	version := int64(1609675200000)
+
+	op := NewBulkOp("test")
+	err := op.Index("1", versionedDoc{A: "b", version: &version})
+	assert.Nil(t, err)
+	err = op.Index("2", versionedDoc{A: "c"})
+	assert.Nil(t, err)
+
+	assert.Equal(t, `{"index":{"_id":"1","version":1609675200000,"version_type":"external_gte"}}
+{"a":"b"}
+{"index":{"_id":"2"}}
+{"a":"c"}
+`, op.buf.String())
+}
+
+type mergedDoc struct {
+	Cases []map[string]string `json:"cases"`
+}
+
+func (mergedDoc) ArrayKeys() map[string]string {
+	return map[string]string{"cases": "uId"}
+}
+
+func TestBulkOpUpdate(t *testing.T) {
+	op := NewBulkOp("test")
+	err := op.Update("1", map[string]interface{}{"a": 1})
+	assert.Nil(t, err)
+	err = op.Update("2", mergedDoc{Cases: []map[string]string{{"uId": "7000-0000-0001"}}})
+	assert.Nil(t, err)
+
+	script, _ := json.Marshal(mergeScript)
+
+	assert.Equal(t, `{"update":{"_id":"1"}}
+{"script":{"source":`+string(script)+`,"lang":"painless","params":{"doc":{"a":1},"arrayKeys":{}}}}
+{"update":{"_id":"2"}}
+{"script":{"source":`+string(script)+`,"lang":"painless","params":{"doc":{"cases":[{"uId":"7000-0000-0001"}]},"arrayKeys":{"cases":"uId"}}}}
+`, op.buf.String())
+}
+
+func TestBulkOpDelete(t *testing.T) {
+	op := NewBulkOp("test")
+	assert.Nil(t, op.Delete("1"))
+	assert.Nil(t, op.Index("2", map[string]interface{}{"a": 1}))
+
+	assert.Equal(t, `{"delete":{"_id":"1"}}
+{"index":{"_id":"2"}}
+{"a":1}
+`, op.buf.String())
+}
+
+func TestBulkOpLenAndSize(t *testing.T) {
+	op := NewBulkOp("test")
+	assert.Equal(t, 0, op.Len())
+
+	assert.Nil(t, op.Index("1", map[string]interface{}{"a": 1}))
+	assert.Nil(t, op.Delete("2"))
+
+	assert.Equal(t, 2, op.Len())
+	assert.Equal(t, len(`{"index":{"_id":"1"}}`+"\n"+`{"a":1}`+"\n"+`{"delete":{"_id":"2"}}`+"\n"), op.Size())
+
+	op.Reset()
+	assert.Equal(t, 0, op.Len())
+}
+
+func TestClient_RefreshAndCount(t *testing.T) {
+	assert := assert.New(t)
+
+	mc := new(MockHttpClient)
+	l, _ := logrus_test.NewNullLogger()
+
+	_ = os.Setenv("AWS_ACCESS_KEY_ID", "test")
+	_ = os.Setenv("AWS_SECRET_ACCESS_KEY", "test")
+	cfg, _ := config.LoadDefaultConfig(context.Background())
+	c, _ := NewClient(mc, l, &cfg)
+
+	mc.
+		On("Do", mock.MatchedBy(func(req *http.Request) bool {
+			return req.Method == http.MethodPost &&
+				req.URL.String() == os.Getenv("AWS_ELASTICSEARCH_ENDPOINT")+"/person_a/_refresh"
+		})).
+		Return(&http.Response{StatusCode: http.StatusOK, Body: io.NopCloser(strings.NewReader(`{}`))}, nil).
+		Once()
+
+	mc.
+		On("Do", mock.MatchedBy(func(req *http.Request) bool {
+			return req.Method == http.MethodGet &&
+				req.URL.String() == os.Getenv("AWS_ELASTICSEARCH_ENDPOINT")+"/person_a/_count"
+		})).
+		Return(&http.Response{StatusCode: http.StatusOK, Body: io.NopCloser(strings.NewReader(`{"count":12}`))}, nil).
+		Once()
+
+	assert.Nil(c.Refresh(context.Background(), "person_a"))
+
+	count, err := c.Count(context.Background(), "person_a")
+	assert.Nil(err)
+	assert.Equal(12, count)
+	mc.AssertExpectations(t)
+}
+
+func TestClient_Reindex(t *testing.T) {
+	assert := assert.New(t)
+
+	mc := new(MockHttpClient)
+	l, _ := logrus_test.NewNullLogger()
+
+	_ = os.Setenv("AWS_ACCESS_KEY_ID", "test")
+	_ = os.Setenv("AWS_SECRET_ACCESS_KEY", "test")
+	cfg, _ := config.LoadDefaultConfig(context.Background())
+	c, _ := NewClient(mc, l, &cfg)
+
+	var slept []time.Duration
+	c.sleep = func(ctx context.Context, d time.Duration) error {
+		slept = append(slept, d)
+		return nil
+	}
+
+	mc.
+		On("Do", mock.MatchedBy(func(req *http.Request) bool {
+			data, _ := io.ReadAll(req.Body)
+
+			return req.Method == http.MethodPost &&
+				req.URL.String() == os.Getenv("AWS_ELASTICSEARCH_ENDPOINT")+"/_reindex?wait_for_completion=false" &&
+				string(data) == `{"conflicts":"proceed","dest":{"index":"person_b","version_type":"external"},"source":{"index":"person_a"}}`
+		})).
+		Return(&http.Response{StatusCode: http.StatusOK, Body: io.NopCloser(strings.NewReader(`{"task":"node:1"}`))}, nil).
+		Once()
+
+	isTask := mock.MatchedBy(func(req *http.Request) bool {
+		return req.Method == http.MethodGet &&
+			req.URL.String() == os.Getenv("AWS_ELASTICSEARCH_ENDPOINT")+"/_tasks/node:1"
+	})
+	mc.
+		On("Do", isTask).
+		Return(&http.Response{StatusCode: http.StatusOK, Body: io.NopCloser(strings.NewReader(`{"completed":false}`))}, nil).
+		Once()
+	mc.
+		On("Do", isTask).
+		Return(&http.Response{StatusCode: http.StatusOK, Body: io.NopCloser(strings.NewReader(`{"completed":true,"response":{"total":3,"failures":[]}}`))}, nil).
+		Once()
+
+	assert.Nil(c.Reindex(context.Background(), "person_a", "person_b"))
+	assert.Equal([]time.Duration{reindexPollInterval}, slept)
+	mc.AssertExpectations(t)
+}
+
+func TestClient_ReindexFailures(t *testing.T) {
+	mc := new(MockHttpClient)
+	l, _ := logrus_test.NewNullLogger()
+
+	_ = os.Setenv("AWS_ACCESS_KEY_ID", "test")
+	_ = os.Setenv("AWS_SECRET_ACCESS_KEY", "test")
+	cfg, _ := config.LoadDefaultConfig(context.Background())
+	c, _ := NewClient(mc, l, &cfg)
+
+	mc.
+		On("Do", mock.MatchedBy(func(req *http.Request) bool { return req.Method == http.MethodPost })).
+		Return(&http.Response{StatusCode: http.StatusOK, Body: io.NopCloser(strings.NewReader(`{"task":"node:1"}`))}, nil).
+		Once()
+	mc.
+		On("Do", mock.MatchedBy(func(req *http.Request) bool { return req.Method == http.MethodGet })).
+		Return(&http.Response{StatusCode: http.StatusOK, Body: io.NopCloser(strings.NewReader(`{"completed":true,"response":{"failures":[{"id":"1"}]}}`))}, nil).
+		Once()
+
+	err := c.Reindex(context.Background(), "person_a", "person_b")
+	assert.Equal(t, `reindex task 'node:1' failed for 1 documents, first failure: {"id":"1"}`, err.Error())
+}
@@ -1,7 +1,23 @@
 package elasticsearch
 
+import "net/http"
+
+// IndexResult is the outcome of indexing a single document in a bulk request.
+// Index is the index it was written to, as a request may write the same
+// document to more than one index.
 type IndexResult struct {
-	Id         int64  `json:"id"`
+	Id         string `json:"id"`
+	Index      string `json:"index"`
 	StatusCode int    `json:"statusCode"`
-	Message    string `json:"message"`
+	Message    string `json:"message,omitempty"`
+}
+
+func (r IndexResult) Successful() bool {
+	return r.StatusCode == http.StatusOK || r.StatusCode == http.StatusCreated
+}
+
+// Conflict is true when the document was not written as the index already has
+// a newer version
+func (r IndexResult) Conflict() bool {
+	return r.StatusCode == http.StatusConflict
 }
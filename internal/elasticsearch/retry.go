@@ -0,0 +1,188 @@
+package elasticsearch
+
+import (
+	"context"
+	"errors"
+	"io"
+	"math/rand/v2"
+	"net/http"
+	"strconv"
+	"sync"
+	"syscall"
+	"time"
+)
+
+var ErrCircuitOpen = errors.New("circuit breaker is open, opensearch is not accepting requests")
+
+// RetryPolicy controls how a request that failed because the cluster was busy
+// or unavailable is retried
+type RetryPolicy struct {
+	// MaxRetries is the number of times a request is retried after the first
+	// attempt
+	MaxRetries int
+	// BaseDelay is the delay before the first retry, which doubles for each
+	// retry up to MaxDelay. The delay used is a random duration up to this, so
+	// that clients retrying at the same time are spread out.
+	BaseDelay time.Duration
+	MaxDelay  time.Duration
+	// BreakerThreshold is the number of failed attempts in a row after which
+	// requests are refused, without being sent, for BreakerCooldown
+	BreakerThreshold int
+	BreakerCooldown  time.Duration
+}
+
+var DefaultRetryPolicy = RetryPolicy{
+	MaxRetries:       10,
+	BaseDelay:        500 * time.Millisecond,
+	MaxDelay:         30 * time.Second,
+	BreakerThreshold: 20,
+	BreakerCooldown:  30 * time.Second,
+}
+
+// backoff returns the delay before the given retry, counting from zero
+func (p RetryPolicy) backoff(retry int, jitter func(time.Duration) time.Duration) time.Duration {
+	delay := p.MaxDelay
+	if retry < 32 && p.BaseDelay<<retry < p.MaxDelay {
+		delay = p.BaseDelay << retry
+	}
+
+	return jitter(delay)
+}
+
+func fullJitter(d time.Duration) time.Duration {
+	if d <= 0 {
+		return 0
+	}
+
+	return time.Duration(rand.Int64N(int64(d) + 1))
+}
+
+// retryable reports whether the attempt failed in a way that may succeed if
+// it is tried again
+func retryable(resp *http.Response, err error) bool {
+	if err != nil {
+		return errors.Is(err, syscall.ECONNRESET) ||
+			errors.Is(err, syscall.ECONNREFUSED) ||
+			errors.Is(err, io.EOF) ||
+			errors.Is(err, io.ErrUnexpectedEOF)
+	}
+
+	switch resp.StatusCode {
+	case http.StatusTooManyRequests, http.StatusBadGateway, http.StatusServiceUnavailable, http.StatusGatewayTimeout:
+		return true
+	}
+
+	return false
+}
+
+// unhealthy reports whether the attempt counts as a failure of the cluster for
+// the circuit breaker, which includes errors that are not worth retrying such
+// as a timeout
+func unhealthy(resp *http.Response, err error) bool {
+	if err != nil {
+		return true
+	}
+
+	return resp.StatusCode == http.StatusTooManyRequests || resp.StatusCode >= http.StatusInternalServerError
+}
+
+// retryAfter reads the Retry-After header, which is either a number of
+// seconds or a date
+func retryAfter(resp *http.Response, now time.Time) (time.Duration, bool) {
+	if resp == nil {
+		return 0, false
+	}
+
+	value := resp.Header.Get("Retry-After")
+	if value == "" {
+		return 0, false
+	}
+
+	if seconds, err := strconv.Atoi(value); err == nil && seconds >= 0 {
+		return time.Duration(seconds) * time.Second, true
+	}
+
+	if t, err := http.ParseTime(value); err == nil {
+		if d := t.Sub(now); d > 0 {
+			return d, true
+		}
+		return 0, true
+	}
+
+	return 0, false
+}
+
+func sleepContext(ctx context.Context, d time.Duration) error {
+	timer := time.NewTimer(d)
+	defer timer.Stop()
+
+	select {
+	case <-ctx.Done():
+		return ctx.Err()
+	case <-timer.C:
+		return nil
+	}
+}
+
+// circuitBreaker stops requests being sent once too many have failed in a
+// row, after the cooldown a single request is let through and the breaker
+// closes again if it succeeds
+type circuitBreaker struct {
+	mu        sync.Mutex
+	threshold int
+	cooldown  time.Duration
+	now       func() time.Time
+	failures  int
+	openUntil time.Time
+	probing   bool
+}
+
+func newCircuitBreaker(threshold int, cooldown time.Duration) *circuitBreaker {
+	return &circuitBreaker{
+		threshold: threshold,
+		cooldown:  cooldown,
+		now:       time.Now,
+	}
+}
+
+func (b *circuitBreaker) allow() error {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	if b.threshold <= 0 || b.failures < b.threshold {
+		return nil
+	}
+
+	if b.probing || b.now().Before(b.openUntil) {
+		return ErrCircuitOpen
+	}
+
+	b.probing = true
+	return nil
+}
+
+// release lets another request probe the cluster, when the probe ended
+// without an outcome such as by its context being cancelled
+func (b *circuitBreaker) release() {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	b.probing = false
+}
+
+func (b *circuitBreaker) record(failed bool) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	b.probing = false
+
+	if !failed {
+		b.failures = 0
+		return
+	}
+
+	b.failures++
+	if b.threshold > 0 && b.failures >= b.threshold {
+		b.openUntil = b.now().Add(b.cooldown)
+	}
+}
@@ -0,0 +1,226 @@
+package elasticsearch
+
+import (
+	"context"
+	"errors"
+	"io"
+	"net/http"
+	"os"
+	"strings"
+	"syscall"
+	"testing"
+	"time"
+
+	"github.com/aws/aws-sdk-go-v2/config"
+	logrus_test "github.com/sirupsen/logrus/hooks/test"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+)
+
+func newRetryTestClient(t *testing.T, policy RetryPolicy) (*Client, *MockHttpClient, *[]time.Duration) {
+	mc := new(MockHttpClient)
+	l, _ := logrus_test.NewNullLogger()
+
+	_ = os.Setenv("AWS_ACCESS_KEY_ID", "test")
+	_ = os.Setenv("AWS_SECRET_ACCESS_KEY", "test")
+	cfg, _ := config.LoadDefaultConfig(context.Background())
+	c, err := NewClient(mc, l, &cfg)
+	assert.Nil(t, err)
+
+	var delays []time.Duration
+	c.SetRetryPolicy(policy)
+	c.jitter = func(d time.Duration) time.Duration { return d }
+	c.sleep = func(ctx context.Context, d time.Duration) error {
+		delays = append(delays, d)
+		return ctx.Err()
+	}
+
+	return c, mc, &delays
+}
+
+func statusResponse(code int, header http.Header) *http.Response {
+	return &http.Response{StatusCode: code, Header: header, Body: io.NopCloser(strings.NewReader(""))}
+}
+
+var testRetryPolicy = RetryPolicy{
+	MaxRetries:       3,
+	BaseDelay:        time.Second,
+	MaxDelay:         3 * time.Second,
+	BreakerThreshold: 10,
+	BreakerCooldown:  time.Minute,
+}
+
+func TestDoRequestRetriesWithBackoff(t *testing.T) {
+	c, mc, delays := newRetryTestClient(t, testRetryPolicy)
+
+	mc.On("Do", mock.Anything).Return(statusResponse(http.StatusServiceUnavailable, nil), nil).Once()
+	mc.On("Do", mock.Anything).Return(statusResponse(http.StatusBadGateway, nil), nil).Once()
+	mc.On("Do", mock.Anything).Return((*http.Response)(nil), syscall.ECONNRESET).Once()
+	mc.On("Do", mock.Anything).Return(statusResponse(http.StatusOK, nil), nil).Once()
+
+	resp, err := c.doRequest(context.Background(), http.MethodPost, "x/_search", strings.NewReader("{}"), "application/json")
+	assert.Nil(t, err)
+	assert.Equal(t, http.StatusOK, resp.StatusCode)
+	assert.Equal(t, []time.Duration{time.Second, 2 * time.Second, 3 * time.Second}, *delays)
+	mc.AssertExpectations(t)
+}
+
+func TestDoRequestReturnsLastResponseWhenRetriesExhausted(t *testing.T) {
+	c, mc, delays := newRetryTestClient(t, testRetryPolicy)
+
+	mc.On("Do", mock.Anything).Return(statusResponse(http.StatusTooManyRequests, nil), nil).Times(4)
+
+	resp, err := c.doRequest(context.Background(), http.MethodGet, "_alias/x", nil, "")
+	assert.Nil(t, err)
+	assert.Equal(t, http.StatusTooManyRequests, resp.StatusCode)
+	assert.Len(t, *delays, 3)
+	mc.AssertExpectations(t)
+}
+
+func TestNonIdempotentRequestsAreNotRetried(t *testing.T) {
+	c, mc, delays := newRetryTestClient(t, testRetryPolicy)
+
+	mc.On("Do", mock.MatchedBy(func(r *http.Request) bool { return strings.HasSuffix(r.URL.Path, "/_reindex") })).
+		Return(statusResponse(http.StatusServiceUnavailable, nil), nil).Once()
+	mc.On("Do", mock.MatchedBy(func(r *http.Request) bool { return strings.HasSuffix(r.URL.Path, "/_delete_by_query") })).
+		Return((*http.Response)(nil), syscall.ECONNRESET).Once()
+
+	err := c.Reindex(context.Background(), "person_a", "person_b")
+	assert.ErrorContains(t, err, "reindex failed with status code 503")
+
+	_, err = c.Delete(context.Background(), []string{"person"}, map[string]interface{}{})
+	assert.ErrorIs(t, err, syscall.ECONNRESET)
+
+	assert.Empty(t, *delays)
+	mc.AssertExpectations(t)
+}
+
+func TestDoRequestDoesNotRetryClientErrors(t *testing.T) {
+	c, mc, delays := newRetryTestClient(t, testRetryPolicy)
+
+	mc.On("Do", mock.Anything).Return(statusResponse(http.StatusBadRequest, nil), nil).Once()
+
+	resp, err := c.doRequest(context.Background(), http.MethodGet, "_alias/x", nil, "")
+	assert.Nil(t, err)
+	assert.Equal(t, http.StatusBadRequest, resp.StatusCode)
+	assert.Len(t, *delays, 0)
+}
+
+func TestDoRequestHonoursRetryAfter(t *testing.T) {
+	c, mc, delays := newRetryTestClient(t, testRetryPolicy)
+
+	mc.On("Do", mock.Anything).Return(statusResponse(http.StatusTooManyRequests, http.Header{"Retry-After": {"7"}}), nil).Once()
+	mc.On("Do", mock.Anything).Return(statusResponse(http.StatusOK, nil), nil).Once()
+
+	_, err := c.doRequest(context.Background(), http.MethodGet, "_alias/x", nil, "")
+	assert.Nil(t, err)
+	assert.Equal(t, []time.Duration{7 * time.Second}, *delays)
+}
+
+func TestDoRequestStopsWhenContextCancelled(t *testing.T) {
+	c, mc, _ := newRetryTestClient(t, testRetryPolicy)
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+
+	mc.On("Do", mock.Anything).Return(statusResponse(http.StatusServiceUnavailable, nil), nil).Once()
+
+	_, err := c.doRequest(ctx, http.MethodGet, "_alias/x", nil, "")
+	assert.Equal(t, context.Canceled, err)
+	mc.AssertExpectations(t)
+}
+
+func TestDoRequestCircuitBreaker(t *testing.T) {
+	policy := testRetryPolicy
+	policy.MaxRetries = 0
+	policy.BreakerThreshold = 2
+	c, mc, _ := newRetryTestClient(t, policy)
+
+	now := time.Date(2026, 1, 2, 3, 4, 5, 0, time.UTC)
+	c.breaker.now = func() time.Time { return now }
+
+	mc.On("Do", mock.Anything).Return(statusResponse(http.StatusServiceUnavailable, nil), nil).Twice()
+
+	for i := 0; i < 2; i++ {
+		_, err := c.doRequest(context.Background(), http.MethodGet, "_alias/x", nil, "")
+		assert.Nil(t, err)
+	}
+
+	_, err := c.doRequest(context.Background(), http.MethodGet, "_alias/x", nil, "")
+	assert.Equal(t, ErrCircuitOpen, err)
+
+	now = now.Add(time.Minute)
+	mc.On("Do", mock.Anything).Return(statusResponse(http.StatusOK, nil), nil).Once()
+
+	resp, err := c.doRequest(context.Background(), http.MethodGet, "_alias/x", nil, "")
+	assert.Nil(t, err)
+	assert.Equal(t, http.StatusOK, resp.StatusCode)
+	mc.AssertExpectations(t)
+}
+
+func TestDoRequestCircuitBreakerCountsErrorsNotRetried(t *testing.T) {
+	policy := testRetryPolicy
+	policy.BreakerThreshold = 2
+	c, mc, delays := newRetryTestClient(t, policy)
+
+	mc.On("Do", mock.Anything).Return((*http.Response)(nil), errors.New("i/o timeout")).Once()
+	mc.On("Do", mock.Anything).Return(statusResponse(http.StatusInternalServerError, nil), nil).Once()
+
+	_, err := c.doRequest(context.Background(), http.MethodGet, "_alias/x", nil, "")
+	assert.NotNil(t, err)
+
+	resp, err := c.doRequest(context.Background(), http.MethodGet, "_alias/x", nil, "")
+	assert.Nil(t, err)
+	assert.Equal(t, http.StatusInternalServerError, resp.StatusCode)
+
+	_, err = c.doRequest(context.Background(), http.MethodGet, "_alias/x", nil, "")
+	assert.Equal(t, ErrCircuitOpen, err)
+	assert.Empty(t, *delays)
+	mc.AssertExpectations(t)
+}
+
+func TestCircuitBreakerAllowsOneProbe(t *testing.T) {
+	now := time.Date(2026, 1, 2, 3, 4, 5, 0, time.UTC)
+	b := newCircuitBreaker(1, time.Minute)
+	b.now = func() time.Time { return now }
+
+	b.record(true)
+	assert.Equal(t, ErrCircuitOpen, b.allow())
+
+	now = now.Add(time.Minute)
+	assert.Nil(t, b.allow())
+	assert.Equal(t, ErrCircuitOpen, b.allow())
+
+	b.record(true)
+	assert.Equal(t, ErrCircuitOpen, b.allow())
+}
+
+func TestUnhealthy(t *testing.T) {
+	assert.True(t, unhealthy(nil, errors.New("i/o timeout")))
+	assert.True(t, unhealthy(statusResponse(http.StatusInternalServerError, nil), nil))
+	assert.True(t, unhealthy(statusResponse(http.StatusTooManyRequests, nil), nil))
+	assert.False(t, unhealthy(statusResponse(http.StatusNotFound, nil), nil))
+	assert.False(t, unhealthy(statusResponse(http.StatusOK, nil), nil))
+}
+
+func TestRetryAfter(t *testing.T) {
+	now := time.Date(2026, 1, 2, 3, 4, 5, 0, time.UTC)
+
+	d, ok := retryAfter(statusResponse(http.StatusTooManyRequests, http.Header{"Retry-After": {"3"}}), now)
+	assert.True(t, ok)
+	assert.Equal(t, 3*time.Second, d)
+
+	d, ok = retryAfter(statusResponse(http.StatusTooManyRequests, http.Header{"Retry-After": {now.Add(5 * time.Second).Format(http.TimeFormat)}}), now)
+	assert.True(t, ok)
+	assert.Equal(t, 5*time.Second, d)
+
+	_, ok = retryAfter(statusResponse(http.StatusTooManyRequests, nil), now)
+	assert.False(t, ok)
+}
+
+func TestRetryable(t *testing.T) {
+	assert.True(t, retryable(nil, syscall.ECONNRESET))
+	assert.True(t, retryable(nil, io.ErrUnexpectedEOF))
+	assert.False(t, retryable(nil, errors.New("some error")))
+	assert.True(t, retryable(statusResponse(http.StatusGatewayTimeout, nil), nil))
+	assert.False(t, retryable(statusResponse(http.StatusInternalServerError, nil), nil))
+}
@@ -2,7 +2,6 @@ package firm
 
 import (
 	"context"
-	"errors"
 	"strconv"
 	"time"
 
@@ -10,12 +9,12 @@ import (
 	"github.com/ministryofjustice/opg-search-service/internal/index"
 )
 
-func NewDB(conn *pgx.Conn) *DB {
+func NewDB(conn index.Conn) *DB {
 	return &DB{conn: conn}
 }
 
 type DB struct {
-	conn *pgx.Conn
+	conn index.Conn
 }
 
 func (db *DB) QueryIDRange(ctx context.Context) (min int, max int, err error) {
@@ -34,7 +33,12 @@ func (db *DB) QueryByID(ctx context.Context, results chan<- index.Indexable, fro
 }
 
 func (db *DB) QueryFromDate(ctx context.Context, results chan<- index.Indexable, fromDate time.Time) error {
-	return errors.New("firms cannot be queried by date")
+	rows, err := db.conn.Query(ctx, makeQueryFirm(`f.updateddate >= $1`), fromDate)
+	if err != nil {
+		return err
+	}
+
+	return scan(ctx, rows, results)
 }
 
 func makeQueryFirm(whereClause string) string {
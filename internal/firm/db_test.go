@@ -151,6 +151,61 @@ func TestQueryByID(t *testing.T) {
 	assert.False(ok)
 }
 
+func TestQueryFromDate(t *testing.T) {
+	if testing.Short() {
+		t.Skip("skipping postgres test")
+		return
+	}
+
+	assert := assert.New(t)
+	ctx := context.Background()
+
+	conn, err := pgx.Connect(ctx, connectionString)
+	if !assert.Nil(err) {
+		return
+	}
+	defer conn.Close(ctx) //nolint:errcheck // no need to check DB close error in tests
+
+	schemaSql, _ := os.ReadFile("../testdata/schema.sql")
+
+	_, err = conn.Exec(ctx, string(schemaSql))
+	if !assert.Nil(err) {
+		return
+	}
+
+	_, err = conn.Exec(ctx, `
+		INSERT INTO supervision.firm (id, firmname, firmnumber, updateddate)
+		VALUES (1, 'old firm', 1, '2020-12-31 23:00:00'),
+		(2, 'new firm', 2, '2021-01-02 09:00:00'),
+		(3, 'unknown firm', 3, NULL);
+	`)
+	if !assert.Nil(err) {
+		return
+	}
+
+	resultsCh := make(chan index.Indexable)
+	db := DB{conn: conn}
+
+	go func() {
+		err = db.QueryFromDate(ctx, resultsCh, time.Date(2021, time.January, 1, 23, 0, 0, 0, time.UTC))
+		assert.Nil(err)
+	}()
+
+	first, ok := read(resultsCh, time.Second)
+	if !assert.True(ok) {
+		return
+	}
+	assert.Equal(Firm{
+		ID:         i64(2),
+		Persontype: "Firm",
+		FirmName:   "new firm",
+		FirmNumber: "2",
+	}, first)
+
+	_, ok = read(resultsCh, time.Nanosecond)
+	assert.False(ok)
+}
+
 func i64(x int) *int64 {
 	y := int64(x)
 	return &y
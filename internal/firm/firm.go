@@ -4,6 +4,7 @@ import (
 	"encoding/json"
 	"strconv"
 
+	"github.com/ministryofjustice/opg-search-service/internal/autocomplete"
 	"github.com/ministryofjustice/opg-search-service/internal/response"
 )
 
@@ -51,6 +52,15 @@ func IndexConfig() (config []byte, err error) {
 			"number_of_shards":   3,
 			"number_of_replicas": 1,
 			"refresh_interval":   "1s",
+			"analysis": map[string]interface{}{
+				"filter": map[string]interface{}{
+					autocomplete.Filter: autocomplete.FilterConfig(),
+				},
+				"analyzer": map[string]interface{}{
+					autocomplete.Analyzer:       autocomplete.AnalyzerConfig(),
+					autocomplete.SearchAnalyzer: autocomplete.SearchAnalyzerConfig(),
+				},
+			},
 		},
 		"mappings": map[string]interface{}{
 			"properties": map[string]interface{}{
@@ -61,10 +71,12 @@ func IndexConfig() (config []byte, err error) {
 					"type": "text",
 				},
 				"firmName": map[string]interface{}{
-					"type": "text",
+					"type":    "text",
+					"copy_to": autocomplete.Field,
 				},
 				"firmNumber": map[string]interface{}{
-					"type": "keyword",
+					"type":    "keyword",
+					"copy_to": autocomplete.Field,
 				},
 				"phoneNumber": map[string]interface{}{
 					"type": "keyword",
@@ -80,13 +92,24 @@ func IndexConfig() (config []byte, err error) {
 				},
 				"town": map[string]interface{}{
 					"type": "text",
+					"fields": map[string]interface{}{
+						"keyword": map[string]interface{}{
+							"type": "keyword",
+						},
+					},
 				},
 				"county": map[string]interface{}{
 					"type": "text",
+					"fields": map[string]interface{}{
+						"keyword": map[string]interface{}{
+							"type": "keyword",
+						},
+					},
 				},
 				"postcode": map[string]interface{}{
 					"type": "keyword",
 				},
+				autocomplete.Field: autocomplete.FieldConfig(),
 			},
 		},
 	}
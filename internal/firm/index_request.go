@@ -42,3 +42,9 @@ func ParseIndexRequest(body []byte) (index.Validatable, error) {
 
 	return &req, err
 }
+
+// ParsePartialIndexRequest reads a request to update only some fields of each
+// item, the items must still have an id
+func ParsePartialIndexRequest(body []byte) (index.Validatable, error) {
+	return index.ParsePartial(body, "firms", "id", Firm{}, nil)
+}
@@ -0,0 +1,165 @@
+package index
+
+import (
+	"context"
+	"net/http"
+	"sync"
+	"time"
+
+	"github.com/ministryofjustice/opg-search-service/internal/elasticsearch"
+)
+
+// BulkSizer decides how many documents, and how many bytes, are sent in each
+// bulk request. The limits grow while the cluster responds quickly and are
+// halved when it is slow or rejects documents, so that a busy cluster is sent
+// less at once.
+type BulkSizer struct {
+	mu    sync.Mutex
+	docs  int
+	bytes int
+
+	MinDocs  int
+	MaxDocs  int
+	MinBytes int
+	MaxBytes int
+	// TargetLatency is how long a bulk request should take, the limits are
+	// only increased when requests take less than half of this
+	TargetLatency time.Duration
+}
+
+func NewBulkSizer() *BulkSizer {
+	return &BulkSizer{
+		docs:          1000,
+		bytes:         2 << 20,
+		MinDocs:       50,
+		MaxDocs:       10000,
+		MinBytes:      256 << 10,
+		MaxBytes:      elasticsearch.MaxPayloadSize,
+		TargetLatency: 2 * time.Second,
+	}
+}
+
+// Full reports whether the op has reached the current limits and should be
+// sent
+func (s *BulkSizer) Full(op *elasticsearch.BulkOp) bool {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	return op.Len() >= s.docs || op.Size() >= s.bytes
+}
+
+// Limits returns the current number of documents and bytes per request
+func (s *BulkSizer) Limits() (docs, bytes int) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	return s.docs, s.bytes
+}
+
+// Observe adjusts the limits using how long a bulk request took and whether
+// the cluster rejected it, or any of its documents, as too busy
+func (s *BulkSizer) Observe(latency time.Duration, result elasticsearch.BulkResult, err error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if err != nil || rejected(result) || latency > s.TargetLatency {
+		s.docs = max(s.docs/2, s.MinDocs)
+		s.bytes = max(s.bytes/2, s.MinBytes)
+		return
+	}
+
+	if latency < s.TargetLatency/2 {
+		s.docs = min(s.docs+s.docs/4+1, s.MaxDocs)
+		s.bytes = min(s.bytes+s.bytes/4+1, s.MaxBytes)
+	}
+}
+
+func rejected(result elasticsearch.BulkResult) bool {
+	for _, item := range result.Results {
+		if item.StatusCode == http.StatusTooManyRequests {
+			return true
+		}
+	}
+
+	return false
+}
+
+// bulkSender sends bulk requests with up to concurrency in flight at once,
+// the recorder is given each result in turn
+type bulkSender struct {
+	client   BulkClient
+	sizer    *BulkSizer
+	recorder recorder
+	sem      chan struct{}
+	wg       sync.WaitGroup
+	mu       sync.Mutex
+}
+
+func newBulkSender(client BulkClient, sizer *BulkSizer, recorder recorder, concurrency int) *bulkSender {
+	return &bulkSender{
+		client:   client,
+		sizer:    sizer,
+		recorder: recorder,
+		sem:      make(chan struct{}, max(concurrency, 1)),
+	}
+}
+
+// send makes the bulk request, waiting when there are already concurrency
+// requests in flight. The op must not be changed after it is sent.
+func (s *bulkSender) send(ctx context.Context, op *elasticsearch.BulkOp) {
+	s.sem <- struct{}{}
+	s.wg.Add(1)
+
+	go func() {
+		defer func() {
+			<-s.sem
+			s.wg.Done()
+		}()
+
+		start := time.Now()
+		result, err := s.client.DoBulk(ctx, op)
+		s.sizer.Observe(time.Since(start), result, err)
+
+		s.mu.Lock()
+		defer s.mu.Unlock()
+		s.recorder.Add(result, err)
+	}()
+}
+
+// wait blocks until all requests that have been sent have completed
+func (s *bulkSender) wait() {
+	s.wg.Wait()
+}
+
+// writeBulk adds each item to bulk requests that are sent when they reach the
+// limits of the sizer, or the maximum size of a request
+func writeBulk(ctx context.Context, sender *bulkSender, indexName string, items <-chan Indexable, write func(op *elasticsearch.BulkOp, item Indexable) error) (Indexable, error) {
+	op := elasticsearch.NewBulkOp(indexName)
+
+	for item := range items {
+		err := write(op, item)
+
+		if err == elasticsearch.ErrOpTooLarge {
+			sender.send(ctx, op)
+			op = elasticsearch.NewBulkOp(indexName)
+			err = write(op, item)
+		}
+
+		if err != nil {
+			sender.wait()
+			return item, err
+		}
+
+		if sender.sizer.Full(op) {
+			sender.send(ctx, op)
+			op = elasticsearch.NewBulkOp(indexName)
+		}
+	}
+
+	if !op.Empty() {
+		sender.send(ctx, op)
+	}
+
+	sender.wait()
+	return nil, nil
+}
@@ -0,0 +1,120 @@
+package index
+
+import (
+	"context"
+	"errors"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/ministryofjustice/opg-search-service/internal/elasticsearch"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestBulkSizerObserve(t *testing.T) {
+	sizer := NewBulkSizer()
+	docs, bytes := sizer.Limits()
+
+	sizer.Observe(10*time.Millisecond, elasticsearch.BulkResult{}, nil)
+	grownDocs, grownBytes := sizer.Limits()
+	assert.Equal(t, docs+docs/4+1, grownDocs)
+	assert.Equal(t, bytes+bytes/4+1, grownBytes)
+
+	sizer.Observe(sizer.TargetLatency*3/4, elasticsearch.BulkResult{}, nil)
+	sameDocs, _ := sizer.Limits()
+	assert.Equal(t, grownDocs, sameDocs)
+
+	sizer.Observe(sizer.TargetLatency+time.Second, elasticsearch.BulkResult{}, nil)
+	slowDocs, slowBytes := sizer.Limits()
+	assert.Equal(t, grownDocs/2, slowDocs)
+	assert.Equal(t, grownBytes/2, slowBytes)
+
+	sizer.Observe(time.Millisecond, elasticsearch.BulkResult{Results: []elasticsearch.IndexResult{{Id: "1", StatusCode: 429}}}, nil)
+	rejectedDocs, _ := sizer.Limits()
+	assert.Equal(t, slowDocs/2, rejectedDocs)
+}
+
+func TestBulkSizerLimits(t *testing.T) {
+	sizer := NewBulkSizer()
+
+	for i := 0; i < 100; i++ {
+		sizer.Observe(time.Minute, elasticsearch.BulkResult{}, errors.New("too many requests"))
+	}
+	docs, bytes := sizer.Limits()
+	assert.Equal(t, sizer.MinDocs, docs)
+	assert.Equal(t, sizer.MinBytes, bytes)
+
+	for i := 0; i < 100; i++ {
+		sizer.Observe(time.Millisecond, elasticsearch.BulkResult{}, nil)
+	}
+	docs, bytes = sizer.Limits()
+	assert.Equal(t, sizer.MaxDocs, docs)
+	assert.Equal(t, sizer.MaxBytes, bytes)
+}
+
+func TestWriteBulkSplitsAtSizerLimit(t *testing.T) {
+	ctx := context.Background()
+	sizer := NewBulkSizer()
+	sizer.docs = 2
+
+	firstOp := elasticsearch.NewBulkOp("whatever")
+	_ = firstOp.Index("1", mockIndexable{id: "1"})
+	_ = firstOp.Index("2", mockIndexable{id: "2"})
+	secondOp := elasticsearch.NewBulkOp("whatever")
+	_ = secondOp.Index("3", mockIndexable{id: "3"})
+
+	client := &mockClient{}
+	client.On("DoBulk", ctx, firstOp).Return(elasticsearch.BulkResult{Successful: 2}, nil).Once()
+	client.On("DoBulk", ctx, secondOp).Return(elasticsearch.BulkResult{Successful: 1}, nil).Once()
+
+	items := make(chan Indexable, 3)
+	items <- mockIndexable{id: "1"}
+	items <- mockIndexable{id: "2"}
+	items <- mockIndexable{id: "3"}
+	close(items)
+
+	result := &indexResponse{}
+	_, err := writeBulk(ctx, newBulkSender(client, sizer, result, 1), "whatever", items, func(op *elasticsearch.BulkOp, item Indexable) error {
+		return op.Index(item.Id(), item)
+	})
+
+	assert.Nil(t, err)
+	assert.Equal(t, 3, result.Successful)
+	client.AssertExpectations(t)
+}
+
+type slowClient struct {
+	inFlight    atomic.Int32
+	maxInFlight atomic.Int32
+}
+
+func (c *slowClient) DoBulk(ctx context.Context, op *elasticsearch.BulkOp) (elasticsearch.BulkResult, error) {
+	n := c.inFlight.Add(1)
+	defer c.inFlight.Add(-1)
+
+	for {
+		m := c.maxInFlight.Load()
+		if n <= m || c.maxInFlight.CompareAndSwap(m, n) {
+			break
+		}
+	}
+
+	time.Sleep(20 * time.Millisecond)
+	return elasticsearch.BulkResult{Successful: op.Len()}, nil
+}
+
+func TestBulkSenderLimitsConcurrency(t *testing.T) {
+	client := &slowClient{}
+	result := &indexResponse{}
+	sender := newBulkSender(client, NewBulkSizer(), result, 3)
+
+	for i := 0; i < 10; i++ {
+		op := elasticsearch.NewBulkOp("whatever")
+		_ = op.Index("1", mockIndexable{id: "1"})
+		sender.send(context.Background(), op)
+	}
+	sender.wait()
+
+	assert.Equal(t, 10, result.Successful)
+	assert.Equal(t, int32(3), client.maxInFlight.Load())
+}
@@ -0,0 +1,139 @@
+package index
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"sort"
+	"sync"
+)
+
+// Checkpoint records which id ranges of a reindex have been completed in a
+// local file, so that a run which fails can be resumed without starting over
+type Checkpoint struct {
+	mu    sync.Mutex
+	path  string
+	state checkpointState
+}
+
+type checkpointState struct {
+	Index     string `json:"index"`
+	Start     int    `json:"start"`
+	End       int    `json:"end"`
+	BatchSize int    `json:"batchSize"`
+	// Completed holds the first id of each range that has been indexed
+	Completed []int `json:"completed"`
+}
+
+// NewCheckpoint starts a checkpoint at path, replacing any earlier one when
+// the first range is completed
+func NewCheckpoint(path string) *Checkpoint {
+	return &Checkpoint{path: path}
+}
+
+// LoadCheckpoint reads the checkpoint at path to resume from, a missing file
+// is treated as a run that has not started
+func LoadCheckpoint(path string) (*Checkpoint, error) {
+	c := &Checkpoint{path: path}
+
+	data, err := os.ReadFile(path)
+	if errors.Is(err, os.ErrNotExist) {
+		return c, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	if err := json.Unmarshal(data, &c.state); err != nil {
+		return nil, fmt.Errorf("reading checkpoint %s: %w", path, err)
+	}
+
+	return c, nil
+}
+
+// Range returns the ids the checkpointed run was for, if it has started
+func (c *Checkpoint) Range() (start, end int, ok bool) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	return c.state.Start, c.state.End, c.state.Index != ""
+}
+
+// begin sets the run that the checkpoint is for, when resuming it must be
+// the same run as was checkpointed
+func (c *Checkpoint) begin(indexName string, start, end, batchSize int) error {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	if c.state.Index == "" {
+		c.state = checkpointState{Index: indexName, Start: start, End: end, BatchSize: batchSize, Completed: []int{}}
+		return c.save()
+	}
+
+	if c.state.Index != indexName || c.state.Start != start || c.state.End != end || c.state.BatchSize != batchSize {
+		return fmt.Errorf("checkpoint %s is for index=%s from=%d to=%d batchSize=%d", c.path, c.state.Index, c.state.Start, c.state.End, c.state.BatchSize)
+	}
+
+	return nil
+}
+
+func (c *Checkpoint) done(from int) bool {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	for _, v := range c.state.Completed {
+		if v == from {
+			return true
+		}
+	}
+
+	return false
+}
+
+func (c *Checkpoint) complete(from int) error {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	c.state.Completed = append(c.state.Completed, from)
+	sort.Ints(c.state.Completed)
+
+	return c.save()
+}
+
+// Remove deletes the checkpoint file, once the run it is for has finished
+func (c *Checkpoint) Remove() error {
+	if err := os.Remove(c.path); err != nil && !errors.Is(err, os.ErrNotExist) {
+		return err
+	}
+
+	return nil
+}
+
+// save writes the state to a temporary file which replaces the checkpoint, so
+// that a failure while writing does not lose the earlier state
+func (c *Checkpoint) save() error {
+	data, err := json.Marshal(c.state)
+	if err != nil {
+		return err
+	}
+
+	tmp, err := os.CreateTemp(filepath.Dir(c.path), filepath.Base(c.path)+".*")
+	if err != nil {
+		return err
+	}
+
+	if _, err := tmp.Write(data); err != nil {
+		_ = tmp.Close()
+		_ = os.Remove(tmp.Name())
+		return err
+	}
+
+	if err := tmp.Close(); err != nil {
+		_ = os.Remove(tmp.Name())
+		return err
+	}
+
+	return os.Rename(tmp.Name(), c.path)
+}
@@ -0,0 +1,68 @@
+package index
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestCheckpoint(t *testing.T) {
+	assert := assert.New(t)
+	path := filepath.Join(t.TempDir(), "index.checkpoint.json")
+
+	checkpoint := NewCheckpoint(path)
+	_, _, ok := checkpoint.Range()
+	assert.False(ok)
+
+	assert.Nil(checkpoint.begin("person_abc", 1, 30, 10))
+	assert.Nil(checkpoint.complete(11))
+	assert.Nil(checkpoint.complete(1))
+
+	loaded, err := LoadCheckpoint(path)
+	assert.Nil(err)
+
+	start, end, ok := loaded.Range()
+	assert.True(ok)
+	assert.Equal(1, start)
+	assert.Equal(30, end)
+
+	assert.Nil(loaded.begin("person_abc", 1, 30, 10))
+	assert.True(loaded.done(1))
+	assert.True(loaded.done(11))
+	assert.False(loaded.done(21))
+
+	assert.Nil(loaded.Remove())
+	_, err = os.Stat(path)
+	assert.True(os.IsNotExist(err))
+	assert.Nil(loaded.Remove())
+}
+
+func TestLoadCheckpointMissing(t *testing.T) {
+	checkpoint, err := LoadCheckpoint(filepath.Join(t.TempDir(), "missing.json"))
+	assert.Nil(t, err)
+
+	_, _, ok := checkpoint.Range()
+	assert.False(t, ok)
+}
+
+func TestLoadCheckpointInvalid(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "index.checkpoint.json")
+	assert.Nil(t, os.WriteFile(path, []byte("{"), 0600))
+
+	_, err := LoadCheckpoint(path)
+	assert.NotNil(t, err)
+}
+
+func TestCheckpointBeginMismatch(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "index.checkpoint.json")
+
+	checkpoint := NewCheckpoint(path)
+	assert.Nil(t, checkpoint.begin("person_abc", 1, 30, 10))
+
+	loaded, err := LoadCheckpoint(path)
+	assert.Nil(t, err)
+	assert.NotNil(t, loaded.begin("person_abc", 1, 30, 5))
+	assert.NotNil(t, loaded.begin("firm_abc", 1, 30, 10))
+}
@@ -0,0 +1,61 @@
+package index
+
+import (
+	"encoding/json"
+	"fmt"
+
+	"github.com/ministryofjustice/opg-search-service/internal/response"
+)
+
+type deleteItem string
+
+func (d deleteItem) Id() string {
+	return string(d)
+}
+
+type deleteRequest struct {
+	items []Indexable
+	errs  []response.Error
+}
+
+func (r *deleteRequest) Validate() []response.Error {
+	if len(r.items) == 0 && len(r.errs) == 0 {
+		return []response.Error{{
+			Name:        "ids",
+			Description: "field is empty",
+		}}
+	}
+
+	return r.errs
+}
+
+func (r *deleteRequest) Items() []Indexable {
+	return r.items
+}
+
+// ParseDeleteRequest reads a request of the form {"ids": [...]}, where each id
+// is the id of a document in the index as a string or number
+func ParseDeleteRequest(body []byte) (Validatable, error) {
+	var req struct {
+		IDs []json.RawMessage `json:"ids"`
+	}
+	if err := json.Unmarshal(body, &req); err != nil {
+		return nil, err
+	}
+
+	dr := &deleteRequest{}
+	for i, raw := range req.IDs {
+		id := partialID(raw)
+		if id == "" {
+			dr.errs = append(dr.errs, response.Error{
+				Name:        fmt.Sprintf("ids[%d]", i),
+				Description: "must be a string or number",
+			})
+			continue
+		}
+
+		dr.items = append(dr.items, deleteItem(id))
+	}
+
+	return dr, nil
+}
@@ -19,20 +19,52 @@ type IndexClient interface {
 
 type Parser func([]byte) (Validatable, error)
 
+// recorder is given the result of each bulk request as it is made
+type recorder interface {
+	Add(result elasticsearch.BulkResult, err error)
+}
+
 type Handler struct {
 	logger  *logrus.Logger
 	client  IndexClient
+	jobs    *JobStore
 	indices []string
 	parser  Parser
+	sizer   *BulkSizer
+	write   func(op *elasticsearch.BulkOp, item Indexable) error
 }
 
-func NewHandler(logger *logrus.Logger, client IndexClient, indices []string, parser Parser) *Handler {
+func NewHandler(logger *logrus.Logger, client IndexClient, jobs *JobStore, indices []string, parser Parser) *Handler {
 	return &Handler{
 		logger:  logger,
 		client:  client,
+		jobs:    jobs,
 		indices: indices,
 		parser:  parser,
+		sizer:   NewBulkSizer(),
+		write: func(op *elasticsearch.BulkOp, item Indexable) error {
+			return op.Index(item.Id(), item)
+		},
+	}
+}
+
+// NewUpdateHandler returns a Handler that merges the fields of each item into
+// the indexed document, rather than replacing it
+func NewUpdateHandler(logger *logrus.Logger, client IndexClient, jobs *JobStore, indices []string, parser Parser) *Handler {
+	h := NewHandler(logger, client, jobs, indices, parser)
+	h.write = func(op *elasticsearch.BulkOp, item Indexable) error {
+		return op.Update(item.Id(), item)
+	}
+	return h
+}
+
+// NewDeleteHandler returns a Handler that deletes the document for each item
+func NewDeleteHandler(logger *logrus.Logger, client IndexClient, jobs *JobStore, indices []string, parser Parser) *Handler {
+	h := NewHandler(logger, client, jobs, indices, parser)
+	h.write = func(op *elasticsearch.BulkOp, item Indexable) error {
+		return op.Delete(item.Id())
 	}
+	return h
 }
 
 func (i *Handler) ServeHTTP(w http.ResponseWriter, r *http.Request) {
@@ -61,6 +93,11 @@ func (i *Handler) ServeHTTP(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
+	if r.URL.Query().Get("async") == "true" {
+		i.serveAsync(w, r, req.Items())
+		return
+	}
+
 	response := &indexResponse{}
 
 	for _, index := range i.indices {
@@ -78,26 +115,67 @@ func (i *Handler) ServeHTTP(w http.ResponseWriter, r *http.Request) {
 	i.logger.Println("Request took: ", time.Since(start))
 }
 
-func (i *Handler) doIndex(ctx context.Context, indexName string, response *indexResponse, items []Indexable) error {
-	op := elasticsearch.NewBulkOp(indexName)
-
-	for _, f := range items {
-		err := op.Index(f.Id(), f)
+// serveAsync accepts the items to be indexed after the response is sent, as
+// retries against a busy cluster can take longer than the server will wait to
+// write a response. The job id is returned so its progress can be polled.
+func (i *Handler) serveAsync(w http.ResponseWriter, r *http.Request, items []Indexable) {
+	job, err := i.jobs.Create(len(items) * len(i.indices))
+	if err != nil {
+		i.logger.Println(err)
+		response.WriteJSONErrors(w, "unable to create job", []response.Error{}, http.StatusInternalServerError)
+		return
+	}
 
-		if err == elasticsearch.ErrOpTooLarge {
-			response.Add(i.client.DoBulk(ctx, op))
-			op.Reset()
-			err = op.Index(f.Id(), f)
+	// the job must outlive the request, but keeps its values for logging
+	ctx, cancel := context.WithTimeout(context.WithoutCancel(r.Context()), jobTimeout)
+
+	go func() {
+		start := time.Now()
+		defer cancel()
+
+		// a job that panics is stopped, rather than left running forever
+		defer func() {
+			if v := recover(); v != nil {
+				i.logger.Println("Job", job.id, "panicked:", v)
+				job.stop(fmt.Errorf("job stopped: %v", v))
+			}
+		}()
+
+		for _, index := range i.indices {
+			if err := i.doIndex(ctx, index, job, items); err != nil {
+				job.fail(err)
+			}
 		}
 
-		if err != nil {
-			i.logger.Println(err)
-			return fmt.Errorf("could not construct index request for id=%s", f.Id())
+		if err := ctx.Err(); err != nil {
+			job.stop(fmt.Errorf("job stopped: %w", err))
+		} else {
+			job.complete()
 		}
+		i.logger.Println("Job", job.id, "took: ", time.Since(start))
+	}()
+
+	jsonResp, _ := json.Marshal(job)
+
+	w.WriteHeader(http.StatusAccepted)
+
+	_, _ = w.Write(jsonResp)
+}
+
+func (i *Handler) doIndex(ctx context.Context, indexName string, response recorder, items []Indexable) error {
+	ch := make(chan Indexable, len(items))
+	for _, item := range items {
+		ch <- item
 	}
+	close(ch)
+
+	// requests are sent one at a time so that results are in the same order
+	// as the items
+	sender := newBulkSender(i.client, i.sizer, response, 1)
 
-	if !op.Empty() {
-		response.Add(i.client.DoBulk(ctx, op))
+	if item, err := writeBulk(ctx, sender, indexName, ch, i.write); err != nil {
+		i.logger.Println(err)
+		return fmt.Errorf("could not construct index request for id=%s", item.Id())
 	}
 
 	return nil
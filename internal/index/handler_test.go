@@ -3,11 +3,13 @@ package index
 import (
 	"bytes"
 	"context"
+	"encoding/json"
 	"errors"
 	"net/http"
 	"net/http/httptest"
 	"strings"
 	"testing"
+	"time"
 
 	"github.com/stretchr/testify/mock"
 
@@ -23,6 +25,7 @@ type HandlerTestSuite struct {
 	suite.Suite
 	logger            *logrus.Logger
 	esClient          *elasticsearch.MockESClient
+	jobs              *JobStore
 	handler           *Handler
 	recorder          *httptest.ResponseRecorder
 	respBody          *string
@@ -35,7 +38,9 @@ func (suite *HandlerTestSuite) SetupTest() {
 	suite.esClient = &elasticsearch.MockESClient{}
 	suite.parserValidatable = nil
 	suite.parserError = nil
-	suite.handler = NewHandler(suite.logger, suite.esClient, []string{"whatever-test", "whatever-new"}, func(body []byte) (Validatable, error) {
+	suite.jobs = NewJobStore()
+	suite.jobs.now = func() time.Time { return time.Date(2026, 1, 2, 3, 4, 5, 0, time.UTC) }
+	suite.handler = NewHandler(suite.logger, suite.esClient, suite.jobs, []string{"whatever-test", "whatever-new"}, func(body []byte) (Validatable, error) {
 		return suite.parserValidatable, suite.parserError
 	})
 	suite.recorder = httptest.NewRecorder()
@@ -132,6 +137,201 @@ func (suite *HandlerTestSuite) Test_Index() {
 	suite.Equal(`{"successful":4,"failed":2,"errors":["hmm","hey"]}`, suite.RespBody())
 }
 
+func (suite *HandlerTestSuite) Test_IndexWithItemResults() {
+	suite.parserValidatable = mockValidatable{
+		items: []Indexable{
+			mockIndexable{id: "13"},
+			mockIndexable{id: "14"},
+		},
+	}
+
+	firstOp := elasticsearch.NewBulkOp("whatever-test")
+	suite.Nil(firstOp.Index("13", mockIndexable{id: "13"}))
+	suite.Nil(firstOp.Index("14", mockIndexable{id: "14"}))
+	secondOp := elasticsearch.NewBulkOp("whatever-new")
+	suite.Nil(secondOp.Index("13", mockIndexable{id: "13"}))
+	suite.Nil(secondOp.Index("14", mockIndexable{id: "14"}))
+
+	suite.esClient.
+		On("DoBulk", mock.Anything, firstOp).
+		Return(elasticsearch.BulkResult{
+			Successful: 2,
+			Results: []elasticsearch.IndexResult{
+				{Id: "13", Index: "whatever-test", StatusCode: 201},
+				{Id: "14", Index: "whatever-test", StatusCode: 201},
+			},
+		}, nil).
+		Once()
+	suite.esClient.
+		On("DoBulk", mock.Anything, secondOp).
+		Return(elasticsearch.BulkResult{
+			Successful: 1,
+			Failed:     1,
+			Results: []elasticsearch.IndexResult{
+				{Id: "13", Index: "whatever-new", StatusCode: 201},
+				{Id: "14", Index: "whatever-new", StatusCode: 400, Message: "mapper_parsing_exception: failed to parse"},
+			},
+		}, nil).
+		Once()
+
+	suite.ServeRequest(http.MethodPost, "", `{"whatevers":[{"id":13},{"id":14}]}`)
+
+	suite.Equal(http.StatusAccepted, suite.RespCode())
+	suite.Equal(`{"successful":3,"failed":1,"results":[`+
+		`{"id":"13","index":"whatever-test","statusCode":201},{"id":"14","index":"whatever-test","statusCode":201},`+
+		`{"id":"13","index":"whatever-new","statusCode":201},`+
+		`{"id":"14","index":"whatever-new","statusCode":400,"message":"mapper_parsing_exception: failed to parse"}]}`, suite.RespBody())
+}
+
+func (suite *HandlerTestSuite) Test_IndexAsync() {
+	suite.parserValidatable = mockValidatable{
+		items: []Indexable{
+			mockIndexable{id: "13"},
+		},
+	}
+
+	suite.esClient.
+		On("DoBulk", mock.Anything, mock.Anything).
+		Return(elasticsearch.BulkResult{
+			Successful: 1,
+			Results:    []elasticsearch.IndexResult{{Id: "13", Index: "whatever", StatusCode: 201}},
+		}, nil).
+		Twice()
+
+	suite.ServeRequest(http.MethodPost, "/?async=true", `{"whatevers":[{"id":13}]}`)
+
+	suite.Equal(http.StatusAccepted, suite.RespCode())
+
+	var accepted struct {
+		ID     string `json:"id"`
+		Total  int    `json:"total"`
+		Status string `json:"status"`
+	}
+	suite.Nil(json.Unmarshal([]byte(suite.RespBody()), &accepted))
+	suite.Len(accepted.ID, 32)
+	suite.Equal(2, accepted.Total)
+
+	job, ok := suite.jobs.Get(accepted.ID)
+	suite.True(ok)
+
+	suite.Eventually(func() bool {
+		data, _ := json.Marshal(job)
+		return strings.Contains(string(data), `"status":"completed"`)
+	}, time.Second, 10*time.Millisecond)
+
+	data, _ := json.Marshal(job)
+	suite.Equal(`{"id":"`+accepted.ID+`","status":"completed","total":2,"processed":2,`+
+		`"createdAt":"2026-01-02T03:04:05Z","completedAt":"2026-01-02T03:04:05Z","successful":2,"failed":0,`+
+		`"results":[{"id":"13","index":"whatever","statusCode":201},{"id":"13","index":"whatever","statusCode":201}]}`, string(data))
+}
+
+type panicIndexable struct{}
+
+func (panicIndexable) Id() string {
+	return "13"
+}
+
+func (panicIndexable) MarshalJSON() ([]byte, error) {
+	panic("cannot marshal")
+}
+
+func (suite *HandlerTestSuite) Test_IndexAsyncPanic() {
+	suite.parserValidatable = mockValidatable{
+		items: []Indexable{panicIndexable{}},
+	}
+
+	suite.ServeRequest(http.MethodPost, "/?async=true", `{"whatevers":[{"id":13}]}`)
+
+	suite.Equal(http.StatusAccepted, suite.RespCode())
+
+	var accepted struct {
+		ID string `json:"id"`
+	}
+	suite.Nil(json.Unmarshal([]byte(suite.RespBody()), &accepted))
+
+	job, ok := suite.jobs.Get(accepted.ID)
+	suite.True(ok)
+
+	suite.Eventually(func() bool {
+		data, _ := json.Marshal(job)
+		return strings.Contains(string(data), `"status":"failed"`)
+	}, time.Second, 10*time.Millisecond)
+
+	data, _ := json.Marshal(job)
+	suite.Equal(`{"id":"`+accepted.ID+`","status":"failed","total":2,"processed":0,`+
+		`"createdAt":"2026-01-02T03:04:05Z","completedAt":"2026-01-02T03:04:05Z","successful":0,"failed":0,`+
+		`"errors":["job stopped: cannot marshal"]}`, string(data))
+}
+
+func (suite *HandlerTestSuite) Test_Update() {
+	suite.handler = NewUpdateHandler(suite.logger, suite.esClient, NewJobStore(), []string{"whatever-test"}, func(body []byte) (Validatable, error) {
+		return suite.parserValidatable, suite.parserError
+	})
+	suite.parserValidatable = mockValidatable{
+		items: []Indexable{
+			mockIndexable{id: "13"},
+		},
+	}
+
+	op := elasticsearch.NewBulkOp("whatever-test")
+	err := op.Update("13", mockIndexable{id: "13"})
+	suite.Nil(err)
+
+	suite.esClient.
+		On("DoBulk", mock.Anything, op).
+		Return(elasticsearch.BulkResult{Successful: 1}, nil).
+		Once()
+
+	suite.ServeRequest(http.MethodPatch, "", `{"whatevers":[{"id":13}]}`)
+
+	suite.Equal(http.StatusAccepted, suite.RespCode())
+	suite.Equal(`{"successful":1,"failed":0}`, suite.RespBody())
+}
+
+func (suite *HandlerTestSuite) Test_Delete() {
+	suite.handler = NewDeleteHandler(suite.logger, suite.esClient, NewJobStore(), []string{"whatever-test"}, ParseDeleteRequest)
+
+	op := elasticsearch.NewBulkOp("whatever-test")
+	suite.Nil(op.Delete("13"))
+	suite.Nil(op.Delete("M-1234-5678-9012"))
+
+	suite.esClient.
+		On("DoBulk", mock.Anything, op).
+		Return(elasticsearch.BulkResult{
+			Successful: 1,
+			Failed:     1,
+			Results: []elasticsearch.IndexResult{
+				{Id: "13", Index: "whatever-test", StatusCode: 200},
+				{Id: "M-1234-5678-9012", Index: "whatever-test", StatusCode: 404, Message: "not_found"},
+			},
+		}, nil).
+		Once()
+
+	suite.ServeRequest(http.MethodPost, "", `{"ids":[13,"M-1234-5678-9012"]}`)
+
+	suite.Equal(http.StatusAccepted, suite.RespCode())
+	suite.Equal(`{"successful":1,"failed":1,"results":[{"id":"13","index":"whatever-test","statusCode":200},`+
+		`{"id":"M-1234-5678-9012","index":"whatever-test","statusCode":404,"message":"not_found"}]}`, suite.RespBody())
+}
+
+func (suite *HandlerTestSuite) Test_DeleteInvalidIDs() {
+	suite.handler = NewDeleteHandler(suite.logger, suite.esClient, NewJobStore(), []string{"whatever-test"}, ParseDeleteRequest)
+
+	suite.ServeRequest(http.MethodPost, "", `{"ids":[13,{"id":14}]}`)
+
+	suite.Equal(http.StatusBadRequest, suite.RespCode())
+	suite.Equal(`{"message":"Some fields have failed validation","errors":[{"name":"ids[1]","description":"must be a string or number"}]}`+"\n", suite.RespBody())
+}
+
+func (suite *HandlerTestSuite) Test_DeleteNoIDs() {
+	suite.handler = NewDeleteHandler(suite.logger, suite.esClient, NewJobStore(), []string{"whatever-test"}, ParseDeleteRequest)
+
+	suite.ServeRequest(http.MethodPost, "", `{"ids":[]}`)
+
+	suite.Equal(http.StatusBadRequest, suite.RespCode())
+	suite.Equal(`{"message":"Some fields have failed validation","errors":[{"name":"ids","description":"field is empty"}]}`+"\n", suite.RespBody())
+}
+
 func TestHandler(t *testing.T) {
 	suite.Run(t, new(HandlerTestSuite))
 }
@@ -3,8 +3,11 @@ package index
 import (
 	"context"
 	"fmt"
+	"slices"
+	"sync"
 	"time"
 
+	"github.com/jackc/pgx/v5"
 	"github.com/ministryofjustice/opg-search-service/internal/elasticsearch"
 )
 
@@ -14,6 +17,13 @@ type DB interface {
 	QueryFromDate(ctx context.Context, results chan<- Indexable, fromDate time.Time) error
 }
 
+// Conn is satisfied by both a single connection and a pool, so that ranges
+// can be read in parallel
+type Conn interface {
+	Query(ctx context.Context, sql string, args ...any) (pgx.Rows, error)
+	QueryRow(ctx context.Context, sql string, args ...any) pgx.Row
+}
+
 type BulkClient interface {
 	DoBulk(ctx context.Context, op *elasticsearch.BulkOp) (elasticsearch.BulkResult, error)
 }
@@ -24,21 +34,54 @@ type Logger interface {
 
 func New(es BulkClient, logger Logger, db DB, indexName string) *Indexer {
 	return &Indexer{
-		es:        es,
-		log:       logger,
-		db:        db,
-		indexName: indexName,
+		es:          es,
+		log:         logger,
+		db:          db,
+		indexName:   indexName,
+		sizer:       NewBulkSizer(),
+		concurrency: 1,
+		workers:     1,
 	}
 }
 
 type Indexer struct {
-	es        BulkClient
-	log       Logger
-	db        DB
-	indexName string
+	es          BulkClient
+	log         Logger
+	db          DB
+	indexName   string
+	sizer       *BulkSizer
+	concurrency int
+	workers     int
+	checkpoint  *Checkpoint
+}
+
+// SetConcurrency sets the number of bulk requests that can be in flight at
+// once, so that a large reindex is not limited by the latency of each request
+func (r *Indexer) SetConcurrency(n int) {
+	r.concurrency = n
+}
+
+// SetWorkers sets the number of id ranges that are read and indexed at once,
+// each worker uses its own database connection so the pool must allow it
+func (r *Indexer) SetWorkers(n int) {
+	r.workers = n
+}
+
+// SetCheckpoint records each id range as it is completed, ranges that the
+// checkpoint already has as completed are skipped
+func (r *Indexer) SetCheckpoint(checkpoint *Checkpoint) {
+	r.checkpoint = checkpoint
 }
 
 func (r *Indexer) All(ctx context.Context, batchSize int) (*Result, error) {
+	// a resumed run keeps its range, as records may have been added since
+	if r.checkpoint != nil {
+		if start, end, ok := r.checkpoint.Range(); ok {
+			r.log.Printf("resuming from checkpoint range (%d, %d)", start, end)
+			return r.ByID(ctx, start, end, batchSize)
+		}
+	}
+
 	min, max, err := r.db.QueryIDRange(ctx)
 	if err != nil {
 		return nil, err
@@ -48,30 +91,181 @@ func (r *Indexer) All(ctx context.Context, batchSize int) (*Result, error) {
 }
 
 func (r *Indexer) ByID(ctx context.Context, start, end, batchSize int) (*Result, error) {
+	if r.checkpoint != nil {
+		if err := r.checkpoint.begin(r.indexName, start, end, batchSize); err != nil {
+			return nil, err
+		}
+	}
+
+	var ranges [][2]int
+	batch := &batchIter{start: start, end: end, size: batchSize}
+	for batch.Next() {
+		if r.checkpoint != nil && r.checkpoint.done(batch.From()) {
+			continue
+		}
+		ranges = append(ranges, [2]int{batch.From(), batch.To()})
+	}
+
+	result := &Result{}
+	rec := &lockedRecorder{recorder: indexerRecorder{log: r.log, result: result}}
+	progress := newProgress(r.log, len(ranges))
+
+	var (
+		wg       sync.WaitGroup
+		stopOnce sync.Once
+		stop     = make(chan struct{})
+		rerr     error
+	)
+
+	jobs := make(chan [2]int)
+	for i := 0; i < max(r.workers, 1); i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+
+			for rng := range jobs {
+				complete, err := r.indexRange(ctx, rec, rng[0], rng[1], batchSize)
+				if err != nil {
+					stopOnce.Do(func() {
+						rerr = err
+						close(stop)
+					})
+					continue
+				}
+
+				// a range with a failed bulk request is left to be retried
+				// when the run is resumed
+				if complete && r.checkpoint != nil {
+					if err := r.checkpoint.complete(rng[0]); err != nil {
+						r.log.Printf("could not checkpoint range (%d, %d): %s", rng[0], rng[1], err.Error())
+					}
+				}
+
+				progress.complete(rng[0], rng[1])
+			}
+		}()
+	}
+
+feed:
+	for _, rng := range ranges {
+		select {
+		case jobs <- rng:
+		case <-stop:
+			break feed
+		}
+	}
+	close(jobs)
+	wg.Wait()
+
+	return result, rerr
+}
+
+// indexRange reads and indexes the records in the range, it is complete when
+// every bulk request was made without error
+func (r *Indexer) indexRange(ctx context.Context, rec recorder, from, to, batchSize int) (bool, error) {
+	r.log.Printf("reading range from db (%d, %d)", from, to)
+
 	var rerr error
 	items := make(chan Indexable, batchSize)
 
 	go func() {
 		defer func() { close(items) }()
 
-		batch := &batchIter{start: start, end: end, size: batchSize}
+		rerr = r.db.QueryByID(ctx, items, from, to)
+	}()
 
-		for batch.Next() {
-			r.log.Printf("reading range from db (%d, %d)", batch.From(), batch.To())
+	rangeRec := &rangeRecorder{recorder: rec}
+	sender := newBulkSender(r.es, r.sizer, rangeRec, r.concurrency)
 
-			if err := r.db.QueryByID(ctx, items, batch.From(), batch.To()); err != nil {
-				rerr = err
-				break
+	item, err := writeBulk(ctx, sender, r.indexName, items, func(op *elasticsearch.BulkOp, e Indexable) error {
+		return op.Index(e.Id(), e)
+	})
+	if err != nil {
+		// let the query finish so that it is not blocked on the channel
+		for range items {
+		}
+		return false, fmt.Errorf("could not construct index request for id=%s; %w", item.Id(), err)
+	}
+
+	if rerr != nil {
+		return false, rerr
+	}
+
+	return rangeRec.errors == 0, nil
+}
+
+// ByIDs indexes the records with the given ids, which need not be in order.
+// Consecutive ids are read from the database together.
+func (r *Indexer) ByIDs(ctx context.Context, ids []int) (*Result, error) {
+	var rerr error
+	items := make(chan Indexable, len(ids))
+
+	go func() {
+		defer func() { close(items) }()
+
+		for _, rng := range idRanges(ids) {
+			if rerr = r.db.QueryByID(ctx, items, rng[0], rng[1]); rerr != nil {
+				return
 			}
 		}
 	}()
 
 	result, err := r.index(ctx, items)
-	if rerr != nil {
-		return result, rerr
+	if err != nil {
+		// let the queries finish so that they are not blocked on the channel
+		for range items {
+		}
+		return nil, err
 	}
 
-	return result, err
+	return result, rerr
+}
+
+// Delete removes the documents with the given ids from the index
+func (r *Indexer) Delete(ctx context.Context, ids []string) (*Result, error) {
+	items := make(chan Indexable, len(ids))
+	for _, id := range ids {
+		items <- deletion(id)
+	}
+	close(items)
+
+	result := &Result{}
+	sender := newBulkSender(r.es, r.sizer, indexerRecorder{log: r.log, result: result}, r.concurrency)
+
+	item, err := writeBulk(ctx, sender, r.indexName, items, func(op *elasticsearch.BulkOp, e Indexable) error {
+		return op.Delete(e.Id())
+	})
+	if err != nil {
+		return nil, fmt.Errorf("could not construct delete request for id=%s; %w", item.Id(), err)
+	}
+
+	return result, nil
+}
+
+type deletion string
+
+func (d deletion) Id() string {
+	return string(d)
+}
+
+// idRanges sorts the ids and groups those that are consecutive, duplicates are
+// removed
+func idRanges(ids []int) [][2]int {
+	sorted := slices.Clone(ids)
+	slices.Sort(sorted)
+	sorted = slices.Compact(sorted)
+
+	var ranges [][2]int
+	for _, id := range sorted {
+		if n := len(ranges); n > 0 && ranges[n-1][1] == id-1 {
+			ranges[n-1][1] = id
+			continue
+		}
+
+		ranges = append(ranges, [2]int{id, id})
+	}
+
+	return ranges
 }
 
 func (r *Indexer) FromDate(ctx context.Context, from time.Time, batchSize int) (*Result, error) {
@@ -93,46 +287,109 @@ func (r *Indexer) FromDate(ctx context.Context, from time.Time, batchSize int) (
 }
 
 func (r *Indexer) index(ctx context.Context, entity <-chan Indexable) (*Result, error) {
-	op := elasticsearch.NewBulkOp(r.indexName)
 	result := &Result{}
+	sender := newBulkSender(r.es, r.sizer, indexerRecorder{log: r.log, result: result}, r.concurrency)
 
-	for e := range entity {
-		err := op.Index(e.Id(), e)
+	item, err := writeBulk(ctx, sender, r.indexName, entity, func(op *elasticsearch.BulkOp, e Indexable) error {
+		return op.Index(e.Id(), e)
+	})
+	if err != nil {
+		return nil, fmt.Errorf("could not construct index request for id=%s; %w", item.Id(), err)
+	}
 
-		if err == elasticsearch.ErrOpTooLarge {
-			res, bulkErr := r.es.DoBulk(ctx, op)
-			if bulkErr == nil {
-				r.log.Printf("batch indexed successful=%d failed=%d error=%s", res.Successful, res.Failed, res.Error)
-			} else {
-				r.log.Printf("indexing error: %s", bulkErr.Error())
-			}
+	return result, nil
+}
 
-			result.Add(res, bulkErr)
-			op.Reset()
-			err = op.Index(e.Id(), e)
-		}
+// lockedRecorder allows a recorder to be shared by workers
+type lockedRecorder struct {
+	mu       sync.Mutex
+	recorder recorder
+}
 
-		if err != nil {
-			return nil, fmt.Errorf("could not construct index request for id=%s; %w", e.Id(), err)
-		}
+func (r *lockedRecorder) Add(res elasticsearch.BulkResult, err error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	r.recorder.Add(res, err)
+}
+
+// rangeRecorder counts the bulk requests for a range that failed, or that had
+// documents which failed, so that the range is not checkpointed as done
+type rangeRecorder struct {
+	recorder recorder
+	errors   int
+}
+
+func (r *rangeRecorder) Add(res elasticsearch.BulkResult, err error) {
+	if err != nil || res.Failed > 0 {
+		r.errors++
 	}
 
-	if !op.Empty() {
-		result.Add(r.es.DoBulk(ctx, op))
+	r.recorder.Add(res, err)
+}
+
+// progress logs how many ranges have been indexed and an estimate of how long
+// the remaining ranges will take
+type progress struct {
+	mu    sync.Mutex
+	log   Logger
+	total int
+	done  int
+	start time.Time
+	now   func() time.Time
+}
+
+func newProgress(log Logger, total int) *progress {
+	return &progress{log: log, total: total, start: time.Now(), now: time.Now}
+}
+
+func (p *progress) complete(from, to int) {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
+	p.done++
+	elapsed := p.now().Sub(p.start)
+	eta := elapsed / time.Duration(p.done) * time.Duration(p.total-p.done)
+
+	p.log.Printf("indexed range (%d, %d) %d/%d ranges elapsed=%s eta=%s", from, to, p.done, p.total, elapsed.Round(time.Second), eta.Round(time.Second))
+}
+
+// indexerRecorder logs the result of each bulk request as it is added
+type indexerRecorder struct {
+	log    Logger
+	result *Result
+}
+
+func (r indexerRecorder) Add(res elasticsearch.BulkResult, err error) {
+	if err == nil {
+		r.log.Printf("batch indexed successful=%d failed=%d error=%s", res.Successful, res.Failed, res.Error)
+	} else {
+		r.log.Printf("indexing error: %s", err.Error())
 	}
 
-	return result, nil
+	r.result.Add(res, err)
 }
 
 type Result struct {
 	Successful int
 	Failed     int
+	Conflicts  int
 	Errors     []string
+	// Failures has the outcome of each item that could not be indexed, as
+	// keeping successes for every record would use too much memory
+	Failures []elasticsearch.IndexResult
 }
 
 func (r *Result) Add(result elasticsearch.BulkResult, err error) {
 	r.Successful += result.Successful
 	r.Failed += result.Failed
+	r.Conflicts += result.Conflicts
+
+	for _, item := range result.Results {
+		if !item.Successful() && !item.Conflict() {
+			r.Failures = append(r.Failures, item)
+		}
+	}
 
 	if err != nil {
 		r.Errors = append(r.Errors, err.Error())
@@ -2,6 +2,9 @@ package index
 
 import (
 	"context"
+	"errors"
+	"path/filepath"
+	"strconv"
 	"testing"
 	"time"
 
@@ -119,6 +122,187 @@ func TestByID(t *testing.T) {
 	mock.AssertExpectationsForObjects(t, db, client)
 }
 
+func TestByIDWorkers(t *testing.T) {
+	assert := assert.New(t)
+	ctx := context.Background()
+
+	db := &mockDB{}
+	for _, from := range []int{1, 3, 5} {
+		item := mockIndexable{id: strconv.Itoa(from)}
+		db.
+			On("QueryByID", ctx, mock.Anything, from, from+1).
+			Run(func(args mock.Arguments) {
+				args.Get(1).(chan<- Indexable) <- item
+			}).
+			Return(nil)
+	}
+
+	client := &mockClient{}
+	client.
+		On("DoBulk", ctx, mock.Anything).
+		Return(elasticsearch.BulkResult{Successful: 1}, nil).
+		Times(3)
+
+	indexer := New(client, &mockLogger{}, db, "whatever")
+	indexer.SetWorkers(3)
+
+	result, err := indexer.ByID(ctx, 1, 6, 2)
+	assert.Nil(err)
+	assert.Equal(&Result{Successful: 3}, result)
+
+	mock.AssertExpectationsForObjects(t, db, client)
+}
+
+func TestByIDResumesFromCheckpoint(t *testing.T) {
+	assert := assert.New(t)
+	ctx := context.Background()
+	path := filepath.Join(t.TempDir(), "index.checkpoint.json")
+
+	previous := NewCheckpoint(path)
+	assert.Nil(previous.begin("whatever", 1, 6, 2))
+	assert.Nil(previous.complete(1))
+
+	checkpoint, err := LoadCheckpoint(path)
+	assert.Nil(err)
+
+	item := mockIndexable{id: "3"}
+	db := &mockDB{}
+	db.
+		On("QueryByID", ctx, mock.Anything, 3, 4).
+		Run(func(args mock.Arguments) {
+			args.Get(1).(chan<- Indexable) <- item
+		}).
+		Return(nil)
+	db.
+		On("QueryByID", ctx, mock.Anything, 5, 6).
+		Return(nil)
+
+	client := &mockClient{}
+	client.
+		On("DoBulk", ctx, mock.Anything).
+		Return(elasticsearch.BulkResult{}, errors.New("hmm")).
+		Once()
+
+	indexer := New(client, &mockLogger{}, db, "whatever")
+	indexer.SetCheckpoint(checkpoint)
+
+	result, err := indexer.All(ctx, 2)
+	assert.Nil(err)
+	assert.Equal([]string{"hmm"}, result.Errors)
+
+	// the range with a failed request is not checkpointed
+	assert.True(checkpoint.done(1))
+	assert.False(checkpoint.done(3))
+	assert.True(checkpoint.done(5))
+
+	mock.AssertExpectationsForObjects(t, db, client)
+}
+
+func TestByIDDoesNotCheckpointFailedDocuments(t *testing.T) {
+	assert := assert.New(t)
+	ctx := context.Background()
+
+	checkpoint := NewCheckpoint(filepath.Join(t.TempDir(), "index.checkpoint.json"))
+
+	item := mockIndexable{id: "1"}
+	db := &mockDB{}
+	db.
+		On("QueryByID", ctx, mock.Anything, 1, 2).
+		Run(func(args mock.Arguments) {
+			args.Get(1).(chan<- Indexable) <- item
+		}).
+		Return(nil)
+
+	client := &mockClient{}
+	client.
+		On("DoBulk", ctx, mock.Anything).
+		Return(elasticsearch.BulkResult{
+			Failed:  1,
+			Results: []elasticsearch.IndexResult{{Id: "1", StatusCode: 429, Message: "too many requests"}},
+		}, nil).
+		Once()
+
+	indexer := New(client, &mockLogger{}, db, "whatever")
+	indexer.SetCheckpoint(checkpoint)
+
+	result, err := indexer.ByID(ctx, 1, 2, 2)
+	assert.Nil(err)
+	assert.Equal(1, result.Failed)
+	assert.False(checkpoint.done(1))
+
+	mock.AssertExpectationsForObjects(t, db, client)
+}
+
+func TestByIDs(t *testing.T) {
+	assert := assert.New(t)
+	ctx := context.Background()
+
+	first := mockIndexable{id: "1"}
+	second := mockIndexable{id: "2"}
+	fifth := mockIndexable{id: "5"}
+
+	db := &mockDB{}
+	db.
+		On("QueryByID", ctx, mock.Anything, 1, 2).
+		Run(func(args mock.Arguments) {
+			ch := args.Get(1).(chan<- Indexable)
+			ch <- first
+			ch <- second
+		}).
+		Return(nil)
+	db.
+		On("QueryByID", ctx, mock.Anything, 5, 5).
+		Run(func(args mock.Arguments) {
+			args.Get(1).(chan<- Indexable) <- fifth
+		}).
+		Return(nil)
+
+	bulkOp := elasticsearch.NewBulkOp("whatever")
+	assert.Nil(bulkOp.Index("1", first))
+	assert.Nil(bulkOp.Index("2", second))
+	assert.Nil(bulkOp.Index("5", fifth))
+
+	client := &mockClient{}
+	client.
+		On("DoBulk", ctx, bulkOp).
+		Return(elasticsearch.BulkResult{Successful: 3}, nil)
+
+	indexer := New(client, &mockLogger{}, db, "whatever")
+
+	result, err := indexer.ByIDs(ctx, []int{5, 2, 1, 2})
+	assert.Nil(err)
+	assert.Equal(&Result{Successful: 3}, result)
+
+	mock.AssertExpectationsForObjects(t, db, client)
+}
+
+func TestDelete(t *testing.T) {
+	assert := assert.New(t)
+	ctx := context.Background()
+
+	bulkOp := elasticsearch.NewBulkOp("whatever")
+	assert.Nil(bulkOp.Delete("1"))
+	assert.Nil(bulkOp.Delete("4"))
+
+	client := &mockClient{}
+	client.
+		On("DoBulk", ctx, bulkOp).
+		Return(elasticsearch.BulkResult{Successful: 2}, nil)
+
+	indexer := New(client, &mockLogger{}, &mockDB{}, "whatever")
+
+	result, err := indexer.Delete(ctx, []string{"1", "4"})
+	assert.Nil(err)
+	assert.Equal(&Result{Successful: 2}, result)
+
+	mock.AssertExpectationsForObjects(t, client)
+}
+
+func TestIDRanges(t *testing.T) {
+	assert.Nil(t, idRanges(nil))
+	assert.Equal(t, [][2]int{{1, 3}, {5, 5}, {8, 9}}, idRanges([]int{9, 3, 1, 2, 5, 8, 2}))
+}
+
 func TestFromDate(t *testing.T) {
 	assert := assert.New(t)
 	ctx := context.Background()
@@ -157,3 +341,29 @@ func TestFromDate(t *testing.T) {
 
 	mock.AssertExpectationsForObjects(t, db, client)
 }
+
+func TestResultAdd(t *testing.T) {
+	result := &Result{}
+
+	result.Add(elasticsearch.BulkResult{
+		Successful: 1,
+		Failed:     1,
+		Conflicts:  1,
+		Results: []elasticsearch.IndexResult{
+			{Id: "1", StatusCode: 200},
+			{Id: "2", StatusCode: 400, Message: "mapper_parsing_exception: failed to parse"},
+			{Id: "3", StatusCode: 409, Message: "version_conflict_engine_exception: conflict"},
+		},
+	}, nil)
+	result.Add(elasticsearch.BulkResult{}, errors.New("hmm"))
+
+	assert.Equal(t, &Result{
+		Successful: 1,
+		Failed:     1,
+		Conflicts:  1,
+		Errors:     []string{"hmm"},
+		Failures: []elasticsearch.IndexResult{
+			{Id: "2", StatusCode: 400, Message: "mapper_parsing_exception: failed to parse"},
+		},
+	}, result)
+}
@@ -0,0 +1,205 @@
+package index
+
+import (
+	"crypto/rand"
+	"encoding/hex"
+	"encoding/json"
+	"net/http"
+	"sync"
+	"time"
+
+	"github.com/gorilla/mux"
+	"github.com/ministryofjustice/opg-search-service/internal/elasticsearch"
+	"github.com/ministryofjustice/opg-search-service/internal/response"
+	"github.com/sirupsen/logrus"
+)
+
+// jobRetention is how long a finished job can be polled for before it is
+// removed from the store
+const jobRetention = time.Hour
+
+// jobTimeout is how long a job can run before its requests are cancelled and
+// it is stopped
+const jobTimeout = 30 * time.Minute
+
+const (
+	JobRunning   = "running"
+	JobCompleted = "completed"
+	JobFailed    = "failed"
+)
+
+// Job is an index request that is processed after the response is sent, its
+// progress is updated as each bulk request is made
+type Job struct {
+	mu          sync.Mutex
+	now         func() time.Time
+	id          string
+	total       int
+	createdAt   time.Time
+	completedAt time.Time
+	stopped     bool
+	response    indexResponse
+}
+
+type jobStatus struct {
+	ID          string     `json:"id"`
+	Status      string     `json:"status"`
+	Total       int        `json:"total"`
+	Processed   int        `json:"processed"`
+	CreatedAt   time.Time  `json:"createdAt"`
+	CompletedAt *time.Time `json:"completedAt,omitempty"`
+	indexResponse
+}
+
+func (j *Job) Add(result elasticsearch.BulkResult, err error) {
+	j.mu.Lock()
+	defer j.mu.Unlock()
+
+	j.response.Add(result, err)
+}
+
+func (j *Job) fail(err error) {
+	j.mu.Lock()
+	defer j.mu.Unlock()
+
+	j.response.Errors = append(j.response.Errors, err.Error())
+}
+
+func (j *Job) complete() {
+	j.mu.Lock()
+	defer j.mu.Unlock()
+
+	j.completedAt = j.now()
+}
+
+// stop finishes a job that did not process all of its items, because it ran
+// past jobTimeout or panicked
+func (j *Job) stop(err error) {
+	j.mu.Lock()
+	defer j.mu.Unlock()
+
+	j.response.Errors = append(j.response.Errors, err.Error())
+	j.completedAt = j.now()
+	j.stopped = true
+}
+
+func (j *Job) MarshalJSON() ([]byte, error) {
+	j.mu.Lock()
+	defer j.mu.Unlock()
+
+	status := jobStatus{
+		ID:            j.id,
+		Status:        JobRunning,
+		Total:         j.total,
+		Processed:     len(j.response.Results),
+		CreatedAt:     j.createdAt,
+		indexResponse: j.response,
+	}
+
+	if !j.completedAt.IsZero() {
+		completedAt := j.completedAt
+		status.Status = JobCompleted
+		status.CompletedAt = &completedAt
+	}
+
+	if j.stopped {
+		status.Status = JobFailed
+	}
+
+	return json.Marshal(status)
+}
+
+// JobStore holds jobs in memory, so a job can only be polled on the instance
+// that accepted it and is lost if the service restarts. Job ids are random, so
+// polling another instance returns a 404 rather than another job.
+type JobStore struct {
+	mu   sync.Mutex
+	jobs map[string]*Job
+	now  func() time.Time
+}
+
+func NewJobStore() *JobStore {
+	return &JobStore{
+		jobs: map[string]*Job{},
+		now:  time.Now,
+	}
+}
+
+func (s *JobStore) Create(total int) (*Job, error) {
+	b := make([]byte, 16)
+	if _, err := rand.Read(b); err != nil {
+		return nil, err
+	}
+
+	job := &Job{
+		now:       s.now,
+		id:        hex.EncodeToString(b),
+		total:     total,
+		createdAt: s.now(),
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	s.expire()
+	s.jobs[job.id] = job
+
+	return job, nil
+}
+
+func (s *JobStore) Get(id string) (*Job, bool) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	job, ok := s.jobs[id]
+	return job, ok
+}
+
+// expire removes jobs that finished longer ago than jobRetention, and jobs
+// still running long after jobTimeout as they will never finish. It must be
+// called with the lock held.
+func (s *JobStore) expire() {
+	cutoff := s.now().Add(-jobRetention)
+	stuck := cutoff.Add(-jobTimeout)
+
+	for id, job := range s.jobs {
+		job.mu.Lock()
+		expired := !job.completedAt.IsZero() && job.completedAt.Before(cutoff) ||
+			job.completedAt.IsZero() && job.createdAt.Before(stuck)
+		job.mu.Unlock()
+
+		if expired {
+			delete(s.jobs, id)
+		}
+	}
+}
+
+type JobHandler struct {
+	logger *logrus.Logger
+	jobs   *JobStore
+}
+
+func NewJobHandler(logger *logrus.Logger, jobs *JobStore) *JobHandler {
+	return &JobHandler{
+		logger: logger,
+		jobs:   jobs,
+	}
+}
+
+func (h *JobHandler) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	job, ok := h.jobs.Get(mux.Vars(r)["id"])
+	if !ok {
+		response.WriteJSONErrors(w, "could not find job", []response.Error{}, http.StatusNotFound)
+		return
+	}
+
+	jsonResp, err := json.Marshal(job)
+	if err != nil {
+		h.logger.Println(err)
+		response.WriteJSONErrors(w, "unable to write job", []response.Error{}, http.StatusInternalServerError)
+		return
+	}
+
+	w.WriteHeader(http.StatusOK)
+	_, _ = w.Write(jsonResp)
+}
@@ -0,0 +1,98 @@
+package index
+
+import (
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/gorilla/mux"
+	"github.com/ministryofjustice/opg-search-service/internal/elasticsearch"
+	"github.com/sirupsen/logrus/hooks/test"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestJobStoreExpiresCompletedJobs(t *testing.T) {
+	now := time.Date(2026, 1, 2, 3, 4, 5, 0, time.UTC)
+	store := NewJobStore()
+	store.now = func() time.Time { return now }
+
+	completed, _ := store.Create(1)
+	completed.complete()
+	running, _ := store.Create(1)
+
+	now = now.Add(jobRetention + time.Second)
+	_, _ = store.Create(1)
+
+	_, ok := store.Get(completed.id)
+	assert.False(t, ok)
+	_, ok = store.Get(running.id)
+	assert.True(t, ok)
+}
+
+func TestJobStoreExpiresStuckJobs(t *testing.T) {
+	now := time.Date(2026, 1, 2, 3, 4, 5, 0, time.UTC)
+	store := NewJobStore()
+	store.now = func() time.Time { return now }
+
+	stuck, _ := store.Create(1)
+
+	now = now.Add(jobTimeout + jobRetention - time.Second)
+	running, _ := store.Create(1)
+
+	_, ok := store.Get(stuck.id)
+	assert.True(t, ok)
+
+	now = now.Add(2 * time.Second)
+	_, _ = store.Create(1)
+
+	_, ok = store.Get(stuck.id)
+	assert.False(t, ok)
+	_, ok = store.Get(running.id)
+	assert.True(t, ok)
+}
+
+func TestJobStop(t *testing.T) {
+	store := NewJobStore()
+	store.now = func() time.Time { return time.Date(2026, 1, 2, 3, 4, 5, 0, time.UTC) }
+
+	job, _ := store.Create(2)
+	job.stop(errors.New("job stopped: context deadline exceeded"))
+
+	data, err := job.MarshalJSON()
+	assert.Nil(t, err)
+	assert.Equal(t, `{"id":"`+job.id+`","status":"failed","total":2,"processed":0,"createdAt":"2026-01-02T03:04:05Z",`+
+		`"completedAt":"2026-01-02T03:04:05Z","successful":0,"failed":0,"errors":["job stopped: context deadline exceeded"]}`, string(data))
+}
+
+func TestJobHandler(t *testing.T) {
+	l, _ := test.NewNullLogger()
+	store := NewJobStore()
+	store.now = func() time.Time { return time.Date(2026, 1, 2, 3, 4, 5, 0, time.UTC) }
+
+	job, _ := store.Create(2)
+	job.Add(elasticsearch.BulkResult{
+		Failed:  1,
+		Results: []elasticsearch.IndexResult{{Id: "1", Index: "person_abc", StatusCode: 400, Message: "bad"}},
+	}, nil)
+
+	w := httptest.NewRecorder()
+	r := mux.SetURLVars(httptest.NewRequest(http.MethodGet, "/jobs/"+job.id, nil), map[string]string{"id": job.id})
+	NewJobHandler(l, store).ServeHTTP(w, r)
+
+	assert.Equal(t, http.StatusOK, w.Code)
+	assert.Equal(t, `{"id":"`+job.id+`","status":"running","total":2,"processed":1,"createdAt":"2026-01-02T03:04:05Z",`+
+		`"successful":0,"failed":1,"results":[{"id":"1","index":"person_abc","statusCode":400,"message":"bad"}]}`, w.Body.String())
+}
+
+func TestJobHandlerNotFound(t *testing.T) {
+	l, _ := test.NewNullLogger()
+
+	w := httptest.NewRecorder()
+	r := mux.SetURLVars(httptest.NewRequest(http.MethodGet, "/jobs/abc", nil), map[string]string{"id": "abc"})
+	NewJobHandler(l, NewJobStore()).ServeHTTP(w, r)
+
+	assert.Equal(t, http.StatusNotFound, w.Code)
+	assert.Equal(t, `{"message":"could not find job","errors":[]}`+"\n", w.Body.String())
+}
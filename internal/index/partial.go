@@ -0,0 +1,119 @@
+package index
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"reflect"
+	"strings"
+
+	"github.com/ministryofjustice/opg-search-service/internal/response"
+)
+
+// PartialItem is a document that only includes the fields to be changed
+type PartialItem struct {
+	id        string
+	fields    map[string]json.RawMessage
+	arrayKeys map[string]string
+}
+
+func (p PartialItem) Id() string {
+	return p.id
+}
+
+func (p PartialItem) ArrayKeys() map[string]string {
+	return p.arrayKeys
+}
+
+func (p PartialItem) MarshalJSON() ([]byte, error) {
+	return json.Marshal(p.fields)
+}
+
+type partialRequest struct {
+	key   string
+	items []PartialItem
+	errs  []response.Error
+}
+
+func (r *partialRequest) Validate() []response.Error {
+	if len(r.items) == 0 && len(r.errs) == 0 {
+		return []response.Error{{
+			Name:        r.key,
+			Description: "field is empty",
+		}}
+	}
+
+	return r.errs
+}
+
+func (r *partialRequest) Items() []Indexable {
+	indexables := make([]Indexable, len(r.items))
+	for i, item := range r.items {
+		indexables[i] = item
+	}
+
+	return indexables
+}
+
+// ParsePartial reads a request of the form {key: [...]} where each item must
+// have idField set and any other fields must be those of entity, with the
+// same types, so that a partial item cannot write fields a full one could not.
+// Elements of the arrays in arrayKeys are added to the indexed array by their
+// key, see elasticsearch.Merged.
+func ParsePartial(body []byte, key, idField string, entity interface{}, arrayKeys map[string]string) (Validatable, error) {
+	var req map[string][]map[string]json.RawMessage
+	if err := json.Unmarshal(body, &req); err != nil {
+		return nil, err
+	}
+
+	entityType := reflect.TypeOf(entity)
+	pr := &partialRequest{key: key}
+
+	for i, fields := range req[key] {
+		name := fmt.Sprintf("%s[%d]", key, i)
+
+		id := partialID(fields[idField])
+		if id == "" {
+			pr.errs = append(pr.errs, response.Error{
+				Name:        name + "." + idField,
+				Description: "field is empty",
+			})
+			continue
+		}
+
+		data, _ := json.Marshal(fields)
+		dec := json.NewDecoder(bytes.NewReader(data))
+		dec.DisallowUnknownFields()
+
+		if err := dec.Decode(reflect.New(entityType).Interface()); err != nil {
+			pr.errs = append(pr.errs, response.Error{
+				Name:        name,
+				Description: strings.TrimPrefix(err.Error(), "json: "),
+			})
+			continue
+		}
+
+		pr.items = append(pr.items, PartialItem{id: id, fields: fields, arrayKeys: arrayKeys})
+	}
+
+	return pr, nil
+}
+
+func partialID(raw json.RawMessage) string {
+	var id interface{}
+
+	dec := json.NewDecoder(bytes.NewReader(raw))
+	dec.UseNumber()
+	if err := dec.Decode(&id); err != nil {
+		return ""
+	}
+
+	switch v := id.(type) {
+	case string:
+		return v
+	case json.Number:
+		return v.String()
+	default:
+		return ""
+	}
+}
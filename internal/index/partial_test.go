@@ -0,0 +1,78 @@
+package index
+
+import (
+	"encoding/json"
+	"testing"
+
+	"github.com/ministryofjustice/opg-search-service/internal/response"
+	"github.com/stretchr/testify/assert"
+)
+
+type partialEntity struct {
+	ID      *int64   `json:"id"`
+	Name    string   `json:"name"`
+	Numbers []string `json:"numbers"`
+}
+
+func TestParsePartial(t *testing.T) {
+	req, err := ParsePartial([]byte(`{"things":[{"id":13,"name":"a"},{"id":14,"numbers":["1"]}]}`), "things", "id", partialEntity{}, map[string]string{"numbers": ""})
+	assert.Nil(t, err)
+	assert.Empty(t, req.Validate())
+
+	items := req.Items()
+	assert.Len(t, items, 2)
+	assert.Equal(t, "13", items[0].Id())
+	assert.Equal(t, "14", items[1].Id())
+
+	data, err := json.Marshal(items[1])
+	assert.Nil(t, err)
+	assert.JSONEq(t, `{"id":14,"numbers":["1"]}`, string(data))
+	assert.Equal(t, map[string]string{"numbers": ""}, items[1].(PartialItem).ArrayKeys())
+}
+
+func TestParsePartialStringID(t *testing.T) {
+	req, err := ParsePartial([]byte(`{"things":[{"uId":"M-1234","name":"a"}]}`), "things", "uId", struct {
+		UID  string `json:"uId"`
+		Name string `json:"name"`
+	}{}, nil)
+	assert.Nil(t, err)
+	assert.Empty(t, req.Validate())
+	assert.Equal(t, "M-1234", req.Items()[0].Id())
+}
+
+func TestParsePartialInvalid(t *testing.T) {
+	testCases := map[string]struct {
+		body     string
+		expected []response.Error
+	}{
+		"missing key": {
+			body:     `{}`,
+			expected: []response.Error{{Name: "things", Description: "field is empty"}},
+		},
+		"missing id": {
+			body:     `{"things":[{"name":"a"}]}`,
+			expected: []response.Error{{Name: "things[0].id", Description: "field is empty"}},
+		},
+		"unknown field": {
+			body:     `{"things":[{"id":1,"colour":"red"}]}`,
+			expected: []response.Error{{Name: "things[0]", Description: `unknown field "colour"`}},
+		},
+		"wrong type": {
+			body:     `{"things":[{"id":1},{"id":2,"numbers":"1"}]}`,
+			expected: []response.Error{{Name: "things[1]", Description: "cannot unmarshal string into Go struct field partialEntity.numbers of type []string"}},
+		},
+	}
+
+	for name, tc := range testCases {
+		t.Run(name, func(t *testing.T) {
+			req, err := ParsePartial([]byte(tc.body), "things", "id", partialEntity{}, nil)
+			assert.Nil(t, err)
+			assert.Equal(t, tc.expected, req.Validate())
+		})
+	}
+}
+
+func TestParsePartialBadJSON(t *testing.T) {
+	_, err := ParsePartial([]byte(`{"things":{}}`), "things", "id", partialEntity{}, nil)
+	assert.NotNil(t, err)
+}
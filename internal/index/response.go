@@ -5,12 +5,17 @@ import "github.com/ministryofjustice/opg-search-service/internal/elasticsearch"
 type indexResponse struct {
 	Successful int      `json:"successful"`
 	Failed     int      `json:"failed"`
+	Conflicts  int      `json:"conflicts,omitempty"`
 	Errors     []string `json:"errors,omitempty"`
+	// Results has the outcome of each item, so that failures can be retried
+	Results []elasticsearch.IndexResult `json:"results,omitempty"`
 }
 
 func (r *indexResponse) Add(result elasticsearch.BulkResult, err error) {
 	r.Successful += result.Successful
 	r.Failed += result.Failed
+	r.Conflicts += result.Conflicts
+	r.Results = append(r.Results, result.Results...)
 
 	if err != nil {
 		r.Errors = append(r.Errors, err.Error())
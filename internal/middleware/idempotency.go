@@ -0,0 +1,204 @@
+package middleware
+
+import (
+	"bytes"
+	"container/list"
+	"crypto/sha256"
+	"encoding/hex"
+	"io"
+	"net/http"
+	"sync"
+	"time"
+
+	"github.com/ministryofjustice/opg-search-service/internal/response"
+)
+
+const IdempotencyKeyHeader = "Idempotency-Key"
+
+// IdempotentReplayedHeader is set on a response that was replayed from an
+// earlier request with the same key
+const IdempotentReplayedHeader = "Idempotent-Replayed"
+
+type idempotentResponse struct {
+	key         string
+	fingerprint string
+	done        bool
+	expires     time.Time
+	status      int
+	header      http.Header
+	body        []byte
+}
+
+// IdempotencyStore holds responses in memory, keyed by the caller and their
+// Idempotency-Key, for the retention given. At most maxBytes of response
+// bodies are kept, the oldest responses are dropped to make room. Responses
+// are not shared between instances of the service, nor kept over a restart.
+type IdempotencyStore struct {
+	mu        sync.Mutex
+	responses map[string]*idempotentResponse
+	// finished holds the completed responses in the order they finished,
+	// which is also the order they expire in as the retention is fixed
+	finished  *list.List
+	size      int
+	maxBytes  int
+	retention time.Duration
+	now       func() time.Time
+}
+
+func NewIdempotencyStore(retention time.Duration, maxBytes int) *IdempotencyStore {
+	return &IdempotencyStore{
+		responses: map[string]*idempotentResponse{},
+		finished:  list.New(),
+		maxBytes:  maxBytes,
+		retention: retention,
+		now:       time.Now,
+	}
+}
+
+// start returns a copy of the stored response for the key, or reserves the key
+// when there is not one so that a concurrent request with it is not also
+// handled. A copy is returned as the stored response is changed by finish.
+func (s *IdempotencyStore) start(key, fingerprint string) (idempotentResponse, bool) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	s.evict()
+
+	if existing, ok := s.responses[key]; ok {
+		v := *existing
+		v.header = existing.header.Clone()
+		return v, true
+	}
+
+	s.responses[key] = &idempotentResponse{key: key, fingerprint: fingerprint}
+	return idempotentResponse{}, false
+}
+
+func (s *IdempotencyStore) finish(key string, status int, header http.Header, body []byte) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	// a server error is not kept, so that the request can be retried, nor is
+	// a response too large to ever fit in the store
+	if status >= http.StatusInternalServerError || len(body) > s.maxBytes {
+		delete(s.responses, key)
+		return
+	}
+
+	v := s.responses[key]
+	v.done = true
+	v.expires = s.now().Add(s.retention)
+	v.status = status
+	v.header = header
+	v.body = body
+
+	s.finished.PushBack(v)
+	s.size += len(body)
+	s.evict()
+}
+
+// evict removes responses from the front of finished until the rest have not
+// expired and fit in maxBytes, it must be called with mu held
+func (s *IdempotencyStore) evict() {
+	now := s.now()
+
+	for front := s.finished.Front(); front != nil; front = s.finished.Front() {
+		v := front.Value.(*idempotentResponse)
+		if s.size <= s.maxBytes && !now.After(v.expires) {
+			return
+		}
+
+		s.finished.Remove(front)
+		s.size -= len(v.body)
+		delete(s.responses, v.key)
+	}
+}
+
+// Idempotency replays the response to a request that has the same
+// Idempotency-Key as an earlier one, so that a retried request is not
+// handled twice. Reusing a key for a different request is rejected.
+// Requests without the header are handled as normal.
+func Idempotency(store *IdempotencyStore) func(next http.Handler) http.Handler {
+	return func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(rw http.ResponseWriter, r *http.Request) {
+			idempotencyKey := r.Header.Get(IdempotencyKeyHeader)
+			if idempotencyKey == "" {
+				next.ServeHTTP(rw, r)
+				return
+			}
+
+			body, err := io.ReadAll(r.Body)
+			if err != nil {
+				response.WriteJSONError(rw, "request", "Unable to read request body", http.StatusBadRequest)
+				return
+			}
+			r.Body = io.NopCloser(bytes.NewReader(body))
+
+			// keys are scoped to the caller, so one caller cannot replay
+			// another's response
+			caller, _ := r.Context().Value(HashedEmail{}).(string)
+			key := caller + ":" + idempotencyKey
+			fingerprint := fingerprintRequest(r, body)
+
+			existing, ok := store.start(key, fingerprint)
+			if ok {
+				switch {
+				case existing.fingerprint != fingerprint:
+					response.WriteJSONError(rw, IdempotencyKeyHeader, "key has already been used for a different request", http.StatusUnprocessableEntity)
+				case !existing.done:
+					response.WriteJSONError(rw, IdempotencyKeyHeader, "a request with this key is in progress", http.StatusConflict)
+				default:
+					for name, values := range existing.header {
+						rw.Header()[name] = values
+					}
+					rw.Header().Set(IdempotentReplayedHeader, "true")
+					rw.WriteHeader(existing.status)
+					_, _ = rw.Write(existing.body)
+				}
+				return
+			}
+
+			rec := &recordingWriter{ResponseWriter: rw, status: http.StatusOK}
+			defer func() {
+				if p := recover(); p != nil {
+					// release the key so the request can be retried
+					store.finish(key, http.StatusInternalServerError, nil, nil)
+					panic(p)
+				}
+
+				store.finish(key, rec.status, rw.Header().Clone(), rec.body.Bytes())
+			}()
+
+			next.ServeHTTP(rec, r)
+		})
+	}
+}
+
+func fingerprintRequest(r *http.Request, body []byte) string {
+	hash := sha256.New()
+	_, _ = hash.Write([]byte(r.Method + " " + r.URL.Path + "?" + r.URL.RawQuery + "\n"))
+	_, _ = hash.Write(body)
+	return hex.EncodeToString(hash.Sum(nil))
+}
+
+// recordingWriter keeps a copy of the response as it is written
+type recordingWriter struct {
+	http.ResponseWriter
+	status      int
+	wroteHeader bool
+	body        bytes.Buffer
+}
+
+func (w *recordingWriter) WriteHeader(status int) {
+	if !w.wroteHeader {
+		w.status = status
+		w.wroteHeader = true
+	}
+	w.ResponseWriter.WriteHeader(status)
+}
+
+func (w *recordingWriter) Write(b []byte) (int, error) {
+	w.wroteHeader = true
+	w.body.Write(b)
+	return w.ResponseWriter.Write(b)
+}
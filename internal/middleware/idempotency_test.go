@@ -0,0 +1,191 @@
+package middleware
+
+import (
+	"context"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+type countingHandler struct {
+	calls  int
+	status int
+}
+
+func (h *countingHandler) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	h.calls++
+	body, _ := io.ReadAll(r.Body)
+	w.Header().Set("X-Test", "yes")
+	w.WriteHeader(h.status)
+	_, _ = w.Write(append([]byte("handled "), body...))
+}
+
+func serveIdempotent(handler http.Handler, key, caller, path, body string) *httptest.ResponseRecorder {
+	r := httptest.NewRequest(http.MethodPost, path, strings.NewReader(body))
+	if key != "" {
+		r.Header.Set(IdempotencyKeyHeader, key)
+	}
+	r = r.WithContext(context.WithValue(r.Context(), HashedEmail{}, caller))
+
+	w := httptest.NewRecorder()
+	handler.ServeHTTP(w, r)
+	return w
+}
+
+func TestIdempotencyReplaysResponse(t *testing.T) {
+	next := &countingHandler{status: http.StatusAccepted}
+	handler := Idempotency(NewIdempotencyStore(time.Hour, 1024))(next)
+
+	first := serveIdempotent(handler, "abc", "me", "/persons", `{"a":1}`)
+	second := serveIdempotent(handler, "abc", "me", "/persons", `{"a":1}`)
+
+	assert.Equal(t, 1, next.calls)
+	assert.Equal(t, http.StatusAccepted, first.Code)
+	assert.Equal(t, http.StatusAccepted, second.Code)
+	assert.Equal(t, `handled {"a":1}`, second.Body.String())
+	assert.Equal(t, "yes", second.Header().Get("X-Test"))
+	assert.Equal(t, "", first.Header().Get(IdempotentReplayedHeader))
+	assert.Equal(t, "true", second.Header().Get(IdempotentReplayedHeader))
+}
+
+func TestIdempotencyRejectsDifferentRequest(t *testing.T) {
+	next := &countingHandler{status: http.StatusOK}
+	handler := Idempotency(NewIdempotencyStore(time.Hour, 1024))(next)
+
+	_ = serveIdempotent(handler, "abc", "me", "/persons", `{"a":1}`)
+	differentBody := serveIdempotent(handler, "abc", "me", "/persons", `{"a":2}`)
+	differentPath := serveIdempotent(handler, "abc", "me", "/firms", `{"a":1}`)
+
+	assert.Equal(t, 1, next.calls)
+	assert.Equal(t, http.StatusUnprocessableEntity, differentBody.Code)
+	assert.Equal(t, `{"message":"Idempotency-Key","errors":[{"name":"Idempotency-Key","description":"key has already been used for a different request"}]}`+"\n", differentBody.Body.String())
+	assert.Equal(t, http.StatusUnprocessableEntity, differentPath.Code)
+}
+
+func TestIdempotencyWithoutKey(t *testing.T) {
+	next := &countingHandler{status: http.StatusOK}
+	handler := Idempotency(NewIdempotencyStore(time.Hour, 1024))(next)
+
+	_ = serveIdempotent(handler, "", "me", "/persons", `{"a":1}`)
+	_ = serveIdempotent(handler, "", "me", "/persons", `{"a":1}`)
+
+	assert.Equal(t, 2, next.calls)
+}
+
+func TestIdempotencyKeysAreScopedToCaller(t *testing.T) {
+	next := &countingHandler{status: http.StatusOK}
+	handler := Idempotency(NewIdempotencyStore(time.Hour, 1024))(next)
+
+	_ = serveIdempotent(handler, "abc", "me", "/persons", `{"a":1}`)
+	_ = serveIdempotent(handler, "abc", "you", "/persons", `{"a":1}`)
+
+	assert.Equal(t, 2, next.calls)
+}
+
+func TestIdempotencyDoesNotKeepServerErrors(t *testing.T) {
+	next := &countingHandler{status: http.StatusInternalServerError}
+	handler := Idempotency(NewIdempotencyStore(time.Hour, 1024))(next)
+
+	_ = serveIdempotent(handler, "abc", "me", "/persons", `{"a":1}`)
+	_ = serveIdempotent(handler, "abc", "me", "/persons", `{"a":1}`)
+
+	assert.Equal(t, 2, next.calls)
+}
+
+func TestIdempotencyExpires(t *testing.T) {
+	now := time.Date(2026, 1, 2, 3, 4, 5, 0, time.UTC)
+	store := NewIdempotencyStore(time.Hour, 1024)
+	store.now = func() time.Time { return now }
+
+	next := &countingHandler{status: http.StatusOK}
+	handler := Idempotency(store)(next)
+
+	_ = serveIdempotent(handler, "abc", "me", "/persons", `{"a":1}`)
+	now = now.Add(time.Hour + time.Second)
+	_ = serveIdempotent(handler, "abc", "me", "/persons", `{"a":2}`)
+
+	assert.Equal(t, 2, next.calls)
+}
+
+func TestIdempotencyInProgress(t *testing.T) {
+	store := NewIdempotencyStore(time.Hour, 1024)
+	store.start("me:abc", fingerprintRequest(httptest.NewRequest(http.MethodPost, "/persons", nil), []byte(`{"a":1}`)))
+
+	next := &countingHandler{status: http.StatusOK}
+	w := serveIdempotent(Idempotency(store)(next), "abc", "me", "/persons", `{"a":1}`)
+
+	assert.Equal(t, 0, next.calls)
+	assert.Equal(t, http.StatusConflict, w.Code)
+}
+
+func TestIdempotencyConcurrentDuplicates(t *testing.T) {
+	var calls atomic.Int32
+	next := http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		calls.Add(1)
+		time.Sleep(time.Millisecond)
+		w.Header().Set("X-Test", "yes")
+		w.WriteHeader(http.StatusAccepted)
+		_, _ = w.Write([]byte("handled"))
+	})
+	handler := Idempotency(NewIdempotencyStore(time.Hour, 1024))(next)
+
+	var wg sync.WaitGroup
+	codes := make([]int, 50)
+	for i := range codes {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			w := serveIdempotent(handler, "abc", "me", "/persons", `{"a":1}`)
+			codes[i] = w.Code
+
+			if w.Code == http.StatusAccepted {
+				assert.Equal(t, "handled", w.Body.String())
+				assert.Equal(t, "yes", w.Header().Get("X-Test"))
+			}
+		}()
+	}
+	wg.Wait()
+
+	assert.Equal(t, int32(1), calls.Load())
+	for _, code := range codes {
+		assert.Contains(t, []int{http.StatusAccepted, http.StatusConflict}, code)
+	}
+}
+
+func TestIdempotencyDropsOldestOverMaxBytes(t *testing.T) {
+	store := NewIdempotencyStore(time.Hour, 40)
+
+	next := &countingHandler{status: http.StatusOK}
+	handler := Idempotency(store)(next)
+
+	_ = serveIdempotent(handler, "a", "me", "/persons", `{"a":1}`)
+	_ = serveIdempotent(handler, "b", "me", "/persons", `{"b":1}`)
+	_ = serveIdempotent(handler, "c", "me", "/persons", `{"c":1}`)
+	assert.Len(t, store.responses, 2)
+
+	_ = serveIdempotent(handler, "b", "me", "/persons", `{"b":1}`)
+	assert.Equal(t, 3, next.calls)
+
+	_ = serveIdempotent(handler, "a", "me", "/persons", `{"a":1}`)
+	assert.Equal(t, 4, next.calls)
+}
+
+func TestIdempotencyDoesNotKeepLargeResponses(t *testing.T) {
+	store := NewIdempotencyStore(time.Hour, 10)
+
+	next := &countingHandler{status: http.StatusOK}
+	handler := Idempotency(store)(next)
+
+	_ = serveIdempotent(handler, "a", "me", "/persons", `{"a":1}`)
+	_ = serveIdempotent(handler, "a", "me", "/persons", `{"a":1}`)
+
+	assert.Equal(t, 2, next.calls)
+	assert.Empty(t, store.responses)
+}
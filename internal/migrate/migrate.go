@@ -0,0 +1,421 @@
+package migrate
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"math"
+	"time"
+
+	"github.com/ministryofjustice/opg-search-service/internal/elasticsearch"
+	"github.com/ministryofjustice/opg-search-service/internal/index"
+)
+
+const (
+	// SourceDatabase builds the new index by reading every record
+	SourceDatabase = "database"
+	// SourceReindex builds the new index by copying the old index
+	SourceReindex = "reindex"
+)
+
+// catchUpMargin is subtracted from the time the build started when catching
+// up, to allow for the clocks of the database and this process differing
+const catchUpMargin = time.Minute
+
+type Client interface {
+	index.BulkClient
+	Search(ctx context.Context, indices []string, requestBody map[string]interface{}) (*elasticsearch.SearchResult, error)
+	IndexExists(ctx context.Context, name string) (bool, error)
+	CreateIndex(ctx context.Context, name string, config []byte, force bool) error
+	DeleteIndex(ctx context.Context, name string) error
+	ResolveAlias(ctx context.Context, alias string) (string, error)
+	CreateAlias(ctx context.Context, alias, index string) error
+	UpdateAlias(ctx context.Context, alias, oldIndex, newIndex string) error
+	Reindex(ctx context.Context, source, dest string) error
+	Refresh(ctx context.Context, name string) error
+	Count(ctx context.Context, name string) (int, error)
+}
+
+type Logger interface {
+	Printf(string, ...interface{})
+}
+
+// Index is an index to migrate the alias to, and the database its records are
+// read from. Without a database the index is built by copying the old index
+// whatever the Source.
+type Index struct {
+	Alias  string
+	Name   string
+	Config []byte
+	DB     index.DB
+	// Query builds the search the service would run to find a document
+	// sampled from the old index, so that its top hits can be compared between
+	// the old and new index. It returns false when the document has nothing
+	// to search for.
+	Query func(doc json.RawMessage) (map[string]interface{}, bool)
+}
+
+type Options struct {
+	// Source is how the new index is built, SourceDatabase or SourceReindex
+	Source    string
+	BatchSize int
+	Workers   int
+	// Samples is the number of documents picked at random from the old index
+	// that must also be in the new index
+	Samples int
+	// CountTolerance is the fraction by which the number of documents in the
+	// new index may differ from the old index
+	CountTolerance float64
+	// Queries is the number of sampled documents to search for in both the
+	// old and new index, and TopHits the number of hits that must match
+	Queries int
+	TopHits int
+	// Cleanup deletes the old indices once every alias has been moved
+	Cleanup bool
+}
+
+// Migrator moves aliases to new indices. Each new index is built, caught up
+// with changes made while it was being built and checked against the old
+// index before its alias is moved. If any step fails every alias that was
+// moved is moved back, and every index that was created is deleted.
+type Migrator struct {
+	client  Client
+	log     Logger
+	options Options
+	now     func() time.Time
+}
+
+func New(client Client, logger Logger, options Options) *Migrator {
+	return &Migrator{
+		client:  client,
+		log:     logger,
+		options: options,
+		now:     time.Now,
+	}
+}
+
+type migration struct {
+	alias    string
+	oldIndex string
+	newIndex string
+	created  bool
+	swapped  bool
+}
+
+func (m *Migrator) Run(ctx context.Context, indices []Index) error {
+	if m.options.Source != SourceDatabase && m.options.Source != SourceReindex {
+		return fmt.Errorf("source must be %s or %s", SourceDatabase, SourceReindex)
+	}
+
+	var migrations []*migration
+	for _, idx := range indices {
+		mig, err := m.migrate(ctx, idx)
+		if mig != nil {
+			migrations = append(migrations, mig)
+		}
+
+		if err != nil {
+			err = fmt.Errorf("migrating %s to %s: %w", idx.Alias, idx.Name, err)
+			m.log.Printf("%s, rolling back", err.Error())
+
+			return errors.Join(err, m.rollback(ctx, migrations))
+		}
+	}
+
+	if !m.options.Cleanup {
+		return nil
+	}
+
+	// once deleted an old index cannot be rolled back to, so a failure here
+	// leaves the aliases on the new indices
+	for _, mig := range migrations {
+		if mig.oldIndex == "" {
+			continue
+		}
+
+		if err := m.client.DeleteIndex(ctx, mig.oldIndex); err != nil {
+			return fmt.Errorf("cleaning up %s: %w", mig.oldIndex, err)
+		}
+	}
+
+	return nil
+}
+
+// migrate moves the alias to the new index, the migration returned records
+// what was changed so that it can be rolled back
+func (m *Migrator) migrate(ctx context.Context, idx Index) (*migration, error) {
+	oldIndex, err := m.client.ResolveAlias(ctx, idx.Alias)
+	if errors.Is(err, elasticsearch.ErrAliasMissing) {
+		oldIndex = ""
+	} else if err != nil {
+		return nil, err
+	}
+
+	if oldIndex == idx.Name {
+		m.log.Printf("alias '%s' is already set to '%s'", idx.Alias, idx.Name)
+		return nil, nil
+	}
+
+	// an index without a database can only be built by copying the old one
+	if idx.DB == nil && oldIndex == "" {
+		return nil, fmt.Errorf("no database to read %s records from", idx.Alias)
+	}
+
+	mig := &migration{alias: idx.Alias, oldIndex: oldIndex, newIndex: idx.Name}
+
+	// the service creates its current indices when it starts, and writes to
+	// them, so an index that already exists is kept on rollback
+	exists, err := m.client.IndexExists(ctx, idx.Name)
+	if err != nil {
+		return nil, err
+	}
+	if !exists {
+		if err := m.client.CreateIndex(ctx, idx.Name, idx.Config, false); err != nil {
+			return nil, err
+		}
+		mig.created = true
+	}
+
+	source := m.options.Source
+	if idx.DB == nil {
+		source = SourceReindex
+	}
+
+	startedAt := m.now()
+
+	m.log.Printf("building '%s' from %s", idx.Name, source)
+	if source == SourceReindex && oldIndex != "" {
+		err = m.client.Reindex(ctx, oldIndex, idx.Name)
+	} else {
+		err = m.checkResult(m.indexer(idx).All(ctx, m.options.BatchSize))
+	}
+	if err != nil {
+		return mig, fmt.Errorf("building: %w", err)
+	}
+
+	// without a database the changes made while copying were written to
+	// both indices by the service, so there is nothing to catch up on
+	if idx.DB != nil {
+		catchUpFrom := startedAt.Add(-catchUpMargin)
+		m.log.Printf("catching up '%s' with changes from %s", idx.Name, catchUpFrom.Format(time.RFC3339))
+		if err := m.checkResult(m.indexer(idx).FromDate(ctx, catchUpFrom, m.options.BatchSize)); err != nil {
+			return mig, fmt.Errorf("catching up: %w", err)
+		}
+	}
+
+	if oldIndex != "" {
+		if err := m.verify(ctx, oldIndex, idx.Name, idx.Query); err != nil {
+			return mig, fmt.Errorf("verifying: %w", err)
+		}
+	}
+
+	if oldIndex == "" {
+		err = m.client.CreateAlias(ctx, idx.Alias, idx.Name)
+	} else {
+		err = m.client.UpdateAlias(ctx, idx.Alias, oldIndex, idx.Name)
+	}
+	if err != nil {
+		return mig, fmt.Errorf("moving alias: %w", err)
+	}
+	mig.swapped = true
+
+	m.log.Printf("alias '%s' moved from '%s' to '%s'", idx.Alias, oldIndex, idx.Name)
+	return mig, nil
+}
+
+// verify checks that the new index has about as many documents as the old
+// index, has a sample of the documents in the old index, and that searches for
+// some of those documents return the same top hits from both
+func (m *Migrator) verify(ctx context.Context, oldIndex, newIndex string, query func(json.RawMessage) (map[string]interface{}, bool)) error {
+	for _, name := range []string{oldIndex, newIndex} {
+		if err := m.client.Refresh(ctx, name); err != nil {
+			return err
+		}
+	}
+
+	oldCount, err := m.client.Count(ctx, oldIndex)
+	if err != nil {
+		return err
+	}
+
+	newCount, err := m.client.Count(ctx, newIndex)
+	if err != nil {
+		return err
+	}
+
+	m.log.Printf("'%s' has %d documents, '%s' has %d", oldIndex, oldCount, newIndex, newCount)
+	if math.Abs(float64(newCount-oldCount)) > m.options.CountTolerance*float64(max(oldCount, 1)) {
+		return fmt.Errorf("'%s' has %d documents but '%s' has %d", newIndex, newCount, oldIndex, oldCount)
+	}
+
+	if m.options.Samples <= 0 {
+		return nil
+	}
+
+	sample, err := m.client.Search(ctx, []string{oldIndex}, map[string]interface{}{
+		"size":             m.options.Samples,
+		"track_total_hits": false,
+		"query": map[string]interface{}{
+			"function_score": map[string]interface{}{"random_score": map[string]interface{}{}},
+		},
+	})
+	if err != nil {
+		return err
+	}
+	if len(sample.Refs) == 0 {
+		return nil
+	}
+
+	ids := make([]string, len(sample.Refs))
+	for i, ref := range sample.Refs {
+		ids[i] = ref.ID
+	}
+
+	found, err := m.client.Search(ctx, []string{newIndex}, map[string]interface{}{
+		"size":             len(ids),
+		"track_total_hits": false,
+		"query": map[string]interface{}{
+			"ids": map[string]interface{}{"values": ids},
+		},
+	})
+	if err != nil {
+		return err
+	}
+
+	if len(found.Refs) != len(ids) {
+		return fmt.Errorf("%d of %d documents sampled from '%s' are missing from '%s'", len(ids)-len(found.Refs), len(ids), oldIndex, newIndex)
+	}
+
+	return m.compareSearches(ctx, oldIndex, newIndex, sample, query)
+}
+
+// compareSearches runs the search for some of the sampled documents against
+// the old and new index, and checks that the top hits are the same documents.
+// Their order is not compared, as scores depend on the statistics of each
+// index so hits with close scores may swap places.
+func (m *Migrator) compareSearches(ctx context.Context, oldIndex, newIndex string, sample *elasticsearch.SearchResult, query func(json.RawMessage) (map[string]interface{}, bool)) error {
+	if query == nil || m.options.Queries <= 0 {
+		return nil
+	}
+
+	searched := 0
+	for i, doc := range sample.Hits {
+		if searched >= m.options.Queries {
+			break
+		}
+
+		body, ok := query(doc)
+		if !ok {
+			continue
+		}
+		searched++
+
+		body["from"] = 0
+		body["size"] = m.options.TopHits
+
+		oldHits, err := m.topHits(ctx, oldIndex, body)
+		if err != nil {
+			return err
+		}
+
+		newHits, err := m.topHits(ctx, newIndex, body)
+		if err != nil {
+			return err
+		}
+
+		if !sameIDs(oldHits, newHits) {
+			return fmt.Errorf("searching for document %s returns %v from '%s' but %v from '%s'", sample.Refs[i].ID, oldHits, oldIndex, newHits, newIndex)
+		}
+	}
+
+	m.log.Printf("%d searches return the same top hits from '%s' and '%s'", searched, oldIndex, newIndex)
+	return nil
+}
+
+func (m *Migrator) topHits(ctx context.Context, name string, body map[string]interface{}) ([]string, error) {
+	result, err := m.client.Search(ctx, []string{name}, body)
+	if err != nil {
+		return nil, err
+	}
+
+	ids := make([]string, len(result.Refs))
+	for i, ref := range result.Refs {
+		ids[i] = ref.ID
+	}
+
+	return ids, nil
+}
+
+func sameIDs(a, b []string) bool {
+	if len(a) != len(b) {
+		return false
+	}
+
+	seen := make(map[string]int, len(a))
+	for _, id := range a {
+		seen[id]++
+	}
+	for _, id := range b {
+		if seen[id] == 0 {
+			return false
+		}
+		seen[id]--
+	}
+
+	return true
+}
+
+// rollback undoes the migrations in reverse, it carries on past a failure so
+// that as much as possible is restored
+func (m *Migrator) rollback(ctx context.Context, migrations []*migration) error {
+	var errs []error
+
+	for i := len(migrations) - 1; i >= 0; i-- {
+		mig := migrations[i]
+
+		if mig.swapped && mig.oldIndex != "" {
+			if err := m.client.UpdateAlias(ctx, mig.alias, mig.newIndex, mig.oldIndex); err != nil {
+				errs = append(errs, fmt.Errorf("moving %s back to %s: %w", mig.alias, mig.oldIndex, err))
+				continue
+			}
+			m.log.Printf("alias '%s' moved back to '%s'", mig.alias, mig.oldIndex)
+		}
+
+		if mig.created {
+			if err := m.client.DeleteIndex(ctx, mig.newIndex); err != nil {
+				errs = append(errs, fmt.Errorf("deleting %s: %w", mig.newIndex, err))
+			}
+		}
+	}
+
+	return errors.Join(errs...)
+}
+func (m *Migrator) indexer(idx Index) *index.Indexer {
+	indexer := index.New(m.client, m.log, idx.DB, idx.Name)
+	indexer.SetWorkers(m.options.Workers)
+	return indexer
+}
+
+// checkResult fails when any bulk request failed or any document was
+// rejected, as the new index would not match. Conflicts are only logged, as
+// they are documents already written with a newer version
+func (m *Migrator) checkResult(result *index.Result, err error) error {
+	if err != nil {
+		return err
+	}
+
+	if len(result.Errors) > 0 {
+		return errors.New(result.Errors[0])
+	}
+
+	if result.Failed > 0 {
+		return fmt.Errorf("%d documents failed to index", result.Failed)
+	}
+
+	if result.Conflicts > 0 {
+		m.log.Printf("%d documents were already indexed with a newer version", result.Conflicts)
+	}
+
+	return nil
+}
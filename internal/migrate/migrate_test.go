@@ -0,0 +1,417 @@
+package migrate
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/ministryofjustice/opg-search-service/internal/elasticsearch"
+	"github.com/ministryofjustice/opg-search-service/internal/index"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+)
+
+type mockLogger struct{}
+
+func (*mockLogger) Printf(s string, args ...interface{}) {}
+
+type mockIndexable struct {
+	id string
+}
+
+func (m mockIndexable) Id() string {
+	return m.id
+}
+
+type mockDB struct {
+	mock.Mock
+}
+
+func (m *mockDB) QueryIDRange(ctx context.Context) (min, max int, err error) {
+	args := m.Called(ctx)
+	return args.Int(0), args.Int(1), args.Error(2)
+}
+
+func (m *mockDB) QueryByID(ctx context.Context, results chan<- index.Indexable, from, to int) error {
+	args := m.Called(ctx, results, from, to)
+	return args.Error(0)
+}
+
+func (m *mockDB) QueryFromDate(ctx context.Context, results chan<- index.Indexable, from time.Time) error {
+	args := m.Called(ctx, results, from)
+	return args.Error(0)
+}
+
+type mockClient struct {
+	mock.Mock
+}
+
+func (m *mockClient) DoBulk(ctx context.Context, op *elasticsearch.BulkOp) (elasticsearch.BulkResult, error) {
+	args := m.Called(ctx, op)
+	return args.Get(0).(elasticsearch.BulkResult), args.Error(1)
+}
+
+func (m *mockClient) Search(ctx context.Context, indices []string, requestBody map[string]interface{}) (*elasticsearch.SearchResult, error) {
+	args := m.Called(ctx, indices, requestBody)
+	result, _ := args.Get(0).(*elasticsearch.SearchResult)
+	return result, args.Error(1)
+}
+
+func (m *mockClient) IndexExists(ctx context.Context, name string) (bool, error) {
+	args := m.Called(ctx, name)
+	return args.Bool(0), args.Error(1)
+}
+
+func (m *mockClient) CreateIndex(ctx context.Context, name string, config []byte, force bool) error {
+	return m.Called(ctx, name, config, force).Error(0)
+}
+
+func (m *mockClient) DeleteIndex(ctx context.Context, name string) error {
+	return m.Called(ctx, name).Error(0)
+}
+
+func (m *mockClient) ResolveAlias(ctx context.Context, alias string) (string, error) {
+	args := m.Called(ctx, alias)
+	return args.String(0), args.Error(1)
+}
+
+func (m *mockClient) CreateAlias(ctx context.Context, alias, index string) error {
+	return m.Called(ctx, alias, index).Error(0)
+}
+
+func (m *mockClient) UpdateAlias(ctx context.Context, alias, oldIndex, newIndex string) error {
+	return m.Called(ctx, alias, oldIndex, newIndex).Error(0)
+}
+
+func (m *mockClient) Reindex(ctx context.Context, source, dest string) error {
+	return m.Called(ctx, source, dest).Error(0)
+}
+
+func (m *mockClient) Refresh(ctx context.Context, name string) error {
+	return m.Called(ctx, name).Error(0)
+}
+
+func (m *mockClient) Count(ctx context.Context, name string) (int, error) {
+	args := m.Called(ctx, name)
+	return args.Int(0), args.Error(1)
+}
+
+var (
+	ctx       = context.Background()
+	startedAt = time.Date(2026, 1, 2, 3, 4, 5, 0, time.UTC)
+	options   = Options{Source: SourceDatabase, BatchSize: 10, Workers: 1, Samples: 2, CountTolerance: 0.01}
+)
+
+func newTestMigrator(client Client, options Options) *Migrator {
+	m := New(client, &mockLogger{}, options)
+	m.now = func() time.Time { return startedAt }
+	return m
+}
+
+func refs(ids ...string) *elasticsearch.SearchResult {
+	result := &elasticsearch.SearchResult{}
+	for _, id := range ids {
+		result.Refs = append(result.Refs, elasticsearch.DocumentRef{ID: id})
+	}
+	return result
+}
+
+// expectBuild sets up a database with one record that is indexed by the
+// build, and one record that is changed while the build runs
+func expectBuild(db *mockDB, client *mockClient) {
+	db.
+		On("QueryIDRange", ctx).
+		Return(1, 1, nil)
+	db.
+		On("QueryByID", ctx, mock.Anything, 1, 1).
+		Run(func(args mock.Arguments) {
+			args.Get(1).(chan<- index.Indexable) <- mockIndexable{id: "1"}
+		}).
+		Return(nil)
+	db.
+		On("QueryFromDate", ctx, mock.Anything, startedAt.Add(-catchUpMargin)).
+		Run(func(args mock.Arguments) {
+			args.Get(1).(chan<- index.Indexable) <- mockIndexable{id: "2"}
+		}).
+		Return(nil)
+
+	client.
+		On("DoBulk", ctx, mock.MatchedBy(func(op *elasticsearch.BulkOp) bool { return op.Len() == 1 })).
+		Return(elasticsearch.BulkResult{Successful: 1}, nil)
+}
+
+func expectVerify(client *mockClient, oldIndex, newIndex string, oldCount, newCount int) {
+	client.On("Refresh", ctx, oldIndex).Return(nil)
+	client.On("Refresh", ctx, newIndex).Return(nil)
+	client.On("Count", ctx, oldIndex).Return(oldCount, nil)
+	client.On("Count", ctx, newIndex).Return(newCount, nil)
+}
+
+func TestRun(t *testing.T) {
+	db := &mockDB{}
+	client := &mockClient{}
+
+	client.On("ResolveAlias", ctx, "person").Return("person_old", nil)
+	client.On("IndexExists", ctx, "person_new").Return(false, nil)
+	client.On("CreateIndex", ctx, "person_new", []byte("{}"), false).Return(nil)
+	expectBuild(db, client)
+	expectVerify(client, "person_old", "person_new", 100, 101)
+	client.
+		On("Search", ctx, []string{"person_old"}, mock.Anything).
+		Return(refs("1", "2"), nil)
+	client.
+		On("Search", ctx, []string{"person_new"}, mock.MatchedBy(func(body map[string]interface{}) bool {
+			return assert.ObjectsAreEqual(map[string]interface{}{"ids": map[string]interface{}{"values": []string{"1", "2"}}}, body["query"])
+		})).
+		Return(refs("1", "2"), nil)
+	client.On("UpdateAlias", ctx, "person", "person_old", "person_new").Return(nil)
+	client.On("DeleteIndex", ctx, "person_old").Return(nil)
+
+	opts := options
+	opts.Cleanup = true
+
+	err := newTestMigrator(client, opts).Run(ctx, []Index{{Alias: "person", Name: "person_new", Config: []byte("{}"), DB: db}})
+	assert.Nil(t, err)
+
+	mock.AssertExpectationsForObjects(t, db, client)
+}
+
+func TestRunReindex(t *testing.T) {
+	db := &mockDB{}
+	client := &mockClient{}
+
+	client.On("ResolveAlias", ctx, "firm").Return("firm_old", nil)
+	client.On("IndexExists", ctx, "firm_new").Return(true, nil)
+	client.On("Reindex", ctx, "firm_old", "firm_new").Return(nil)
+	db.
+		On("QueryFromDate", ctx, mock.Anything, startedAt.Add(-catchUpMargin)).
+		Return(nil)
+	expectVerify(client, "firm_old", "firm_new", 5, 5)
+	client.On("UpdateAlias", ctx, "firm", "firm_old", "firm_new").Return(nil)
+
+	opts := options
+	opts.Source = SourceReindex
+	opts.Samples = 0
+
+	err := newTestMigrator(client, opts).Run(ctx, []Index{{Alias: "firm", Name: "firm_new", DB: db}})
+	assert.Nil(t, err)
+
+	mock.AssertExpectationsForObjects(t, db, client)
+	client.AssertNotCalled(t, "CreateIndex", mock.Anything, mock.Anything, mock.Anything, mock.Anything)
+}
+
+func TestRunWithoutDatabaseReindexes(t *testing.T) {
+	client := &mockClient{}
+
+	client.On("ResolveAlias", ctx, "digital_lpa").Return("digital_lpa_old", nil)
+	client.On("IndexExists", ctx, "digital_lpa_new").Return(true, nil)
+	client.On("Reindex", ctx, "digital_lpa_old", "digital_lpa_new").Return(nil)
+	expectVerify(client, "digital_lpa_old", "digital_lpa_new", 5, 5)
+	client.On("UpdateAlias", ctx, "digital_lpa", "digital_lpa_old", "digital_lpa_new").Return(nil)
+
+	opts := options
+	opts.Samples = 0
+
+	err := newTestMigrator(client, opts).Run(ctx, []Index{{Alias: "digital_lpa", Name: "digital_lpa_new"}})
+	assert.Nil(t, err)
+
+	mock.AssertExpectationsForObjects(t, client)
+}
+
+func TestRunWithoutDatabaseOrOldIndex(t *testing.T) {
+	client := &mockClient{}
+	client.On("ResolveAlias", ctx, "digital_lpa").Return("", elasticsearch.ErrAliasMissing)
+
+	err := newTestMigrator(client, options).Run(ctx, []Index{{Alias: "digital_lpa", Name: "digital_lpa_new"}})
+	assert.EqualError(t, err, "migrating digital_lpa to digital_lpa_new: no database to read digital_lpa records from")
+
+	client.AssertNotCalled(t, "CreateIndex", mock.Anything, mock.Anything, mock.Anything, mock.Anything)
+}
+
+// searchFor is a sample query that searches for a document by its name
+func searchFor(doc json.RawMessage) (map[string]interface{}, bool) {
+	var v struct {
+		Name string `json:"name"`
+	}
+	_ = json.Unmarshal(doc, &v)
+	if v.Name == "" {
+		return nil, false
+	}
+
+	return map[string]interface{}{"query": map[string]interface{}{"match": map[string]interface{}{"name": v.Name}}}, true
+}
+
+func isSearchFor(name string) interface{} {
+	return mock.MatchedBy(func(body map[string]interface{}) bool {
+		return assert.ObjectsAreEqual(map[string]interface{}{"match": map[string]interface{}{"name": name}}, body["query"]) &&
+			body["size"] == 3 && body["from"] == 0
+	})
+}
+
+func expectSampleSearch(client *mockClient) {
+	sample := refs("1", "2", "3")
+	sample.Hits = []json.RawMessage{[]byte(`{"name":"a"}`), []byte(`{}`), []byte(`{"name":"c"}`)}
+
+	client.
+		On("Search", ctx, []string{"person_old"}, mock.MatchedBy(func(body map[string]interface{}) bool { return body["size"] == 3 && body["from"] == nil })).
+		Return(sample, nil)
+	client.
+		On("Search", ctx, []string{"person_new"}, mock.MatchedBy(func(body map[string]interface{}) bool { return body["from"] == nil })).
+		Return(refs("1", "2", "3"), nil)
+}
+
+func TestVerifyComparesTopHits(t *testing.T) {
+	client := &mockClient{}
+	expectVerify(client, "person_old", "person_new", 3, 3)
+	expectSampleSearch(client)
+	client.On("Search", ctx, []string{"person_old"}, isSearchFor("a")).Return(refs("1", "4", "5"), nil).Once()
+	client.On("Search", ctx, []string{"person_new"}, isSearchFor("a")).Return(refs("4", "1", "5"), nil).Once()
+	client.On("Search", ctx, []string{"person_old"}, isSearchFor("c")).Return(refs("3"), nil).Once()
+	client.On("Search", ctx, []string{"person_new"}, isSearchFor("c")).Return(refs("3"), nil).Once()
+
+	opts := options
+	opts.Samples = 3
+	opts.Queries = 2
+	opts.TopHits = 3
+
+	err := newTestMigrator(client, opts).verify(ctx, "person_old", "person_new", searchFor)
+	assert.Nil(t, err)
+
+	mock.AssertExpectationsForObjects(t, client)
+}
+
+func TestVerifyFailsWhenTopHitsDiffer(t *testing.T) {
+	client := &mockClient{}
+	expectVerify(client, "person_old", "person_new", 3, 3)
+	expectSampleSearch(client)
+	client.On("Search", ctx, []string{"person_old"}, isSearchFor("a")).Return(refs("1", "4", "5"), nil).Once()
+	client.On("Search", ctx, []string{"person_new"}, isSearchFor("a")).Return(refs("1", "4", "6"), nil).Once()
+
+	opts := options
+	opts.Samples = 3
+	opts.Queries = 2
+	opts.TopHits = 3
+
+	err := newTestMigrator(client, opts).verify(ctx, "person_old", "person_new", searchFor)
+	assert.EqualError(t, err, "searching for document 1 returns [1 4 5] from 'person_old' but [1 4 6] from 'person_new'")
+}
+
+func TestRunSkipsCurrentAlias(t *testing.T) {
+	client := &mockClient{}
+	client.On("ResolveAlias", ctx, "person").Return("person_new", nil)
+
+	err := newTestMigrator(client, options).Run(ctx, []Index{{Alias: "person", Name: "person_new", DB: &mockDB{}}})
+	assert.Nil(t, err)
+
+	mock.AssertExpectationsForObjects(t, client)
+}
+
+func TestRunCreatesMissingAlias(t *testing.T) {
+	db := &mockDB{}
+	client := &mockClient{}
+
+	client.On("ResolveAlias", ctx, "person").Return("", elasticsearch.ErrAliasMissing)
+	client.On("IndexExists", ctx, "person_new").Return(false, nil)
+	client.On("CreateIndex", ctx, "person_new", []byte(nil), false).Return(nil)
+	expectBuild(db, client)
+	client.On("CreateAlias", ctx, "person", "person_new").Return(nil)
+
+	opts := options
+	opts.Source = SourceReindex
+
+	err := newTestMigrator(client, opts).Run(ctx, []Index{{Alias: "person", Name: "person_new", DB: db}})
+	assert.Nil(t, err)
+
+	mock.AssertExpectationsForObjects(t, db, client)
+}
+
+func TestRunRollsBackWhenVerifyFails(t *testing.T) {
+	personDB := &mockDB{}
+	firmDB := &mockDB{}
+	client := &mockClient{}
+
+	// the first alias is moved
+	client.On("ResolveAlias", ctx, "person").Return("person_old", nil)
+	client.On("IndexExists", ctx, "person_new").Return(false, nil)
+	client.On("CreateIndex", ctx, "person_new", []byte(nil), false).Return(nil)
+	expectBuild(personDB, client)
+	expectVerify(client, "person_old", "person_new", 1, 1)
+	client.On("UpdateAlias", ctx, "person", "person_old", "person_new").Return(nil)
+
+	// the second has too few documents
+	client.On("ResolveAlias", ctx, "firm").Return("firm_old", nil)
+	client.On("IndexExists", ctx, "firm_new").Return(true, nil)
+	expectBuild(firmDB, client)
+	expectVerify(client, "firm_old", "firm_new", 100, 50)
+
+	client.On("UpdateAlias", ctx, "person", "person_new", "person_old").Return(nil)
+	client.On("DeleteIndex", ctx, "person_new").Return(nil)
+
+	opts := options
+	opts.Samples = 0
+
+	err := newTestMigrator(client, opts).Run(ctx, []Index{
+		{Alias: "person", Name: "person_new", DB: personDB},
+		{Alias: "firm", Name: "firm_new", DB: firmDB},
+	})
+	assert.Equal(t, "migrating firm to firm_new: verifying: 'firm_new' has 50 documents but 'firm_old' has 100", err.Error())
+
+	mock.AssertExpectationsForObjects(t, personDB, firmDB, client)
+	// firm_new existed before the migration so is kept
+	client.AssertNotCalled(t, "DeleteIndex", ctx, "firm_new")
+}
+
+func TestRunRollsBackWhenSampleMissing(t *testing.T) {
+	db := &mockDB{}
+	client := &mockClient{}
+
+	client.On("ResolveAlias", ctx, "person").Return("person_old", nil)
+	client.On("IndexExists", ctx, "person_new").Return(false, nil)
+	client.On("CreateIndex", ctx, "person_new", []byte(nil), false).Return(nil)
+	expectBuild(db, client)
+	expectVerify(client, "person_old", "person_new", 2, 2)
+	client.On("Search", ctx, []string{"person_old"}, mock.Anything).Return(refs("1", "3"), nil)
+	client.On("Search", ctx, []string{"person_new"}, mock.Anything).Return(refs("1"), nil)
+	client.On("DeleteIndex", ctx, "person_new").Return(nil)
+
+	err := newTestMigrator(client, options).Run(ctx, []Index{{Alias: "person", Name: "person_new", DB: db}})
+	assert.Equal(t, "migrating person to person_new: verifying: 1 of 2 documents sampled from 'person_old' are missing from 'person_new'", err.Error())
+
+	mock.AssertExpectationsForObjects(t, db, client)
+	client.AssertNotCalled(t, "UpdateAlias", mock.Anything, mock.Anything, mock.Anything, mock.Anything)
+}
+
+func TestRunRollsBackWhenBuildFails(t *testing.T) {
+	db := &mockDB{}
+	client := &mockClient{}
+
+	client.On("ResolveAlias", ctx, "person").Return("person_old", nil)
+	client.On("IndexExists", ctx, "person_new").Return(false, nil)
+	client.On("CreateIndex", ctx, "person_new", []byte(nil), false).Return(nil)
+	client.On("Reindex", ctx, "person_old", "person_new").Return(errors.New("hmm"))
+	client.On("DeleteIndex", ctx, "person_new").Return(errors.New("oops"))
+
+	opts := options
+	opts.Source = SourceReindex
+
+	err := newTestMigrator(client, opts).Run(ctx, []Index{{Alias: "person", Name: "person_new", DB: db}})
+	assert.Equal(t, "migrating person to person_new: building: hmm\ndeleting person_new: oops", err.Error())
+
+	mock.AssertExpectationsForObjects(t, client)
+}
+
+func TestRunInvalidSource(t *testing.T) {
+	err := newTestMigrator(&mockClient{}, Options{Source: "somewhere"}).Run(ctx, nil)
+	assert.Equal(t, "source must be database or reindex", err.Error())
+}
+
+func TestCheckResult(t *testing.T) {
+	m := newTestMigrator(&mockClient{}, Options{})
+
+	assert.Nil(t, m.checkResult(&index.Result{Successful: 1, Conflicts: 1}, nil))
+	assert.Equal(t, errors.New("hmm"), m.checkResult(nil, errors.New("hmm")))
+	assert.Equal(t, "hmm", m.checkResult(&index.Result{Errors: []string{"hmm"}}, nil).Error())
+	assert.Equal(t, "2 documents failed to index", m.checkResult(&index.Result{Failed: 2}, nil).Error())
+}
@@ -11,12 +11,12 @@ import (
 	"github.com/ministryofjustice/opg-search-service/internal/index"
 )
 
-func NewDB(conn *pgx.Conn) *DB {
+func NewDB(conn index.Conn) *DB {
 	return &DB{conn: conn}
 }
 
 type DB struct {
-	conn *pgx.Conn
+	conn index.Conn
 }
 
 func (db *DB) QueryIDRange(ctx context.Context) (min int, max int, err error) {
@@ -44,7 +44,7 @@ func (db *DB) QueryFromDate(ctx context.Context, results chan<- index.Indexable,
 }
 
 func makeQueryPerson(whereClause string) string {
-	return `SELECT p.id, p.uid, coalesce(p.caseRecNumber, ''), p.deputynumber, coalesce(p.email, ''), coalesce(to_char(p.dob, 'DD/MM/YYYY'), ''),
+	return `SELECT p.id, (SELECT nextval('public.search_version')), p.uid, coalesce(p.caseRecNumber, ''), p.deputynumber, coalesce(p.email, ''), coalesce(to_char(p.dob, 'DD/MM/YYYY'), ''),
 		coalesce(p.firstname, ''), coalesce(p.middlenames, ''), coalesce(p.surname, ''), coalesce(p.previousnames, ''), coalesce(p.othernames, ''), coalesce(p.companyname, ''), p.type, coalesce(p.organisationname, ''),
 		phonenumbers.id, coalesce(phonenumbers.phone_number, ''),
 		addresses.id, addresses.address_lines, coalesce(addresses.postcode, ''),
@@ -60,6 +60,7 @@ ORDER BY p.id`
 
 type rowResult struct {
 	ID                 int
+	Version            *int64
 	UID                string
 	CaseRecNumber      string
 	DeputyNumber       *int
@@ -96,7 +97,7 @@ func scan(ctx context.Context, rows pgx.Rows, results chan<- index.Indexable) er
 
 	for rows.Next() {
 		var v rowResult
-		err = rows.Scan(&v.ID, &v.UID, &v.CaseRecNumber, &v.DeputyNumber, &v.Email, &v.Dob,
+		err = rows.Scan(&v.ID, &v.Version, &v.UID, &v.CaseRecNumber, &v.DeputyNumber, &v.Email, &v.Dob,
 			&v.Firstname, &v.Middlenames, &v.Surname, &v.Previousnames, &v.Othernames, &v.CompanyName, &v.Type, &v.OrganisationName,
 			&v.PhoneNumberID, &v.PhoneNumber,
 			&v.AddressID, &v.AddressLines, &v.Postcode,
@@ -167,6 +168,7 @@ func (a *personAdded) clear() {
 func addResultToPerson(a *personAdded, p *Person, s rowResult) {
 	if p.ID == nil {
 		p.ID = i64(s.ID)
+		p.Version = s.Version
 		p.UID = formatUID(s.UID)
 		p.Normalizeduid = normaliseUid(s.UID)
 		p.CaseRecNumber = s.CaseRecNumber
@@ -43,3 +43,15 @@ func ParseIndexRequest(body []byte) (index.Validatable, error) {
 
 	return &req, err
 }
+
+// ParsePartialIndexRequest reads a request to update only some fields of each
+// item, the items must still have an id. Cases are added to the person's cases
+// or replace the case with the same uId, and addresses and phone numbers are
+// added unless the person already has them.
+func ParsePartialIndexRequest(body []byte) (index.Validatable, error) {
+	return index.ParsePartial(body, "persons", "id", Person{}, map[string]string{
+		"cases":        "uId",
+		"addresses":    "",
+		"phoneNumbers": "",
+	})
+}
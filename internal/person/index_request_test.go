@@ -67,3 +67,24 @@ func TestIndexRequest_Validate(t *testing.T) {
 		assert.Equal(t, errs, test.expectErrs, test.scenario)
 	}
 }
+
+func TestParseIndexRequestVersions(t *testing.T) {
+	req, err := ParseIndexRequest([]byte(`{"persons":[{"id":1,"version":5},{"id":2}]}`))
+	assert.Nil(t, err)
+
+	persons := req.(*IndexRequest).Persons
+	assert.Equal(t, int64(5), *persons[0].Version)
+	assert.Nil(t, persons[1].Version)
+}
+
+func TestParsePartialIndexRequest(t *testing.T) {
+	req, err := ParsePartialIndexRequest([]byte(`{"persons":[{"id":1,"cases":[{"uId":"7000-0000-0001"}]},{"id":2,"surname":5}]}`))
+	assert.Nil(t, err)
+
+	assert.Equal(t, []response.Error{{
+		Name:        "persons[1]",
+		Description: "cannot unmarshal number into Go struct field Person.surname of type string",
+	}}, req.Validate())
+	assert.Len(t, req.Items(), 1)
+	assert.Equal(t, "1", req.Items()[0].Id())
+}
@@ -4,7 +4,9 @@ import (
 	"encoding/json"
 	"strconv"
 
+	"github.com/ministryofjustice/opg-search-service/internal/autocomplete"
 	"github.com/ministryofjustice/opg-search-service/internal/response"
+	"github.com/ministryofjustice/opg-search-service/internal/synonyms"
 )
 
 const AliasName = "person"
@@ -29,6 +31,11 @@ type Person struct {
 	Addresses        []PersonAddress     `json:"addresses"`
 	Phonenumbers     []PersonPhonenumber `json:"phoneNumbers"`
 	Cases            []PersonCase        `json:"cases"`
+	// Version is used to reject writes older than the indexed document, it is
+	// taken from the public.search_version sequence when the person is read so
+	// that it changes with any change to the person, their addresses or their
+	// cases. Persons without a version replace the indexed document.
+	Version *int64 `json:"version,omitempty"`
 }
 
 type PersonCase struct {
@@ -58,6 +65,14 @@ func (p Person) Id() string {
 	return strconv.Itoa(val)
 }
 
+func (p Person) ExternalVersion() (int64, bool) {
+	if p.Version == nil {
+		return 0, false
+	}
+
+	return *p.Version, true
+}
+
 func (p Person) Validate() []response.Error {
 	var errs []response.Error
 
@@ -76,6 +91,21 @@ func IndexConfig() (config []byte, err error) {
 	searchableTextField := map[string]interface{}{"type": "text", "copy_to": "searchable"}
 	keywordField := map[string]interface{}{"type": "keyword"}
 	searchableKeywordField := map[string]interface{}{"type": "keyword", "copy_to": "searchable"}
+	searchableNameField := map[string]interface{}{"type": "text", "copy_to": []string{"searchable", "names"}}
+	searchableDateField := map[string]interface{}{
+		"type":    "text",
+		"copy_to": "searchable",
+		"fields": map[string]interface{}{
+			"date": map[string]interface{}{"type": "date", "format": "dd/MM/yyyy", "ignore_malformed": true},
+		},
+	}
+	searchableFirstNameField := map[string]interface{}{
+		"type":    "text",
+		"copy_to": []string{"searchable", "names"},
+		"fields": map[string]interface{}{
+			"synonym": map[string]interface{}{"type": "text", "analyzer": synonyms.NameAnalyzer},
+		},
+	}
 
 	personConfig := map[string]interface{}{
 		"settings": map[string]interface{}{
@@ -89,6 +119,13 @@ func IndexConfig() (config []byte, err error) {
 						"pattern":     " ",
 						"replacement": "",
 					},
+					"name_trigram": map[string]interface{}{
+						"type":     "ngram",
+						"min_gram": 3,
+						"max_gram": 3,
+					},
+					synonyms.NameFilter: synonyms.NameFilterConfig(),
+					autocomplete.Filter: autocomplete.FilterConfig(),
 				},
 				"analyzer": map[string]interface{}{
 					"default": map[string]interface{}{
@@ -99,26 +136,39 @@ func IndexConfig() (config []byte, err error) {
 						"tokenizer": "keyword",
 						"filter":    []string{"whitespace_remove", "lowercase"},
 					},
+					"name_ngram_analyzer": map[string]interface{}{
+						"tokenizer": "whitespace",
+						"filter":    []string{"asciifolding", "lowercase", "name_trigram"},
+					},
+					synonyms.NameAnalyzer:       synonyms.NameAnalyzerConfig(),
+					autocomplete.Analyzer:       autocomplete.AnalyzerConfig(),
+					autocomplete.SearchAnalyzer: autocomplete.SearchAnalyzerConfig(),
 				},
 			},
 		},
 		"mappings": map[string]interface{}{
 			"properties": map[string]interface{}{
 				"searchable":    textField,
-				"uId":           searchableKeywordField,
+				"uId":           autocomplete.CopyTo(searchableKeywordField),
 				"normalizedUid": searchableKeywordField,
-				"caseRecNumber": searchableKeywordField,
+				"caseRecNumber": autocomplete.CopyTo(searchableKeywordField),
 				"deputyNumber":  searchableKeywordField,
 				"personType":    keywordField,
-				"dob":           searchableTextField,
+				"dob":           searchableDateField,
 				"email":         textField,
-				"firstname":     searchableTextField,
-				"middlenames":   searchableTextField,
-				"surname":       searchableTextField,
-				"previousnames": searchableTextField,
-				"othernames":    searchableTextField,
+				"firstname":     autocomplete.CopyTo(searchableFirstNameField),
+				"middlenames":   searchableFirstNameField,
+				"surname":       autocomplete.CopyTo(searchableNameField),
+				"previousnames": searchableNameField,
+				"othernames":    searchableFirstNameField,
 				"companyName":   searchableTextField,
-				"className":     searchableTextField,
+				"className": map[string]interface{}{
+					"type":    "text",
+					"copy_to": "searchable",
+					"fields": map[string]interface{}{
+						"keyword": keywordField,
+					},
+				},
 				"clientSource": map[string]interface{}{
 					"type":  "text",
 					"index": false,
@@ -152,7 +202,17 @@ func IndexConfig() (config []byte, err error) {
 						"caseSubtype":   searchableKeywordField,
 					},
 				},
-				"organisationName": searchableTextField,
+				"organisationName": autocomplete.CopyTo(searchableTextField),
+				autocomplete.Field: autocomplete.FieldConfig(),
+				"names": map[string]interface{}{
+					"type": "text",
+					"fields": map[string]interface{}{
+						"ngram": map[string]interface{}{
+							"type":     "text",
+							"analyzer": "name_ngram_analyzer",
+						},
+					},
+				},
 			},
 		},
 	}
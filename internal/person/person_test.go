@@ -33,6 +33,16 @@ func TestPerson_Id(t *testing.T) {
 	}
 }
 
+func TestPerson_ExternalVersion(t *testing.T) {
+	_, ok := Person{}.ExternalVersion()
+	assert.False(t, ok)
+
+	version := int64(1609675200000)
+	v, ok := Person{Version: &version}.ExternalVersion()
+	assert.True(t, ok)
+	assert.Equal(t, version, v)
+}
+
 func TestPerson_Validate(t *testing.T) {
 	testId := int64(1)
 	var noErrs []response.Error
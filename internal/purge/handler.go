@@ -0,0 +1,75 @@
+package purge
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"net/http"
+	"regexp"
+
+	"github.com/ministryofjustice/opg-search-service/internal/response"
+	"github.com/sirupsen/logrus"
+)
+
+var uidPattern = regexp.MustCompile(`^\d{4}-\d{4}-\d{4}$`)
+
+type Request struct {
+	UID     string `json:"uId"`
+	Explain bool   `json:"explain"`
+}
+
+type PurgeService interface {
+	Purge(ctx context.Context, uid string, explain bool) (*Report, error)
+}
+
+type Handler struct {
+	logger *logrus.Logger
+	purger PurgeService
+}
+
+func NewHandler(logger *logrus.Logger, purger PurgeService) *Handler {
+	return &Handler{
+		logger: logger,
+		purger: purger,
+	}
+}
+
+func (h *Handler) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	var req Request
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		h.logger.Println(err.Error())
+		response.WriteJSONError(w, "request", "Unable to unmarshal JSON request", http.StatusBadRequest)
+		return
+	}
+
+	if !uidPattern.MatchString(req.UID) {
+		response.WriteJSONErrors(w, "Some fields have failed validation", []response.Error{{
+			Name:        "uId",
+			Description: "must be a UID in the format 0000-0000-0000",
+		}}, http.StatusBadRequest)
+		return
+	}
+
+	report, err := h.purger.Purge(r.Context(), req.UID, req.Explain)
+	if errors.Is(err, ErrNotFound) {
+		response.WriteJSONErrors(w, err.Error(), []response.Error{}, http.StatusNotFound)
+		return
+	}
+	if err != nil {
+		h.logger.Println(err.Error())
+		response.WriteJSONErrors(w, "unexpected error from elasticsearch", []response.Error{}, http.StatusInternalServerError)
+		return
+	}
+
+	h.logger.Printf("purged %s: deleted %d, scrubbed %d, failed %d", report.UID, len(report.Deleted), len(report.Scrubbed), report.Failed)
+
+	// the report is returned on failure too, so that what was purged is known
+	status := http.StatusOK
+	if report.Failed > 0 {
+		status = http.StatusInternalServerError
+	}
+
+	jsonResp, _ := json.Marshal(report)
+	w.WriteHeader(status)
+	_, _ = w.Write(jsonResp)
+}
@@ -0,0 +1,92 @@
+package purge
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/sirupsen/logrus/hooks/test"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+)
+
+type mockPurgeService struct {
+	mock.Mock
+}
+
+func (m *mockPurgeService) Purge(ctx context.Context, uid string, explain bool) (*Report, error) {
+	args := m.Called(ctx, uid, explain)
+	report, _ := args.Get(0).(*Report)
+	return report, args.Error(1)
+}
+
+func TestHandler(t *testing.T) {
+	tests := []struct {
+		scenario     string
+		body         string
+		report       *Report
+		err          error
+		expectedCode int
+		expectedBody string
+	}{
+		{
+			scenario:     "purged",
+			body:         `{"uId":"7000-0000-0001"}`,
+			report:       &Report{UID: "7000-0000-0001", Deleted: []*Document{{Index: "person_a", ID: "1", StatusCode: 200}}, Scrubbed: []*Document{}},
+			expectedCode: http.StatusOK,
+			expectedBody: `{"uId":"7000-0000-0001","deleted":[{"index":"person_a","id":"1","statusCode":200}],"scrubbed":[],"failed":0}`,
+		},
+		{
+			scenario:     "partly failed",
+			body:         `{"uId":"7000-0000-0001"}`,
+			report:       &Report{UID: "7000-0000-0001", Deleted: []*Document{{Index: "person_a", ID: "1", StatusCode: 500, Message: "oops"}}, Scrubbed: []*Document{}, Failed: 1},
+			expectedCode: http.StatusInternalServerError,
+			expectedBody: `{"uId":"7000-0000-0001","deleted":[{"index":"person_a","id":"1","statusCode":500,"message":"oops"}],"scrubbed":[],"failed":1}`,
+		},
+		{
+			scenario:     "not found",
+			body:         `{"uId":"7000-0000-0001"}`,
+			err:          ErrNotFound,
+			expectedCode: http.StatusNotFound,
+			expectedBody: `{"message":"could not find person to purge","errors":[]}` + "\n",
+		},
+		{
+			scenario:     "search error",
+			body:         `{"uId":"7000-0000-0001"}`,
+			err:          errors.New("oops"),
+			expectedCode: http.StatusInternalServerError,
+			expectedBody: `{"message":"unexpected error from elasticsearch","errors":[]}` + "\n",
+		},
+		{
+			scenario:     "invalid uid",
+			body:         `{"uId":"7000"}`,
+			expectedCode: http.StatusBadRequest,
+			expectedBody: `{"message":"Some fields have failed validation","errors":[{"name":"uId","description":"must be a UID in the format 0000-0000-0000"}]}` + "\n",
+		},
+		{
+			scenario:     "invalid body",
+			body:         `{`,
+			expectedCode: http.StatusBadRequest,
+			expectedBody: `{"message":"request","errors":[{"name":"request","description":"Unable to unmarshal JSON request"}]}` + "\n",
+		},
+	}
+
+	for _, tc := range tests {
+		t.Run(tc.scenario, func(t *testing.T) {
+			l, _ := test.NewNullLogger()
+			purger := &mockPurgeService{}
+			purger.On("Purge", mock.Anything, "7000-0000-0001", false).Return(tc.report, tc.err)
+
+			w := httptest.NewRecorder()
+			r := httptest.NewRequest(http.MethodPost, "/persons/purge", strings.NewReader(tc.body))
+
+			NewHandler(l, purger).ServeHTTP(w, r)
+
+			assert.Equal(t, tc.expectedCode, w.Code)
+			assert.Equal(t, tc.expectedBody, w.Body.String())
+		})
+	}
+}
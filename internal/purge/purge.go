@@ -0,0 +1,360 @@
+package purge
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/ministryofjustice/opg-search-service/internal/digitallpa"
+	"github.com/ministryofjustice/opg-search-service/internal/elasticsearch"
+	"github.com/ministryofjustice/opg-search-service/internal/person"
+)
+
+// maxMatches is the most documents a purge will search for in each entity, a
+// purge that matches more is refused rather than being done partially
+const maxMatches = 1000
+
+var ErrNotFound = errors.New("could not find person to purge")
+
+type Client interface {
+	Search(ctx context.Context, indices []string, requestBody map[string]interface{}) (*elasticsearch.SearchResult, error)
+	DoBulk(ctx context.Context, op *elasticsearch.BulkOp) (elasticsearch.BulkResult, error)
+}
+
+// Report lists every document that was deleted or scrubbed, by the concrete
+// index it was in, so that the purge can be checked against the cluster
+type Report struct {
+	UID      string      `json:"uId"`
+	Explain  bool        `json:"explain,omitempty"`
+	Deleted  []*Document `json:"deleted"`
+	Scrubbed []*Document `json:"scrubbed"`
+	Failed   int         `json:"failed"`
+}
+
+type Document struct {
+	Index string `json:"index"`
+	ID    string `json:"id"`
+	// Fields are the parts of a scrubbed document that were removed
+	Fields     []string `json:"fields,omitempty"`
+	StatusCode int      `json:"statusCode,omitempty"`
+	Message    string   `json:"message,omitempty"`
+}
+
+type Purger struct {
+	client Client
+}
+
+func New(client Client) *Purger {
+	return &Purger{client: client}
+}
+
+// Purge deletes the person with the uid from every person index, including
+// those no longer aliased, and removes them from any digital LPA where they
+// are the certificate provider or an attorney. When explain is set the
+// documents are found and reported but not changed.
+func (p *Purger) Purge(ctx context.Context, uid string, explain bool) (*Report, error) {
+	report := &Report{UID: uid, Explain: explain, Deleted: []*Document{}, Scrubbed: []*Document{}}
+	ops := map[string]*pendingOp{}
+
+	persons, err := p.find(ctx, person.AliasName, map[string]interface{}{
+		"term": map[string]interface{}{"uId": uid},
+	})
+	if err != nil {
+		return nil, err
+	}
+	if len(persons) == 0 {
+		return nil, ErrNotFound
+	}
+
+	var identities []identity
+	for _, hit := range persons {
+		var v identity
+		if err := json.Unmarshal(hit.source, &v); err != nil {
+			return nil, fmt.Errorf("reading person %s in %s: %w", hit.ref.ID, hit.ref.Index, err)
+		}
+		identities = append(identities, v)
+
+		doc := &Document{Index: hit.ref.Index, ID: hit.ref.ID}
+		report.Deleted = append(report.Deleted, doc)
+		if err := pending(ops, hit.ref.Index).delete(doc); err != nil {
+			return nil, err
+		}
+	}
+
+	if query := lpaQuery(identities); query != nil {
+		lpas, err := p.find(ctx, digitallpa.AliasName, query)
+		if err != nil {
+			return nil, err
+		}
+
+		for _, hit := range lpas {
+			lpa, fields, err := scrub(hit.source, identities)
+			if err != nil {
+				return nil, fmt.Errorf("reading digital LPA %s in %s: %w", hit.ref.ID, hit.ref.Index, err)
+			}
+			if len(fields) == 0 {
+				continue
+			}
+
+			doc := &Document{Index: hit.ref.Index, ID: hit.ref.ID, Fields: fields}
+			report.Scrubbed = append(report.Scrubbed, doc)
+			if err := pending(ops, hit.ref.Index).index(doc, lpa); err != nil {
+				return nil, err
+			}
+		}
+	}
+
+	if explain {
+		return report, nil
+	}
+
+	indices := make([]string, 0, len(ops))
+	for index := range ops {
+		indices = append(indices, index)
+	}
+	sort.Strings(indices)
+
+	for _, index := range indices {
+		failed, err := ops[index].do(ctx, p.client)
+		if err != nil {
+			return nil, err
+		}
+		report.Failed += failed
+	}
+
+	return report, nil
+}
+
+type hit struct {
+	ref    elasticsearch.DocumentRef
+	source json.RawMessage
+}
+
+// find searches every index for the alias, rather than the alias itself, so
+// that documents are also found in indices that have not been cleaned up
+func (p *Purger) find(ctx context.Context, alias string, query map[string]interface{}) ([]hit, error) {
+	result, err := p.client.Search(ctx, []string{alias + "_*"}, map[string]interface{}{
+		"query":            query,
+		"size":             maxMatches,
+		"track_total_hits": true,
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	if result.Total > len(result.Hits) {
+		return nil, fmt.Errorf("found %d documents in %s indices, which is more than can be purged at once", result.Total, alias)
+	}
+
+	hits := make([]hit, len(result.Hits))
+	for i := range result.Hits {
+		hits[i] = hit{ref: result.Refs[i], source: result.Hits[i]}
+	}
+
+	return hits, nil
+}
+
+// identity holds the fields of a person used to recognise them in a digital
+// LPA, which does not reference the person by uid
+type identity struct {
+	Firstname   string `json:"firstname"`
+	Middlenames string `json:"middlenames"`
+	Surname     string `json:"surname"`
+	Dob         string `json:"dob"`
+	Addresses   []struct {
+		Postcode string `json:"postcode"`
+	} `json:"addresses"`
+}
+
+func (id identity) matchesName(p digitallpa.Person) bool {
+	if id.Surname == "" || normalise(id.Surname) != normalise(p.Surname) {
+		return false
+	}
+
+	firstNames := normalise(p.Firstnames)
+	return firstNames == normalise(id.Firstname) ||
+		firstNames == normalise(id.Firstname+" "+id.Middlenames)
+}
+
+// matchesAttorney requires the date of birth to match, so that someone with
+// the same name is not removed
+func (id identity) matchesAttorney(a digitallpa.Attorney) bool {
+	dob := normaliseDate(id.Dob)
+	return id.matchesName(a.Person) && dob != "" && dob == normaliseDate(a.Dob)
+}
+
+// matchesCertificateProvider requires the postcode to match one of the
+// person's addresses, as a certificate provider does not have a date of birth
+func (id identity) matchesCertificateProvider(p digitallpa.Person) bool {
+	if !id.matchesName(p) {
+		return false
+	}
+
+	postcode := normalisePostcode(p.Address.Postcode)
+	for _, address := range id.Addresses {
+		if postcode != "" && postcode == normalisePostcode(address.Postcode) {
+			return true
+		}
+	}
+
+	return false
+}
+
+// lpaQuery finds digital LPAs with a certificate provider or attorney with the
+// same surname as the person, the full match is made by scrub
+func lpaQuery(identities []identity) map[string]interface{} {
+	should := []interface{}{}
+	seen := map[string]bool{}
+
+	for _, id := range identities {
+		surname := strings.TrimSpace(id.Surname)
+		if surname == "" || seen[normalise(surname)] {
+			continue
+		}
+		seen[normalise(surname)] = true
+
+		for _, field := range []string{"certificateProvider.surname", "attorneys.surname"} {
+			should = append(should, map[string]interface{}{
+				"match_phrase": map[string]interface{}{field: surname},
+			})
+		}
+	}
+
+	if len(should) == 0 {
+		return nil
+	}
+
+	return map[string]interface{}{
+		"bool": map[string]interface{}{
+			"should":               should,
+			"minimum_should_match": 1,
+		},
+	}
+}
+
+// scrub removes the certificate provider and any attorneys that match one of
+// the identities, returning the document to index and the fields removed
+func scrub(source json.RawMessage, identities []identity) (map[string]interface{}, []string, error) {
+	var lpa digitallpa.DigitalLpa
+	if err := json.Unmarshal(source, &lpa); err != nil {
+		return nil, nil, err
+	}
+
+	// the document is rewritten from its source, so that fields not known
+	// to DigitalLpa are kept
+	var doc map[string]interface{}
+	dec := json.NewDecoder(bytes.NewReader(source))
+	dec.UseNumber()
+	if err := dec.Decode(&doc); err != nil {
+		return nil, nil, err
+	}
+	delete(doc, "_index")
+	delete(doc, "_highlight")
+
+	var fields []string
+
+	for _, id := range identities {
+		if id.matchesCertificateProvider(lpa.CertificateProvider) {
+			doc["certificateProvider"] = map[string]interface{}{}
+			fields = append(fields, "certificateProvider")
+			break
+		}
+	}
+
+	attorneys, _ := doc["attorneys"].([]interface{})
+	kept := []interface{}{}
+	for i, attorney := range lpa.Attorneys {
+		matched := false
+		for _, id := range identities {
+			if id.matchesAttorney(attorney) {
+				matched = true
+				break
+			}
+		}
+
+		if matched {
+			fields = append(fields, fmt.Sprintf("attorneys[%d]", i))
+		} else if i < len(attorneys) {
+			kept = append(kept, attorneys[i])
+		}
+	}
+	if len(kept) != len(lpa.Attorneys) {
+		doc["attorneys"] = kept
+	}
+
+	return doc, fields, nil
+}
+
+// pendingOp collects the changes to a single index, so the results of the
+// bulk request can be matched back to the documents in the report
+type pendingOp struct {
+	op   *elasticsearch.BulkOp
+	docs []*Document
+}
+
+func pending(ops map[string]*pendingOp, index string) *pendingOp {
+	if _, ok := ops[index]; !ok {
+		ops[index] = &pendingOp{op: elasticsearch.NewBulkOp(index)}
+	}
+
+	return ops[index]
+}
+
+func (p *pendingOp) delete(doc *Document) error {
+	p.docs = append(p.docs, doc)
+	return p.op.Delete(doc.ID)
+}
+
+func (p *pendingOp) index(doc *Document, v interface{}) error {
+	p.docs = append(p.docs, doc)
+	return p.op.Index(doc.ID, v)
+}
+
+func (p *pendingOp) do(ctx context.Context, client Client) (int, error) {
+	result, err := client.DoBulk(ctx, p.op)
+	if err != nil {
+		return 0, err
+	}
+
+	failed := 0
+	for i, doc := range p.docs {
+		if i >= len(result.Results) {
+			doc.Message = "no result returned"
+			failed++
+			continue
+		}
+
+		doc.StatusCode = result.Results[i].StatusCode
+		doc.Message = result.Results[i].Message
+		if !result.Results[i].Successful() {
+			failed++
+		}
+	}
+
+	return failed, nil
+}
+
+func normalise(s string) string {
+	return strings.ToLower(strings.Join(strings.Fields(s), " "))
+}
+
+func normalisePostcode(s string) string {
+	return strings.ToUpper(strings.Join(strings.Fields(s), ""))
+}
+
+// normaliseDate reads the date formats used by the person and digital LPA
+// indices, so that dates of birth can be compared
+func normaliseDate(s string) string {
+	for _, layout := range []string{"02/01/2006", "2006-01-02"} {
+		if t, err := time.Parse(layout, strings.TrimSpace(s)); err == nil {
+			return t.Format("2006-01-02")
+		}
+	}
+
+	return ""
+}
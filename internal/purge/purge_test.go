@@ -0,0 +1,207 @@
+package purge
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"testing"
+
+	"github.com/ministryofjustice/opg-search-service/internal/elasticsearch"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+)
+
+type mockClient struct {
+	mock.Mock
+}
+
+func (m *mockClient) Search(ctx context.Context, indices []string, requestBody map[string]interface{}) (*elasticsearch.SearchResult, error) {
+	args := m.Called(ctx, indices, requestBody)
+	return args.Get(0).(*elasticsearch.SearchResult), args.Error(1)
+}
+
+func (m *mockClient) DoBulk(ctx context.Context, op *elasticsearch.BulkOp) (elasticsearch.BulkResult, error) {
+	args := m.Called(ctx, op)
+	return args.Get(0).(elasticsearch.BulkResult), args.Error(1)
+}
+
+const personSource = `{"_index":"person","id":1,"uId":"7000-0000-0001","firstname":"John","middlenames":"Paul","surname":"Smith","dob":"01/02/1980","addresses":[{"postcode":"AB1 2CD"}]}`
+
+var personSearch = map[string]interface{}{
+	"query": map[string]interface{}{
+		"term": map[string]interface{}{"uId": "7000-0000-0001"},
+	},
+	"size":             maxMatches,
+	"track_total_hits": true,
+}
+
+var lpaSearch = map[string]interface{}{
+	"query": map[string]interface{}{
+		"bool": map[string]interface{}{
+			"should": []interface{}{
+				map[string]interface{}{"match_phrase": map[string]interface{}{"certificateProvider.surname": "Smith"}},
+				map[string]interface{}{"match_phrase": map[string]interface{}{"attorneys.surname": "Smith"}},
+			},
+			"minimum_should_match": 1,
+		},
+	},
+	"size":             maxMatches,
+	"track_total_hits": true,
+}
+
+func TestPurge(t *testing.T) {
+	client := &mockClient{}
+
+	client.
+		On("Search", mock.Anything, []string{"person_*"}, personSearch).
+		Return(&elasticsearch.SearchResult{
+			Hits:  []json.RawMessage{[]byte(personSource), []byte(personSource)},
+			Total: 2,
+			Refs: []elasticsearch.DocumentRef{
+				{Index: "person_new", ID: "1"},
+				{Index: "person_old", ID: "1"},
+			},
+		}, nil).
+		Once()
+
+	client.
+		On("Search", mock.Anything, []string{"digital_lpa_*"}, lpaSearch).
+		Return(&elasticsearch.SearchResult{
+			Hits: []json.RawMessage{
+				[]byte(`{"_index":"digital","uId":"M-0000-0000-0001","certificateProvider":{"firstNames":"John","surname":"Smith","address":{"postcode":"ab12cd"}},"attorneys":[{"firstNames":"Jane","surname":"Smith","dob":"1982-03-04"},{"firstNames":"John Paul","surname":"Smith","dob":"1980-02-01"}]}`),
+				[]byte(`{"_index":"digital","uId":"M-0000-0000-0002","attorneys":[{"firstNames":"John","surname":"Smith","dob":"1970-01-01"}]}`),
+			},
+			Total: 2,
+			Refs: []elasticsearch.DocumentRef{
+				{Index: "digital_lpa_new", ID: "M-0000-0000-0001"},
+				{Index: "digital_lpa_new", ID: "M-0000-0000-0002"},
+			},
+		}, nil).
+		Once()
+
+	lpaOp := elasticsearch.NewBulkOp("digital_lpa_new")
+	_ = lpaOp.Index("M-0000-0000-0001", map[string]interface{}{
+		"uId":                 "M-0000-0000-0001",
+		"certificateProvider": map[string]interface{}{},
+		"attorneys": []interface{}{
+			map[string]interface{}{"firstNames": "Jane", "surname": "Smith", "dob": "1982-03-04"},
+		},
+	})
+
+	newOp := elasticsearch.NewBulkOp("person_new")
+	_ = newOp.Delete("1")
+
+	oldOp := elasticsearch.NewBulkOp("person_old")
+	_ = oldOp.Delete("1")
+
+	client.
+		On("DoBulk", mock.Anything, lpaOp).
+		Return(elasticsearch.BulkResult{Successful: 1, Results: []elasticsearch.IndexResult{{Id: "M-0000-0000-0001", StatusCode: 200}}}, nil).
+		Once()
+
+	client.
+		On("DoBulk", mock.Anything, newOp).
+		Return(elasticsearch.BulkResult{Successful: 1, Results: []elasticsearch.IndexResult{{Id: "1", StatusCode: 200}}}, nil).
+		Once()
+
+	client.
+		On("DoBulk", mock.Anything, oldOp).
+		Return(elasticsearch.BulkResult{Failed: 1, Results: []elasticsearch.IndexResult{{Id: "1", StatusCode: 404, Message: "not_found"}}}, nil).
+		Once()
+
+	report, err := New(client).Purge(context.Background(), "7000-0000-0001", false)
+	assert.Nil(t, err)
+	assert.Equal(t, &Report{
+		UID: "7000-0000-0001",
+		Deleted: []*Document{
+			{Index: "person_new", ID: "1", StatusCode: 200},
+			{Index: "person_old", ID: "1", StatusCode: 404, Message: "not_found"},
+		},
+		Scrubbed: []*Document{
+			{Index: "digital_lpa_new", ID: "M-0000-0000-0001", Fields: []string{"certificateProvider", "attorneys[1]"}, StatusCode: 200},
+		},
+		Failed: 1,
+	}, report)
+
+	client.AssertExpectations(t)
+}
+
+func TestPurgeExplain(t *testing.T) {
+	client := &mockClient{}
+
+	client.
+		On("Search", mock.Anything, []string{"person_*"}, personSearch).
+		Return(&elasticsearch.SearchResult{
+			Hits:  []json.RawMessage{[]byte(personSource)},
+			Total: 1,
+			Refs:  []elasticsearch.DocumentRef{{Index: "person_new", ID: "1"}},
+		}, nil).
+		Once()
+
+	client.
+		On("Search", mock.Anything, []string{"digital_lpa_*"}, lpaSearch).
+		Return(&elasticsearch.SearchResult{
+			Hits:  []json.RawMessage{[]byte(`{"uId":"M-0000-0000-0001","attorneys":[{"firstNames":"John","surname":"Smith","dob":"1980-02-01"}]}`)},
+			Total: 1,
+			Refs:  []elasticsearch.DocumentRef{{Index: "digital_lpa_new", ID: "M-0000-0000-0001"}},
+		}, nil).
+		Once()
+
+	report, err := New(client).Purge(context.Background(), "7000-0000-0001", true)
+	assert.Nil(t, err)
+	assert.Equal(t, &Report{
+		UID:      "7000-0000-0001",
+		Explain:  true,
+		Deleted:  []*Document{{Index: "person_new", ID: "1"}},
+		Scrubbed: []*Document{{Index: "digital_lpa_new", ID: "M-0000-0000-0001", Fields: []string{"attorneys[0]"}}},
+	}, report)
+
+	client.AssertNotCalled(t, "DoBulk", mock.Anything, mock.Anything)
+}
+
+func TestPurgeNotFound(t *testing.T) {
+	client := &mockClient{}
+
+	client.
+		On("Search", mock.Anything, []string{"person_*"}, personSearch).
+		Return(&elasticsearch.SearchResult{Hits: []json.RawMessage{}, Refs: []elasticsearch.DocumentRef{}}, nil).
+		Once()
+
+	_, err := New(client).Purge(context.Background(), "7000-0000-0001", false)
+	assert.Equal(t, ErrNotFound, err)
+}
+
+func TestPurgeTooManyMatches(t *testing.T) {
+	client := &mockClient{}
+
+	client.
+		On("Search", mock.Anything, []string{"person_*"}, personSearch).
+		Return(&elasticsearch.SearchResult{
+			Hits:  []json.RawMessage{[]byte(personSource)},
+			Total: 1001,
+			Refs:  []elasticsearch.DocumentRef{{Index: "person_new", ID: "1"}},
+		}, nil).
+		Once()
+
+	_, err := New(client).Purge(context.Background(), "7000-0000-0001", false)
+	assert.Equal(t, errors.New("found 1001 documents in person indices, which is more than can be purged at once"), err)
+}
+
+func TestPurgeSearchError(t *testing.T) {
+	client := &mockClient{}
+	expectedErr := errors.New("oops")
+
+	client.
+		On("Search", mock.Anything, []string{"person_*"}, personSearch).
+		Return(&elasticsearch.SearchResult{}, expectedErr).
+		Once()
+
+	_, err := New(client).Purge(context.Background(), "7000-0000-0001", false)
+	assert.Equal(t, expectedErr, err)
+}
+
+func TestNormaliseDate(t *testing.T) {
+	assert.Equal(t, "1980-02-01", normaliseDate("01/02/1980"))
+	assert.Equal(t, "1980-02-01", normaliseDate("1980-02-01"))
+	assert.Equal(t, "", normaliseDate("1980"))
+}
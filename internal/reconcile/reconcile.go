@@ -0,0 +1,378 @@
+package reconcile
+
+import (
+	"bytes"
+	"context"
+	"crypto/sha256"
+	"encoding/json"
+	"fmt"
+	"slices"
+	"strconv"
+
+	"github.com/ministryofjustice/opg-search-service/internal/elasticsearch"
+	"github.com/ministryofjustice/opg-search-service/internal/index"
+)
+
+type Client interface {
+	Search(ctx context.Context, indices []string, requestBody map[string]interface{}) (*elasticsearch.SearchResult, error)
+}
+
+type Indexer interface {
+	ByIDs(ctx context.Context, ids []int) (*index.Result, error)
+	Delete(ctx context.Context, ids []string) (*index.Result, error)
+}
+
+// Report lists the documents in the index that do not match the database, by
+// their id
+type Report struct {
+	Index   string `json:"index"`
+	From    int    `json:"from"`
+	To      int    `json:"to"`
+	Checked int    `json:"checked"`
+	// Missing are records that have no document
+	Missing []string `json:"missing"`
+	// Stale are documents that differ from their record
+	Stale []string `json:"stale"`
+	// Orphaned are documents that have no record
+	Orphaned []string `json:"orphaned"`
+	Fixed    bool     `json:"fixed,omitempty"`
+}
+
+// Reconciler compares the records in the database with the documents in an
+// index, a batch of ids at a time. It only works for indices where the id of
+// a document is the id of its record.
+type Reconciler struct {
+	client    Client
+	db        index.DB
+	indexer   Indexer
+	indexName string
+
+	BatchSize int
+	PageSize  int
+}
+
+func New(client Client, db index.DB, indexer Indexer, indexName string) *Reconciler {
+	return &Reconciler{
+		client:    client,
+		db:        db,
+		indexer:   indexer,
+		indexName: indexName,
+		BatchSize: 10000,
+		PageSize:  1000,
+	}
+}
+
+// Range reconciles the ids from-to inclusive. When fix is set missing and
+// stale documents are reindexed, and orphaned documents are deleted.
+func (r *Reconciler) Range(ctx context.Context, from, to int, fix bool) (*Report, error) {
+	report := &Report{Index: r.indexName, From: from, To: to, Missing: []string{}, Stale: []string{}, Orphaned: []string{}, Fixed: fix}
+
+	for start := from; start <= to; start += r.BatchSize {
+		end := min(start+r.BatchSize-1, to)
+
+		if err := r.batch(ctx, report, start, end, fix); err != nil {
+			return nil, err
+		}
+	}
+
+	return report, nil
+}
+
+func (r *Reconciler) batch(ctx context.Context, report *Report, from, to int, fix bool) error {
+	records, err := r.records(ctx, from, to)
+	if err != nil {
+		return err
+	}
+
+	documents, err := r.documents(ctx, from, to, records)
+	if err != nil {
+		return err
+	}
+
+	var missing, stale, orphaned []string
+	for id, record := range records {
+		docHash, ok := documents[id]
+		if !ok {
+			missing = append(missing, id)
+			continue
+		}
+
+		recordHash, err := hash(normalise(record))
+		if err != nil {
+			return fmt.Errorf("hashing record id=%s: %w", id, err)
+		}
+
+		if !bytes.Equal(recordHash, docHash) {
+			stale = append(stale, id)
+		}
+	}
+	for id := range documents {
+		if _, ok := records[id]; !ok {
+			orphaned = append(orphaned, id)
+		}
+	}
+
+	sortIDs(missing)
+	sortIDs(stale)
+	sortIDs(orphaned)
+
+	report.Checked += len(records)
+	report.Missing = append(report.Missing, missing...)
+	report.Stale = append(report.Stale, stale...)
+	report.Orphaned = append(report.Orphaned, orphaned...)
+
+	if !fix {
+		return nil
+	}
+
+	if reindex := slices.Concat(missing, stale); len(reindex) > 0 {
+		ids := make([]int, len(reindex))
+		for i, id := range reindex {
+			ids[i], _ = strconv.Atoi(id)
+		}
+
+		result, err := r.indexer.ByIDs(ctx, ids)
+		if err := check("reindexing", result, err); err != nil {
+			return err
+		}
+	}
+
+	if len(orphaned) > 0 {
+		result, err := r.indexer.Delete(ctx, orphaned)
+		if err := check("deleting", result, err); err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+// records reads each record in the range from the database, as decoded JSON
+// so that it can be compared with its document
+func (r *Reconciler) records(ctx context.Context, from, to int) (map[string]interface{}, error) {
+	var rerr error
+	items := make(chan index.Indexable, r.PageSize)
+
+	go func() {
+		defer func() { close(items) }()
+
+		rerr = r.db.QueryByID(ctx, items, from, to)
+	}()
+
+	var derr error
+	records := map[string]interface{}{}
+	for item := range items {
+		if derr != nil {
+			continue
+		}
+
+		record, err := decode(item)
+		if err != nil {
+			derr = fmt.Errorf("reading record id=%s: %w", item.Id(), err)
+			continue
+		}
+
+		records[item.Id()] = record
+	}
+
+	if rerr != nil {
+		return nil, rerr
+	}
+
+	return records, derr
+}
+
+// documents pages through the documents in the index with an id in the range,
+// returning the hash of each. A document is hashed with only the fields its
+// record has, so that fields the database does not produce are not compared.
+func (r *Reconciler) documents(ctx context.Context, from, to int, records map[string]interface{}) (map[string][]byte, error) {
+	hashes := map[string][]byte{}
+
+	var after []interface{}
+	for {
+		body := map[string]interface{}{
+			"query": map[string]interface{}{
+				"range": map[string]interface{}{
+					"id": map[string]interface{}{"gte": from, "lte": to},
+				},
+			},
+			"sort":             []interface{}{map[string]interface{}{"id": "asc"}},
+			"size":             r.PageSize,
+			"track_total_hits": false,
+		}
+		if after != nil {
+			body["search_after"] = after
+		}
+
+		result, err := r.client.Search(ctx, []string{r.indexName}, body)
+		if err != nil {
+			return nil, err
+		}
+
+		for i, hit := range result.Hits {
+			id := result.Refs[i].ID
+
+			var source interface{}
+			if err := json.Unmarshal(hit, &source); err != nil {
+				return nil, fmt.Errorf("reading document id=%s: %w", id, err)
+			}
+
+			record, ok := records[id]
+			if !ok {
+				// orphaned, so there is nothing to compare it with
+				hashes[id] = nil
+				continue
+			}
+
+			if hashes[id], err = hash(project(source, record)); err != nil {
+				return nil, err
+			}
+		}
+
+		if len(result.Hits) < r.PageSize {
+			return hashes, nil
+		}
+
+		after = result.LastSort
+	}
+}
+
+// decode converts a record to the JSON it would be indexed as, without its
+// version as that changes each time the record is read
+func decode(item index.Indexable) (interface{}, error) {
+	data, err := json.Marshal(item)
+	if err != nil {
+		return nil, err
+	}
+
+	var v interface{}
+	if err := json.Unmarshal(data, &v); err != nil {
+		return nil, err
+	}
+
+	if m, ok := v.(map[string]interface{}); ok {
+		delete(m, "version")
+	}
+
+	return v, nil
+}
+
+// project keeps only the fields of v that shape has, recursing into objects
+// and arrays, so a document can be compared with the record it came from
+func project(v, shape interface{}) interface{} {
+	switch shape := shape.(type) {
+	case map[string]interface{}:
+		m, _ := v.(map[string]interface{})
+		projected := map[string]interface{}{}
+		for key, value := range shape {
+			projected[key] = project(m[key], value)
+		}
+		return normalise(projected)
+
+	case []interface{}:
+		a, ok := v.([]interface{})
+		if !ok {
+			return normalise(v)
+		}
+
+		projected := make([]interface{}, len(a))
+		for i, value := range a {
+			if i < len(shape) {
+				projected[i] = project(value, shape[i])
+			} else {
+				projected[i] = normalise(value)
+			}
+		}
+		return normalise(projected)
+
+	default:
+		return normalise(v)
+	}
+}
+
+// normalise replaces empty values with nil, as a field that is empty in the
+// database may be missing or null in a document that was sent to the index
+func normalise(v interface{}) interface{} {
+	switch v := v.(type) {
+	case map[string]interface{}:
+		normalised := map[string]interface{}{}
+		for key, value := range v {
+			if value = normalise(value); value != nil {
+				normalised[key] = value
+			}
+		}
+		if len(normalised) == 0 {
+			return nil
+		}
+		return normalised
+
+	case []interface{}:
+		if len(v) == 0 {
+			return nil
+		}
+		normalised := make([]interface{}, len(v))
+		for i, value := range v {
+			normalised[i] = normalise(value)
+		}
+		return normalised
+
+	case string:
+		if v == "" {
+			return nil
+		}
+
+	case float64:
+		if v == 0 {
+			return nil
+		}
+
+	case bool:
+		if !v {
+			return nil
+		}
+	}
+
+	return v
+}
+
+// hash encodes v as JSON before hashing it, object keys are encoded in order
+// so the same fields hash the same regardless of their order in the source
+func hash(v interface{}) ([]byte, error) {
+	data, err := json.Marshal(v)
+	if err != nil {
+		return nil, err
+	}
+
+	sum := sha256.Sum256(data)
+	return sum[:], nil
+}
+
+func sortIDs(ids []string) {
+	slices.SortFunc(ids, func(a, b string) int {
+		x, _ := strconv.Atoi(a)
+		y, _ := strconv.Atoi(b)
+		return x - y
+	})
+}
+
+func check(action string, result *index.Result, err error) error {
+	if err != nil {
+		return fmt.Errorf("%s: %w", action, err)
+	}
+
+	if len(result.Errors) > 0 {
+		return fmt.Errorf("%s: %s", action, result.Errors[0])
+	}
+
+	if result.Failed > 0 {
+		return fmt.Errorf("%s: %d documents failed", action, result.Failed)
+	}
+
+	// a conflict means the document was written by something else while
+	// reconciling, so it has not been fixed and needs checking again
+	if result.Conflicts > 0 {
+		return fmt.Errorf("%s: %d documents had a newer version", action, result.Conflicts)
+	}
+
+	return nil
+}
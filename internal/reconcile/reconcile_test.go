@@ -0,0 +1,284 @@
+package reconcile
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"strconv"
+	"testing"
+	"time"
+
+	"github.com/ministryofjustice/opg-search-service/internal/elasticsearch"
+	"github.com/ministryofjustice/opg-search-service/internal/index"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+)
+
+type record struct {
+	ID   int    `json:"id"`
+	Name string `json:"name"`
+}
+
+func (r record) Id() string {
+	return strconv.Itoa(r.ID)
+}
+
+type mockDB struct {
+	mock.Mock
+}
+
+func (m *mockDB) QueryIDRange(ctx context.Context) (min, max int, err error) {
+	args := m.Called(ctx)
+	return args.Int(0), args.Int(1), args.Error(2)
+}
+
+func (m *mockDB) QueryByID(ctx context.Context, results chan<- index.Indexable, from, to int) error {
+	args := m.Called(ctx, results, from, to)
+	return args.Error(0)
+}
+
+func (m *mockDB) QueryFromDate(ctx context.Context, results chan<- index.Indexable, from time.Time) error {
+	args := m.Called(ctx, results, from)
+	return args.Error(0)
+}
+
+type mockClient struct {
+	mock.Mock
+}
+
+func (m *mockClient) Search(ctx context.Context, indices []string, requestBody map[string]interface{}) (*elasticsearch.SearchResult, error) {
+	args := m.Called(ctx, indices, requestBody)
+	result, _ := args.Get(0).(*elasticsearch.SearchResult)
+	return result, args.Error(1)
+}
+
+type mockIndexer struct {
+	mock.Mock
+}
+
+func (m *mockIndexer) ByIDs(ctx context.Context, ids []int) (*index.Result, error) {
+	args := m.Called(ctx, ids)
+	result, _ := args.Get(0).(*index.Result)
+	return result, args.Error(1)
+}
+
+func (m *mockIndexer) Delete(ctx context.Context, ids []string) (*index.Result, error) {
+	args := m.Called(ctx, ids)
+	result, _ := args.Get(0).(*index.Result)
+	return result, args.Error(1)
+}
+
+func sendRecords(records ...record) func(mock.Arguments) {
+	return func(args mock.Arguments) {
+		ch := args.Get(1).(chan<- index.Indexable)
+		for _, r := range records {
+			ch <- r
+		}
+	}
+}
+
+func rangeQuery(from, to int) map[string]interface{} {
+	return map[string]interface{}{
+		"range": map[string]interface{}{
+			"id": map[string]interface{}{"gte": from, "lte": to},
+		},
+	}
+}
+
+func searchResult(ids []string, sources ...string) *elasticsearch.SearchResult {
+	result := &elasticsearch.SearchResult{}
+	for i, source := range sources {
+		result.Hits = append(result.Hits, json.RawMessage(source))
+		result.Refs = append(result.Refs, elasticsearch.DocumentRef{Index: "person_a", ID: ids[i]})
+		result.LastSort = []interface{}{ids[i]}
+	}
+	return result
+}
+
+func TestRange(t *testing.T) {
+	ctx := context.Background()
+
+	db := &mockDB{}
+	db.
+		On("QueryByID", ctx, mock.Anything, 1, 3).
+		Run(sendRecords(record{ID: 1, Name: "a"}, record{ID: 2, Name: "b"}, record{ID: 3, Name: "c"})).
+		Return(nil)
+	db.
+		On("QueryByID", ctx, mock.Anything, 4, 5).
+		Run(sendRecords(record{ID: 4, Name: "d"})).
+		Return(nil)
+
+	client := &mockClient{}
+	client.
+		On("Search", ctx, []string{"person_a"}, mock.MatchedBy(func(body map[string]interface{}) bool {
+			_, paged := body["search_after"]
+			return assert.ObjectsAreEqual(rangeQuery(1, 3), body["query"]) && !paged
+		})).
+		Return(searchResult([]string{"1", "2"}, `{"_index":"person","name":"a","id":1}`, `{"_index":"person","id":2,"name":"old"}`), nil)
+	client.
+		On("Search", ctx, []string{"person_a"}, mock.MatchedBy(func(body map[string]interface{}) bool {
+			return assert.ObjectsAreEqual(rangeQuery(1, 3), body["query"]) && assert.ObjectsAreEqual([]interface{}{"2"}, body["search_after"])
+		})).
+		Return(searchResult(nil), nil)
+	client.
+		On("Search", ctx, []string{"person_a"}, mock.MatchedBy(func(body map[string]interface{}) bool {
+			return assert.ObjectsAreEqual(rangeQuery(4, 5), body["query"]) && body["search_after"] == nil
+		})).
+		Return(searchResult([]string{"4", "5"}, `{"_index":"person","id":4,"name":"d"}`, `{"_index":"person","id":5,"name":"e"}`), nil)
+	client.
+		On("Search", ctx, []string{"person_a"}, mock.MatchedBy(func(body map[string]interface{}) bool {
+			return assert.ObjectsAreEqual(rangeQuery(4, 5), body["query"]) && body["search_after"] != nil
+		})).
+		Return(searchResult(nil), nil)
+
+	indexer := &mockIndexer{}
+	indexer.
+		On("ByIDs", ctx, []int{3, 2}).
+		Return(&index.Result{Successful: 2}, nil)
+	indexer.
+		On("Delete", ctx, []string{"5"}).
+		Return(&index.Result{Successful: 1}, nil)
+
+	reconciler := New(client, db, indexer, "person_a")
+	reconciler.BatchSize = 3
+	reconciler.PageSize = 2
+
+	report, err := reconciler.Range(ctx, 1, 5, true)
+	assert.Nil(t, err)
+	assert.Equal(t, &Report{
+		Index:    "person_a",
+		From:     1,
+		To:       5,
+		Checked:  4,
+		Missing:  []string{"3"},
+		Stale:    []string{"2"},
+		Orphaned: []string{"5"},
+		Fixed:    true,
+	}, report)
+
+	mock.AssertExpectationsForObjects(t, db, client, indexer)
+}
+
+func TestRangeWithoutFix(t *testing.T) {
+	ctx := context.Background()
+
+	db := &mockDB{}
+	db.
+		On("QueryByID", ctx, mock.Anything, 1, 2).
+		Run(sendRecords(record{ID: 1, Name: "a"})).
+		Return(nil)
+
+	client := &mockClient{}
+	client.
+		On("Search", ctx, []string{"person_a"}, mock.Anything).
+		Return(searchResult([]string{"2"}, `{"_index":"person","id":2,"name":"b"}`), nil)
+
+	indexer := &mockIndexer{}
+
+	report, err := New(client, db, indexer, "person_a").Range(ctx, 1, 2, false)
+	assert.Nil(t, err)
+	assert.Equal(t, []string{"1"}, report.Missing)
+	assert.Equal(t, []string{"2"}, report.Orphaned)
+	assert.False(t, report.Fixed)
+
+	indexer.AssertNotCalled(t, "ByIDs", mock.Anything, mock.Anything)
+	indexer.AssertNotCalled(t, "Delete", mock.Anything, mock.Anything)
+}
+
+func TestRangeFixFails(t *testing.T) {
+	ctx := context.Background()
+
+	db := &mockDB{}
+	db.
+		On("QueryByID", ctx, mock.Anything, 1, 1).
+		Run(sendRecords(record{ID: 1, Name: "a"})).
+		Return(nil)
+
+	client := &mockClient{}
+	client.
+		On("Search", ctx, []string{"person_a"}, mock.Anything).
+		Return(searchResult(nil), nil)
+
+	indexer := &mockIndexer{}
+	indexer.
+		On("ByIDs", ctx, []int{1}).
+		Return(&index.Result{Errors: []string{"hmm"}}, nil)
+
+	_, err := New(client, db, indexer, "person_a").Range(ctx, 1, 1, true)
+	assert.Equal(t, "reindexing: hmm", err.Error())
+}
+
+func TestRangeFixConflicts(t *testing.T) {
+	ctx := context.Background()
+
+	db := &mockDB{}
+	db.
+		On("QueryByID", ctx, mock.Anything, 1, 1).
+		Run(sendRecords(record{ID: 1, Name: "a"})).
+		Return(nil)
+
+	client := &mockClient{}
+	client.
+		On("Search", ctx, []string{"person_a"}, mock.Anything).
+		Return(searchResult(nil), nil)
+
+	indexer := &mockIndexer{}
+	indexer.
+		On("ByIDs", ctx, []int{1}).
+		Return(&index.Result{Conflicts: 1}, nil)
+
+	_, err := New(client, db, indexer, "person_a").Range(ctx, 1, 1, true)
+	assert.Equal(t, "reindexing: 1 documents had a newer version", err.Error())
+}
+
+func TestRangeComparesRecordFields(t *testing.T) {
+	ctx := context.Background()
+
+	db := &mockDB{}
+	db.
+		On("QueryByID", ctx, mock.Anything, 1, 3).
+		Run(sendRecords(record{ID: 1, Name: "a"}, record{ID: 2}, record{ID: 3, Name: "c"})).
+		Return(nil)
+
+	client := &mockClient{}
+	client.
+		On("Search", ctx, []string{"person_a"}, mock.Anything).
+		Return(searchResult([]string{"1", "2", "3"},
+			`{"_index":"person","id":1,"name":"a","version":1609675200000,"className":"Person"}`,
+			`{"_index":"person","id":2,"name":null}`,
+			`{"_index":"person","id":3,"className":"Person"}`,
+		), nil)
+
+	report, err := New(client, db, &mockIndexer{}, "person_a").Range(ctx, 1, 3, false)
+	assert.Nil(t, err)
+	assert.Equal(t, []string{}, report.Missing)
+	assert.Equal(t, []string{"3"}, report.Stale)
+}
+
+func TestProject(t *testing.T) {
+	var record, document interface{}
+	_ = json.Unmarshal([]byte(`{"id":1,"addresses":[{"postcode":"A1"}],"cases":[],"email":""}`), &record)
+	_ = json.Unmarshal([]byte(`{"id":1,"addresses":[{"postcode":"A1","className":"Address"}],"className":"Person"}`), &document)
+
+	assert.Equal(t, normalise(record), project(document, record))
+
+	_ = json.Unmarshal([]byte(`{"id":1,"addresses":[{"postcode":"A1"},{"postcode":"B2"}]}`), &document)
+	assert.NotEqual(t, normalise(record), project(document, record))
+}
+
+func TestRangeSearchError(t *testing.T) {
+	ctx := context.Background()
+
+	db := &mockDB{}
+	db.
+		On("QueryByID", ctx, mock.Anything, 1, 1).
+		Return(nil)
+
+	client := &mockClient{}
+	client.
+		On("Search", ctx, []string{"person_a"}, mock.Anything).
+		Return(nil, errors.New("hmm"))
+
+	_, err := New(client, db, &mockIndexer{}, "person_a").Range(ctx, 1, 1, false)
+	assert.Equal(t, errors.New("hmm"), err)
+}
@@ -2,7 +2,6 @@ package remove
 
 import (
 	"context"
-	"errors"
 	"fmt"
 	"net/http"
 
@@ -16,26 +15,35 @@ type DeleteClient interface {
 	Delete(ctx context.Context, indices []string, requestBody map[string]interface{}) (*elasticsearch.DeleteResult, error)
 }
 
+// Key is how the document to delete is identified, Param is the route variable
+// and Field is the field in the index that it is matched against
+type Key struct {
+	Param string
+	Field string
+}
+
 type Handler struct {
 	logger  *logrus.Logger
 	client  DeleteClient
 	indices []string
+	key     Key
 }
 
-func NewHandler(logger *logrus.Logger, client DeleteClient, indices []string) *Handler {
+func NewHandler(logger *logrus.Logger, client DeleteClient, indices []string, key Key) *Handler {
 	return &Handler{
 		logger:  logger,
 		client:  client,
 		indices: indices,
+		key:     key,
 	}
 }
 
 func (h *Handler) ServeHTTP(w http.ResponseWriter, r *http.Request) {
 	vars := mux.Vars(r)
-	uid := vars["uid"]
+	id := vars[h.key.Param]
 
-	if uid == "" {
-		err := errors.New("uid is required and cannot be empty")
+	if id == "" {
+		err := fmt.Errorf("%s is required and cannot be empty", h.key.Param)
 		h.logger.Println(err)
 		response.WriteJSONErrors(w, err.Error(), []response.Error{}, http.StatusBadRequest)
 		return
@@ -44,7 +52,7 @@ func (h *Handler) ServeHTTP(w http.ResponseWriter, r *http.Request) {
 	requestBody := map[string]interface{}{
 		"query": map[string]interface{}{
 			"match": map[string]interface{}{
-				"uId": uid,
+				h.key.Field: id,
 			},
 		},
 		"max_docs": 1,
@@ -30,7 +30,7 @@ type DeleteHandlerTestSuite struct {
 func (suite *DeleteHandlerTestSuite) SetupTest() {
 	suite.logger, _ = test.NewNullLogger()
 	suite.esClient = new(elasticsearch.MockESClient)
-	suite.handler = NewHandler(suite.logger, suite.esClient, []string{"whatever"})
+	suite.handler = NewHandler(suite.logger, suite.esClient, []string{"whatever"}, Key{Param: "uid", Field: "uId"})
 	suite.recorder = httptest.NewRecorder()
 }
 
@@ -128,6 +128,37 @@ func (suite *DeleteHandlerTestSuite) Test_Delete() {
 	suite.Equal("{}", suite.RespBody())
 }
 
+func (suite *DeleteHandlerTestSuite) Test_DeleteByID() {
+	suite.handler = NewHandler(suite.logger, suite.esClient, []string{"whatever"}, Key{Param: "id", Field: "_id"})
+
+	deleteBody := map[string]interface{}{
+		"query": map[string]interface{}{
+			"match": map[string]interface{}{
+				"_id": "123",
+			},
+		},
+		"max_docs": 1,
+	}
+
+	suite.esClient.
+		On("Delete", mock.Anything, []string{"whatever"}, deleteBody).
+		Return(&elasticsearch.DeleteResult{Total: 1}, nil)
+
+	suite.ServeRequest(http.MethodDelete, "/firms/123", map[string]string{"id": "123"})
+
+	suite.Equal(http.StatusOK, suite.RespCode())
+	suite.Equal("{}", suite.RespBody())
+}
+
+func (suite *DeleteHandlerTestSuite) Test_MissingID() {
+	suite.handler = NewHandler(suite.logger, suite.esClient, []string{"whatever"}, Key{Param: "id", Field: "_id"})
+
+	suite.ServeRequest(http.MethodDelete, "/firms/", map[string]string{})
+
+	suite.Equal(http.StatusBadRequest, suite.RespCode())
+	suite.Equal(`{"message":"id is required and cannot be empty","errors":[]}`+"\n", suite.RespBody())
+}
+
 func TestDeleteHandler(t *testing.T) {
 	suite.Run(t, new(DeleteHandlerTestSuite))
 }
@@ -0,0 +1,57 @@
+package search
+
+import (
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+)
+
+const (
+	// how long a point-in-time is kept open between page requests
+	pitKeepAlive = "5m"
+
+	// the number of results elasticsearch returns when no size is requested
+	defaultPageSize = 10
+)
+
+var errInvalidCursor = errors.New("cursor is invalid")
+
+// cursor marks a position within a point-in-time, it is passed to callers as
+// an opaque string
+type cursor struct {
+	PitID string        `json:"pit"`
+	After []interface{} `json:"after,omitempty"`
+}
+
+func decodeCursor(s string) (*cursor, error) {
+	data, err := base64.RawURLEncoding.DecodeString(s)
+	if err != nil {
+		return nil, errInvalidCursor
+	}
+
+	var c cursor
+	if err := json.Unmarshal(data, &c); err != nil || c.PitID == "" {
+		return nil, errInvalidCursor
+	}
+
+	return &c, nil
+}
+
+func (c *cursor) String() string {
+	data, _ := json.Marshal(c)
+	return base64.RawURLEncoding.EncodeToString(data)
+}
+
+// apply changes a prepared request body to search from the position of the
+// cursor, the index to search is given by the point-in-time instead of the URL
+func (c *cursor) apply(body map[string]interface{}) {
+	body["pit"] = map[string]interface{}{
+		"id":         c.PitID,
+		"keep_alive": pitKeepAlive,
+	}
+	delete(body, "from")
+
+	if len(c.After) > 0 {
+		body["search_after"] = c.After
+	}
+}
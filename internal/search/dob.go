@@ -0,0 +1,82 @@
+package search
+
+import (
+	"fmt"
+	"time"
+)
+
+// dates of birth are normalised to dobFormat, which is the same date as
+// dobRangeFormat in opensearch
+const dobFormat = "2006-01-02"
+const dobRangeFormat = "yyyy-MM-dd"
+
+// the date fields of each entity that a date of birth range is applied to
+var personDobFields = []string{"dob.date"}
+var digitalLpaDobFields = []string{"donor.dob.date", "attorneys.dob.date"}
+var allDobFields = concat(personDobFields, digitalLpaDobFields)
+
+// parseDob reads a full or partial date, so that "1948" can be used to search
+// for anyone born in that year. The start or end of the period is returned
+// depending on whether it is the lower or upper bound of a range.
+func parseDob(s string, end bool) (string, error) {
+	for _, layout := range []struct {
+		format string
+		years  int
+		months int
+		days   int
+	}{
+		{"2006", 1, 0, 0},
+		{"2006-01", 0, 1, 0},
+		{"2006-01-02", 0, 0, 1},
+	} {
+		t, err := time.Parse(layout.format, s)
+		if err != nil {
+			continue
+		}
+
+		if end {
+			t = t.AddDate(layout.years, layout.months, layout.days-1)
+		}
+
+		return t.Format(dobFormat), nil
+	}
+
+	return "", fmt.Errorf("%s is not a date in the format YYYY, YYYY-MM or YYYY-MM-DD", s)
+}
+
+// withDobRange filters the query to entities with a date of birth within the
+// requested range, when there are multiple fields any one of them can match
+func withDobRange(req *Request, query map[string]interface{}, fields []string) map[string]interface{} {
+	if req.DobFrom == "" && req.DobTo == "" {
+		return query
+	}
+
+	dobRange := map[string]interface{}{"format": dobRangeFormat}
+	if req.DobFrom != "" {
+		dobRange["gte"] = req.DobFrom
+	}
+	if req.DobTo != "" {
+		dobRange["lte"] = req.DobTo
+	}
+
+	should := []interface{}{}
+	for _, field := range fields {
+		should = append(should, map[string]interface{}{
+			"range": map[string]interface{}{
+				field: dobRange,
+			},
+		})
+	}
+
+	return map[string]interface{}{
+		"bool": map[string]interface{}{
+			"must": query,
+			"filter": map[string]interface{}{
+				"bool": map[string]interface{}{
+					"should":               should,
+					"minimum_should_match": 1,
+				},
+			},
+		},
+	}
+}
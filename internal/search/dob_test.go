@@ -0,0 +1,45 @@
+package search
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestParseDob(t *testing.T) {
+	testCases := map[string]struct {
+		in    string
+		start string
+		end   string
+	}{
+		"year":         {in: "1948", start: "1948-01-01", end: "1948-12-31"},
+		"month":        {in: "1948-02", start: "1948-02-01", end: "1948-02-29"},
+		"day":          {in: "1948-02-03", start: "1948-02-03", end: "1948-02-03"},
+		"end of month": {in: "1949-12", start: "1949-12-01", end: "1949-12-31"},
+	}
+
+	for name, tc := range testCases {
+		t.Run(name, func(t *testing.T) {
+			start, err := parseDob(tc.in, false)
+			assert.Nil(t, err)
+			assert.Equal(t, tc.start, start)
+
+			end, err := parseDob(tc.in, true)
+			assert.Nil(t, err)
+			assert.Equal(t, tc.end, end)
+		})
+	}
+}
+
+func TestParseDobInvalid(t *testing.T) {
+	for _, in := range []string{"03/02/1948", "48", "1948-13", "tomorrow"} {
+		_, err := parseDob(in, false)
+		assert.NotNil(t, err, in)
+	}
+}
+
+func TestWithDobRangeNotRequested(t *testing.T) {
+	query := map[string]interface{}{"match_all": map[string]interface{}{}}
+
+	assert.Equal(t, query, withDobRange(&Request{}, query, personDobFields))
+}
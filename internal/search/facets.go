@@ -0,0 +1,112 @@
+package search
+
+import (
+	"fmt"
+	"sort"
+)
+
+// the keyword fields of each entity that can be filtered or faceted on, keyed
+// by the name used in a request
+var personFacetFields = map[string]string{
+	"cases.caseType":    "cases.caseType",
+	"cases.caseSubtype": "cases.caseSubtype",
+	"className":         "className.keyword",
+}
+
+var firmFacetFields = map[string]string{
+	"town":   "town.keyword",
+	"county": "county.keyword",
+}
+
+var digitalLpaFacetFields = map[string]string{
+	"lpaType": "lpaType.keyword",
+}
+
+var allFacetFields = mergeFacetFields(personFacetFields, firmFacetFields, digitalLpaFacetFields)
+
+func mergeFacetFields(xs ...map[string]string) map[string]string {
+	result := map[string]string{}
+	for _, x := range xs {
+		for name, field := range x {
+			result[name] = field
+		}
+	}
+
+	return result
+}
+
+// validateFacets checks that the filters and facets requested are on fields
+// that can be used for at least one entity
+func (sr *Request) validateFacets() error {
+	for name := range sr.Filters {
+		if _, ok := allFacetFields[name]; !ok {
+			return fmt.Errorf("cannot filter on %s", name)
+		}
+	}
+
+	for _, name := range sr.Facets {
+		if _, ok := allFacetFields[name]; !ok {
+			return fmt.Errorf("cannot facet on %s", name)
+		}
+	}
+
+	return nil
+}
+
+// withFilters limits the query to entities with one of the requested values
+// for each filter, a filter on a field the entity does not have cannot match
+func withFilters(req *Request, query map[string]interface{}, fields map[string]string) map[string]interface{} {
+	if len(req.Filters) == 0 {
+		return query
+	}
+
+	names := make([]string, 0, len(req.Filters))
+	for name := range req.Filters {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+
+	filters := []interface{}{}
+	for _, name := range names {
+		field, ok := fields[name]
+		if !ok {
+			filters = append(filters, map[string]interface{}{"match_none": map[string]interface{}{}})
+			continue
+		}
+
+		filters = append(filters, map[string]interface{}{
+			"terms": map[string]interface{}{
+				field: req.Filters[name],
+			},
+		})
+	}
+
+	return map[string]interface{}{
+		"bool": map[string]interface{}{
+			"must":   query,
+			"filter": filters,
+		},
+	}
+}
+
+// withFacets adds an aggregation for each requested facet that the entity has,
+// named the same as the facet so the counts are returned under that name
+func withFacets(req *Request, body map[string]interface{}, fields map[string]string) map[string]interface{} {
+	aggs, _ := body["aggs"].(map[string]interface{})
+
+	for _, name := range req.Facets {
+		field, ok := fields[name]
+		if !ok {
+			continue
+		}
+
+		aggs[name] = map[string]interface{}{
+			"terms": map[string]interface{}{
+				"field": field,
+				"size":  "20",
+			},
+		}
+	}
+
+	return body
+}
@@ -4,7 +4,9 @@ import (
 	"context"
 	"encoding/json"
 	"errors"
+	"fmt"
 	"net/http"
+	"slices"
 	"time"
 
 	"github.com/ministryofjustice/opg-search-service/internal/elasticsearch"
@@ -14,6 +16,8 @@ import (
 
 type SearchClient interface {
 	Search(ctx context.Context, indices []string, requestBody map[string]interface{}) (*elasticsearch.SearchResult, error)
+	OpenPointInTime(ctx context.Context, indices []string, keepAlive string) (string, error)
+	ClosePointInTime(ctx context.Context, pitID string) error
 }
 
 type PrepareQuery func(*Request) ([]string, map[string]interface{})
@@ -37,22 +41,40 @@ func (h *Handler) ServeHTTP(w http.ResponseWriter, r *http.Request) {
 
 	req, err := parseSearchRequest(r)
 	if err != nil {
-		h.logger.Println(err)
-		response.WriteJSONError(w, "request", err.Error(), http.StatusBadRequest)
+		writeRequestError(h.logger, w, err)
 		return
 	}
 
 	indices, requestBody := h.prepareQuery(req)
+	if req.Template != "" {
+		templateIndices, templateBody := PrepareQueryForTemplate(req)
+		if !containsAll(indices, templateIndices) {
+			writeRequestError(h.logger, w, fmt.Errorf("template %s cannot be used on this endpoint", req.Template))
+			return
+		}
 
-	result, err := h.client.Search(r.Context(), indices, requestBody)
-	if err != nil {
-		if errors.Is(err, context.Canceled) {
-			response.WriteJSONError(w, "request", "search request was cancelled", 499)
-		} else {
-			response.WriteJSONError(w, "request", "unexpected error from elasticsearch", http.StatusInternalServerError)
+		indices, requestBody = templateIndices, templateBody
+	}
+
+	page := req.cursor
+	if page == nil && req.Paginate {
+		pitID, err := h.client.OpenPointInTime(r.Context(), indices, pitKeepAlive)
+		if err != nil {
+			writeSearchError(h.logger, w, err)
+			return
 		}
-		h.logger.Println(err.Error())
 
+		page = &cursor{PitID: pitID}
+	}
+
+	if page != nil {
+		page.apply(requestBody)
+		indices = nil
+	}
+
+	result, err := h.client.Search(r.Context(), indices, requestBody)
+	if err != nil {
+		writeSearchError(h.logger, w, err)
 		return
 	}
 
@@ -60,14 +82,76 @@ func (h *Handler) ServeHTTP(w http.ResponseWriter, r *http.Request) {
 		Aggregations: result.Aggregations,
 		Results:      result.Hits,
 		Total: ResponseTotal{
-			Count: result.Total,
-			Exact: result.TotalExact,
+			Count:   result.Total,
+			Exact:   result.TotalExact,
+			Indices: indexTotals(result.Aggregations),
 		},
 	}
 
+	if page != nil {
+		resp.Next = h.nextCursor(r.Context(), req, page, result)
+	}
+
 	jsonResp, _ := json.Marshal(resp)
 	w.WriteHeader(http.StatusOK)
 	_, _ = w.Write(jsonResp)
 
 	h.logger.Printf("Request took: %d", time.Since(start))
 }
+
+func writeRequestError(logger *logrus.Logger, w http.ResponseWriter, err error) {
+	logger.Println(err)
+
+	var validationErrs validationError
+	if errors.As(err, &validationErrs) {
+		response.WriteJSONErrors(w, "Some fields have failed validation", validationErrs, http.StatusBadRequest)
+	} else {
+		response.WriteJSONError(w, "request", err.Error(), http.StatusBadRequest)
+	}
+}
+
+func writeSearchError(logger *logrus.Logger, w http.ResponseWriter, err error) {
+	if errors.Is(err, context.Canceled) {
+		response.WriteJSONError(w, "request", "search request was cancelled", 499)
+	} else {
+		response.WriteJSONError(w, "request", "unexpected error from elasticsearch", http.StatusInternalServerError)
+	}
+	logger.Println(err.Error())
+}
+
+// nextCursor returns the cursor for the page following result, or closes the
+// point-in-time when there are no more pages
+func (h *Handler) nextCursor(ctx context.Context, req *Request, page *cursor, result *elasticsearch.SearchResult) string {
+	pitID := page.PitID
+	if result.PitID != "" {
+		pitID = result.PitID
+	}
+
+	size := req.Size
+	if size <= 0 {
+		size = defaultPageSize
+	}
+
+	if len(result.Hits) < size || len(result.LastSort) == 0 {
+		if err := h.client.ClosePointInTime(ctx, pitID); err != nil {
+			h.logger.Println(err.Error())
+		}
+
+		return ""
+	}
+
+	next := &cursor{PitID: pitID, After: result.LastSort}
+	return next.String()
+}
+
+// containsAll reports whether every index in subset is also in indices, so a
+// template can only search the entities its endpoint is allowed to.
+func containsAll(indices, subset []string) bool {
+	for _, index := range subset {
+		if !slices.Contains(indices, index) {
+			return false
+		}
+	}
+
+	return true
+}
@@ -23,7 +23,12 @@ type mockPrepareQuery struct {
 }
 
 func (m *mockPrepareQuery) Fn(req *Request) ([]string, map[string]interface{}) {
-	return []string{}, m.Called(req).Get(0).(map[string]interface{})
+	args := m.Called(req)
+	indices := []string{}
+	if len(args) > 1 {
+		indices = args.Get(1).([]string)
+	}
+	return indices, args.Get(0).(map[string]interface{})
 }
 
 type SearchHandlerTestSuite struct {
@@ -177,6 +182,155 @@ func (suite *SearchHandlerTestSuite) Test_SearchWithAllParameters() {
 	suite.Equal(string(expectedJsonResponse), suite.RespBody())
 }
 
+func (suite *SearchHandlerTestSuite) Test_SearchWithTemplate() {
+	reqBody := `{"template":"person-by-uid","params":{"uid":"7000-0000-0001"}}`
+
+	_, searchBody := PrepareQueryForTemplate(&Request{
+		Template:       "person-by-uid",
+		templateParams: map[string]interface{}{"uid": "7000-0000-0001"},
+	})
+
+	suite.prepareQuery.
+		On("Fn", mock.Anything).
+		Return(map[string]interface{}{}, personIndices)
+
+	suite.esClient.
+		On("Search", mock.Anything, personIndices, searchBody).
+		Return(&elasticsearch.SearchResult{}, nil)
+
+	suite.ServeRequest(http.MethodPost, "", reqBody)
+
+	suite.Equal(http.StatusOK, suite.RespCode())
+}
+
+func (suite *SearchHandlerTestSuite) Test_SearchWithTemplateForOtherEntity() {
+	reqBody := `{"template":"person-by-uid","params":{"uid":"7000-0000-0001"}}`
+
+	suite.prepareQuery.
+		On("Fn", mock.Anything).
+		Return(map[string]interface{}{}, firmIndices)
+
+	suite.ServeRequest(http.MethodPost, "", reqBody)
+
+	suite.Equal(http.StatusBadRequest, suite.RespCode())
+	suite.Equal(`{"message":"request","errors":[{"name":"request","description":"template person-by-uid cannot be used on this endpoint"}]}`+"\n", suite.RespBody())
+	suite.esClient.AssertNotCalled(suite.T(), "Search", mock.Anything, mock.Anything, mock.Anything)
+}
+
+func (suite *SearchHandlerTestSuite) Test_UnknownIndices() {
+	suite.ServeRequest(http.MethodPost, "", `{"term":"x","indices":["firm","firm-*"]}`)
+
+	suite.Equal(http.StatusBadRequest, suite.RespCode())
+	suite.Equal(`{"message":"Some fields have failed validation","errors":[{"name":"indices[1]","description":"firm-* is not a known index"}]}`+"\n", suite.RespBody())
+}
+
+func (suite *SearchHandlerTestSuite) Test_SearchWithIndexTotals() {
+	searchBody := map[string]interface{}{"whatever": nil}
+
+	suite.prepareQuery.
+		On("Fn", mock.Anything).
+		Return(searchBody)
+
+	suite.esClient.
+		On("Search", mock.Anything, []string{}, searchBody).
+		Return(&elasticsearch.SearchResult{
+			Hits: []json.RawMessage{},
+			Aggregations: map[string]map[string]int{
+				"personType": {"firm": 2},
+				"indices":    {"firm_abc": 2, "person_abc": 4, "person_def": 1},
+			},
+			Total:      7,
+			TotalExact: true,
+		}, nil)
+
+	suite.ServeRequest(http.MethodPost, "", `{"term":"x"}`)
+
+	suite.Equal(http.StatusOK, suite.RespCode())
+	suite.Equal(`{"results":[],"aggregations":{"personType":{"firm":2}},"total":{"count":7,"exact":true,"indices":{"firm":2,"person":5}}}`, suite.RespBody())
+}
+
+func (suite *SearchHandlerTestSuite) Test_InvalidCursor() {
+	reqBody := `{"term":"test","cursor":"not-a-cursor"}`
+	suite.ServeRequest(http.MethodPost, "", reqBody)
+
+	suite.Equal(http.StatusBadRequest, suite.RespCode())
+	suite.Contains(suite.RespBody(), `"errors":[{"name":"request","description":"cursor is invalid"}]`)
+}
+
+func (suite *SearchHandlerTestSuite) Test_SearchPaginate() {
+	reqBody := `{"term":"testTerm","size":2,"paginate":true}`
+
+	suite.prepareQuery.
+		On("Fn", mock.Anything).
+		Return(map[string]interface{}{"from": 0, "size": 2})
+
+	suite.esClient.
+		On("OpenPointInTime", mock.Anything, []string{}, "5m").
+		Return("pit-1", nil)
+
+	result := &elasticsearch.SearchResult{
+		Hits: []json.RawMessage{
+			[]byte(`{"id":10}`),
+			[]byte(`{"id":20}`),
+		},
+		Total:      3,
+		TotalExact: true,
+		PitID:      "pit-2",
+		LastSort:   []interface{}{1.5, "20"},
+	}
+
+	suite.esClient.
+		On("Search", mock.Anything, []string(nil), map[string]interface{}{
+			"pit":  map[string]interface{}{"id": "pit-1", "keep_alive": "5m"},
+			"size": 2,
+		}).
+		Return(result, nil)
+
+	suite.ServeRequest(http.MethodPost, "", reqBody)
+
+	next := (&cursor{PitID: "pit-2", After: []interface{}{1.5, "20"}}).String()
+
+	suite.Equal(http.StatusOK, suite.RespCode())
+	suite.Equal(`{"results":[{"id":10},{"id":20}],"total":{"count":3,"exact":true},"next":"`+next+`"}`, suite.RespBody())
+}
+
+func (suite *SearchHandlerTestSuite) Test_SearchWithCursorLastPage() {
+	reqBody := `{"term":"testTerm","size":2,"cursor":"` + (&cursor{PitID: "pit-2", After: []interface{}{1.5, "20"}}).String() + `"}`
+
+	suite.prepareQuery.
+		On("Fn", mock.Anything).
+		Return(map[string]interface{}{"from": 0, "size": 2})
+
+	result := &elasticsearch.SearchResult{
+		Hits: []json.RawMessage{
+			[]byte(`{"id":30}`),
+		},
+		Total:      3,
+		TotalExact: true,
+		PitID:      "pit-3",
+		LastSort:   []interface{}{1.2, "30"},
+	}
+
+	suite.esClient.
+		On("Search", mock.Anything, []string(nil), map[string]interface{}{
+			"pit":          map[string]interface{}{"id": "pit-2", "keep_alive": "5m"},
+			"search_after": []interface{}{1.5, "20"},
+			"size":         2,
+		}).
+		Return(result, nil)
+
+	suite.esClient.
+		On("ClosePointInTime", mock.Anything, "pit-3").
+		Return(nil).
+		Once()
+
+	suite.ServeRequest(http.MethodPost, "", reqBody)
+
+	suite.Equal(http.StatusOK, suite.RespCode())
+	suite.Equal(`{"results":[{"id":30}],"total":{"count":3,"exact":true}}`, suite.RespBody())
+	suite.esClient.AssertExpectations(suite.T())
+}
+
 func TestSearchHandler(t *testing.T) {
 	suite.Run(t, new(SearchHandlerTestSuite))
 }
@@ -0,0 +1,80 @@
+package search
+
+import (
+	"fmt"
+	"strings"
+
+	"github.com/ministryofjustice/opg-search-service/internal/response"
+)
+
+const indicesAggregation = "indices"
+
+// validationError holds each problem found with a request, so that they can
+// all be reported at once
+type validationError []response.Error
+
+func (e validationError) Error() string {
+	descriptions := make([]string, len(e))
+	for i, err := range e {
+		descriptions[i] = err.Description
+	}
+
+	return strings.Join(descriptions, ", ")
+}
+
+// validateIndices checks that only the aliases of known indices have been
+// requested, rather than a concrete index or a wildcard
+func validateIndices(indices []string) error {
+	var errs validationError
+
+	for i, index := range indices {
+		if index == "" {
+			errs = append(errs, response.Error{
+				Name:        fmt.Sprintf("indices[%d]", i),
+				Description: "index cannot be empty",
+			})
+		} else if aliasOf(index) != index {
+			errs = append(errs, response.Error{
+				Name:        fmt.Sprintf("indices[%d]", i),
+				Description: fmt.Sprintf("%s is not a known index", index),
+			})
+		}
+	}
+
+	if len(errs) > 0 {
+		return errs
+	}
+
+	return nil
+}
+
+// aliasOf returns the alias that an index belongs to, indices are named as the
+// alias followed by a hash of their config
+func aliasOf(index string) string {
+	for _, alias := range allIndices {
+		if index == alias || strings.HasPrefix(index, alias+"_") {
+			return alias
+		}
+	}
+
+	return ""
+}
+
+// indexTotals takes the count of results for each index out of the
+// aggregations and returns them by alias
+func indexTotals(aggregations map[string]map[string]int) map[string]int {
+	counts, ok := aggregations[indicesAggregation]
+	if !ok {
+		return nil
+	}
+	delete(aggregations, indicesAggregation)
+
+	totals := map[string]int{}
+	for index, count := range counts {
+		if alias := aliasOf(index); alias != "" {
+			totals[alias] += count
+		}
+	}
+
+	return totals
+}
@@ -11,97 +11,113 @@ var firmIndices = []string{firm.AliasName}
 var personIndices = []string{person.AliasName}
 var allIndices = []string{firm.AliasName, person.AliasName, digitallpa.AliasName}
 
+// fields to return highlights for, as queries are mostly against "searchable"
+// these are the fields that are copied to it
+var firmHighlightFields = []string{"firmName", "firmNumber"}
+var personHighlightFields = []string{
+	"uId", "normalizedUid", "caseRecNumber", "deputyNumber", "dob",
+	"firstname", "middlenames", "surname", "previousnames", "othernames", "companyName", "className", "organisationName",
+	"phoneNumbers.phoneNumber", "addresses.addressLines", "addresses.postcode",
+	"cases.uId", "cases.normalizedUid", "cases.caseRecNumber", "cases.onlineLpaId", "cases.batchId", "cases.caseType", "cases.caseSubtype",
+}
+var deputyHighlightFields = []string{"firstname", "middlenames", "surname", "previousnames", "othernames", "organisationName"}
+var digitalLpaHighlightFields = []string{
+	"uId",
+	"donor.firstNames", "donor.surname", "donor.dob", "donor.address.*",
+	"certificateProvider.firstNames", "certificateProvider.surname", "certificateProvider.address.*",
+	"attorneys.firstNames", "attorneys.surname", "attorneys.dob", "attorneys.address.*",
+}
+var allHighlightFields = concat(firmHighlightFields, personHighlightFields, digitalLpaHighlightFields)
+
 func PrepareQueryForFirm(req *Request) ([]string, map[string]interface{}) {
 	body := map[string]interface{}{
-		"query": map[string]interface{}{
+		"query": withFilters(req, withDobRange(req, withStructured(req, map[string]interface{}{
 			"multi_match": map[string]interface{}{
 				"query":  req.Term,
 				"fields": []string{"firmName", "firmNumber"},
 			},
-		},
+		}, firmStructuredFields), nil), firmFacetFields),
 	}
 
-	return firmIndices, withDefaults(req, body)
+	return firmIndices, withHighlight(req, withFacets(req, withDefaults(req, body), firmFacetFields), firmHighlightFields)
 }
 
 func PrepareQueryForPerson(req *Request) ([]string, map[string]interface{}) {
-	if req.Prepared != nil {
-		return personIndices, req.Prepared
-	}
-
 	body := map[string]interface{}{
-		"query": map[string]interface{}{
-			"bool": map[string]interface{}{
-				"must": map[string]interface{}{
-					"simple_query_string": map[string]interface{}{
-						"query": req.Term,
-						"fields": []string{
-							"searchable",
-							"caseRecNumber",
-						},
-						"default_operator": "AND",
-					},
+		"query": withFilters(req, withDobRange(req, withStructured(req, withFuzzyNames(req, map[string]interface{}{
+			"simple_query_string": map[string]interface{}{
+				"query": req.Term,
+				"fields": []string{
+					"searchable",
+					"caseRecNumber",
+					// informal names only match the synonym fields, which have
+					// a lower boost so that exact names are ranked first
+					"firstname.synonym^0.5",
+					"middlenames.synonym^0.5",
+					"othernames.synonym^0.5",
 				},
+				"default_operator": "AND",
 			},
-		},
+		}), personStructuredFields), personDobFields), personFacetFields),
 	}
 
-	return personIndices, withDefaults(req, body)
+	return personIndices, withHighlight(req, withFacets(req, withDefaults(req, body), personFacetFields), personHighlightFields)
 }
 
 func PrepareQueryForDeputy(req *Request) ([]string, map[string]interface{}) {
 	body := map[string]interface{}{
-		"query": map[string]interface{}{
-			"bool": map[string]interface{}{
-				"must": map[string]interface{}{
-					"simple_query_string": map[string]interface{}{
-						"query": req.Term,
-						"fields": []string{
-							"firstname",
-							"middlenames",
-							"surname",
-							"previousnames",
-							"othernames",
-							"organisationName",
-						},
-						"default_operator": "AND",
-					},
+		"query": withFilters(req, withDobRange(req, withStructured(req, withFuzzyNames(req, map[string]interface{}{
+			"simple_query_string": map[string]interface{}{
+				"query": req.Term,
+				"fields": []string{
+					"firstname",
+					"middlenames",
+					"surname",
+					"previousnames",
+					"othernames",
+					"organisationName",
+					"firstname.synonym^0.5",
+					"middlenames.synonym^0.5",
+					"othernames.synonym^0.5",
 				},
+				"default_operator": "AND",
 			},
-		},
+		}), personStructuredFields), personDobFields), personFacetFields),
 	}
 
-	return personIndices, withDefaults(req, body)
+	return personIndices, withHighlight(req, withFacets(req, withDefaults(req, body), personFacetFields), deputyHighlightFields)
 }
 
 func PrepareQueryForDigitalLpa(req *Request) ([]string, map[string]interface{}) {
 	body := map[string]interface{}{
-		"query": map[string]interface{}{
+		"query": withFilters(req, withDobRange(req, withStructured(req, map[string]interface{}{
 			"bool": map[string]interface{}{
 				"must": map[string]interface{}{
 					"simple_query_string": map[string]interface{}{
 						"query": req.Term,
 						"fields": []string{
 							"searchable",
+							"donor.firstNames.synonym^0.5",
+							"attorneys.firstNames.synonym^0.5",
 						},
 						"default_operator": "AND",
 					},
 				},
 			},
-		},
+		}, digitalLpaStructuredFields), digitalLpaDobFields), digitalLpaFacetFields),
 	}
 
-	return digitalLpaIndices, withDefaults(req, body)
+	return digitalLpaIndices, withHighlight(req, withFacets(req, withDefaults(req, body), digitalLpaFacetFields), digitalLpaHighlightFields)
 }
 
 func PrepareQueryForAll(req *Request) ([]string, map[string]interface{}) {
 	body := map[string]interface{}{
-		"query": map[string]interface{}{
+		"query": withFilters(req, withDobRange(req, withStructured(req, map[string]interface{}{
 			"multi_match": map[string]interface{}{
 				"query":  req.Term,
 				"fields": []string{"firmName", "firmNumber", "caseRecNumber", "searchable"},
 			},
-		},
+		}, allStructuredFields), allDobFields), allFacetFields),
 	}
 
 	indices := allIndices
@@ -110,7 +126,24 @@ func PrepareQueryForAll(req *Request) ([]string, map[string]interface{}) {
 		indices = req.Indices
 	}
 
-	return indices, withDefaults(req, body)
+	body = withDefaults(req, body)
+
+	// counts the results from each index, so the total can be broken down.
+	// Aggregations are run before the post_filter, so it is applied here too
+	// for the counts to add up to the total.
+	body["aggs"].(map[string]interface{})[indicesAggregation] = map[string]interface{}{
+		"filter": body["post_filter"],
+		"aggs": map[string]interface{}{
+			indicesAggregation: map[string]interface{}{
+				"terms": map[string]interface{}{
+					"field": "_index",
+					"size":  "20",
+				},
+			},
+		},
+	}
+
+	return indices, withHighlight(req, withFacets(req, body, allFacetFields), allHighlightFields)
 }
 
 func withDefaults(req *Request, body map[string]interface{}) map[string]interface{} {
@@ -145,3 +178,77 @@ func withDefaults(req *Request, body map[string]interface{}) map[string]interfac
 
 	return body
 }
+
+// withFuzzyNames wraps the exact query for a person, when a fuzzy search is
+// requested it also matches names that are spelled similarly to the term but
+// boosts exact matches so they are ranked first
+func withFuzzyNames(req *Request, exact map[string]interface{}) map[string]interface{} {
+	if !req.Fuzzy {
+		return map[string]interface{}{
+			"bool": map[string]interface{}{
+				"must": exact,
+			},
+		}
+	}
+
+	return map[string]interface{}{
+		"bool": map[string]interface{}{
+			"should": []interface{}{
+				map[string]interface{}{
+					"bool": map[string]interface{}{
+						"must":  exact,
+						"boost": 10,
+					},
+				},
+				map[string]interface{}{
+					"match": map[string]interface{}{
+						"names": map[string]interface{}{
+							"query":     req.Term,
+							"fuzziness": "AUTO",
+							"operator":  "AND",
+							"boost":     2,
+						},
+					},
+				},
+				map[string]interface{}{
+					"match": map[string]interface{}{
+						"names.ngram": map[string]interface{}{
+							"query":                req.Term,
+							"minimum_should_match": "75%",
+						},
+					},
+				},
+			},
+			"minimum_should_match": 1,
+		},
+	}
+}
+
+func withHighlight(req *Request, body map[string]interface{}, fields []string) map[string]interface{} {
+	if !req.Highlight {
+		return body
+	}
+
+	highlightFields := map[string]interface{}{}
+	for _, field := range fields {
+		highlightFields[field] = map[string]interface{}{}
+	}
+
+	body["highlight"] = map[string]interface{}{
+		// the query matches against "searchable" so the fields themselves
+		// will not have been queried directly
+		"require_field_match": false,
+		"fields":              highlightFields,
+	}
+
+	return body
+}
+
+func concat(xs ...[]string) []string {
+	var result []string
+	for _, x := range xs {
+		result = append(result, x...)
+	}
+
+	return result
+}
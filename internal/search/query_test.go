@@ -102,6 +102,9 @@ func TestPrepareQueryForPerson(t *testing.T) {
 						"fields": []string{
 							"searchable",
 							"caseRecNumber",
+							"firstname.synonym^0.5",
+							"middlenames.synonym^0.5",
+							"othernames.synonym^0.5",
 						},
 						"default_operator": "AND",
 					},
@@ -146,6 +149,9 @@ func TestPrepareQueryForPersonWithOptions(t *testing.T) {
 						"fields": []string{
 							"searchable",
 							"caseRecNumber",
+							"firstname.synonym^0.5",
+							"middlenames.synonym^0.5",
+							"othernames.synonym^0.5",
 						},
 						"default_operator": "AND",
 					},
@@ -175,6 +181,410 @@ func TestPrepareQueryForPersonWithOptions(t *testing.T) {
 	assert.Equal(t, []string{person.AliasName}, indices)
 }
 
+func TestPrepareQueryForPersonFuzzy(t *testing.T) {
+	req := &Request{
+		Term:  "Smyth",
+		Fuzzy: true,
+	}
+
+	indices, body := PrepareQueryForPerson(req)
+
+	assert.Equal(t, map[string]interface{}{
+		"bool": map[string]interface{}{
+			"should": []interface{}{
+				map[string]interface{}{
+					"bool": map[string]interface{}{
+						"must": map[string]interface{}{
+							"simple_query_string": map[string]interface{}{
+								"query": "Smyth",
+								"fields": []string{
+									"searchable",
+									"caseRecNumber",
+									"firstname.synonym^0.5",
+									"middlenames.synonym^0.5",
+									"othernames.synonym^0.5",
+								},
+								"default_operator": "AND",
+							},
+						},
+						"boost": 10,
+					},
+				},
+				map[string]interface{}{
+					"match": map[string]interface{}{
+						"names": map[string]interface{}{
+							"query":     "Smyth",
+							"fuzziness": "AUTO",
+							"operator":  "AND",
+							"boost":     2,
+						},
+					},
+				},
+				map[string]interface{}{
+					"match": map[string]interface{}{
+						"names.ngram": map[string]interface{}{
+							"query":                "Smyth",
+							"minimum_should_match": "75%",
+						},
+					},
+				},
+			},
+			"minimum_should_match": 1,
+		},
+	}, body["query"])
+
+	assert.Equal(t, []string{person.AliasName}, indices)
+}
+
+func TestPrepareQueryForDeputyFuzzy(t *testing.T) {
+	req := &Request{
+		Term:  "Nikko",
+		Fuzzy: true,
+	}
+
+	_, body := PrepareQueryForDeputy(req)
+
+	should := body["query"].(map[string]interface{})["bool"].(map[string]interface{})["should"].([]interface{})
+	assert.Len(t, should, 3)
+	assert.Equal(t, map[string]interface{}{
+		"bool": map[string]interface{}{
+			"must": map[string]interface{}{
+				"simple_query_string": map[string]interface{}{
+					"query": "Nikko",
+					"fields": []string{
+						"firstname", "middlenames", "surname", "previousnames", "othernames", "organisationName",
+						"firstname.synonym^0.5", "middlenames.synonym^0.5", "othernames.synonym^0.5",
+					},
+					"default_operator": "AND",
+				},
+			},
+			"boost": 10,
+		},
+	}, should[0])
+}
+
+func TestPrepareQueryForPersonStructured(t *testing.T) {
+	req := &Request{
+		Term: "apples",
+		Structured: &Structured{
+			Surname:  "Smith",
+			Postcode: "NG1 2CD",
+		},
+	}
+
+	_, body := PrepareQueryForPerson(req)
+
+	assert.Equal(t, map[string]interface{}{
+		"bool": map[string]interface{}{
+			"must": []interface{}{
+				map[string]interface{}{
+					"bool": map[string]interface{}{
+						"must": map[string]interface{}{
+							"simple_query_string": map[string]interface{}{
+								"query": "apples",
+								"fields": []string{
+									"searchable",
+									"caseRecNumber",
+									"firstname.synonym^0.5",
+									"middlenames.synonym^0.5",
+									"othernames.synonym^0.5",
+								},
+								"default_operator": "AND",
+							},
+						},
+					},
+				},
+				map[string]interface{}{
+					"multi_match": map[string]interface{}{
+						"query":    "Smith",
+						"fields":   []string{"surname"},
+						"operator": "AND",
+					},
+				},
+				map[string]interface{}{
+					"multi_match": map[string]interface{}{
+						"query":    "NG1 2CD",
+						"fields":   []string{"addresses.postcode"},
+						"operator": "AND",
+					},
+				},
+			},
+		},
+	}, body["query"])
+}
+
+func TestPrepareQueryForFirmStructuredOnly(t *testing.T) {
+	req := &Request{
+		Structured: &Structured{
+			Surname:  "Smith",
+			Postcode: "NG1 2CD",
+		},
+	}
+
+	_, body := PrepareQueryForFirm(req)
+
+	assert.Equal(t, map[string]interface{}{
+		"bool": map[string]interface{}{
+			"must": []interface{}{
+				map[string]interface{}{"match_none": map[string]interface{}{}},
+				map[string]interface{}{
+					"multi_match": map[string]interface{}{
+						"query":    "NG1 2CD",
+						"fields":   []string{"postcode"},
+						"operator": "AND",
+					},
+				},
+			},
+		},
+	}, body["query"])
+}
+
+func TestPrepareQueryForPersonWithDobRange(t *testing.T) {
+	req := &Request{
+		Term:    "apples",
+		DobFrom: "1940-01-01",
+		DobTo:   "1950-12-31",
+	}
+
+	_, body := PrepareQueryForPerson(req)
+
+	assert.Equal(t, map[string]interface{}{
+		"bool": map[string]interface{}{
+			"must": map[string]interface{}{
+				"bool": map[string]interface{}{
+					"must": map[string]interface{}{
+						"simple_query_string": map[string]interface{}{
+							"query": "apples",
+							"fields": []string{
+								"searchable",
+								"caseRecNumber",
+								"firstname.synonym^0.5",
+								"middlenames.synonym^0.5",
+								"othernames.synonym^0.5",
+							},
+							"default_operator": "AND",
+						},
+					},
+				},
+			},
+			"filter": map[string]interface{}{
+				"bool": map[string]interface{}{
+					"should": []interface{}{
+						map[string]interface{}{
+							"range": map[string]interface{}{
+								"dob.date": map[string]interface{}{
+									"format": "yyyy-MM-dd",
+									"gte":    "1940-01-01",
+									"lte":    "1950-12-31",
+								},
+							},
+						},
+					},
+					"minimum_should_match": 1,
+				},
+			},
+		},
+	}, body["query"])
+}
+
+func TestPrepareQueryForDigitalLpaWithDobFrom(t *testing.T) {
+	req := &Request{
+		Term:    "apples",
+		DobFrom: "1948-01-01",
+	}
+
+	_, body := PrepareQueryForDigitalLpa(req)
+
+	assert.Equal(t, map[string]interface{}{
+		"bool": map[string]interface{}{
+			"should": []interface{}{
+				map[string]interface{}{
+					"range": map[string]interface{}{
+						"donor.dob.date": map[string]interface{}{
+							"format": "yyyy-MM-dd",
+							"gte":    "1948-01-01",
+						},
+					},
+				},
+				map[string]interface{}{
+					"range": map[string]interface{}{
+						"attorneys.dob.date": map[string]interface{}{
+							"format": "yyyy-MM-dd",
+							"gte":    "1948-01-01",
+						},
+					},
+				},
+			},
+			"minimum_should_match": 1,
+		},
+	}, body["query"].(map[string]interface{})["bool"].(map[string]interface{})["filter"])
+}
+
+func TestPrepareQueryForPersonWithFiltersAndFacets(t *testing.T) {
+	req := &Request{
+		Term: "apples",
+		Filters: map[string][]string{
+			"cases.caseType": {"ORDER"},
+			"lpaType":        {"hw"},
+		},
+		Facets: []string{"cases.caseSubtype", "town"},
+	}
+
+	_, body := PrepareQueryForPerson(req)
+
+	assert.Equal(t, []interface{}{
+		map[string]interface{}{
+			"terms": map[string]interface{}{
+				"cases.caseType": []string{"ORDER"},
+			},
+		},
+		map[string]interface{}{"match_none": map[string]interface{}{}},
+	}, body["query"].(map[string]interface{})["bool"].(map[string]interface{})["filter"])
+
+	assert.Equal(t, map[string]interface{}{
+		"personType": map[string]interface{}{
+			"terms": map[string]interface{}{
+				"field": "personType",
+				"size":  "20",
+			},
+		},
+		"cases.caseSubtype": map[string]interface{}{
+			"terms": map[string]interface{}{
+				"field": "cases.caseSubtype",
+				"size":  "20",
+			},
+		},
+	}, body["aggs"])
+}
+
+func TestPrepareQueryForDigitalLpaWithFiltersAndFacets(t *testing.T) {
+	req := &Request{
+		Term:    "apples",
+		Filters: map[string][]string{"lpaType": {"hw", "pfa"}},
+		Facets:  []string{"lpaType"},
+	}
+
+	_, body := PrepareQueryForDigitalLpa(req)
+
+	assert.Equal(t, []interface{}{
+		map[string]interface{}{
+			"terms": map[string]interface{}{
+				"lpaType.keyword": []string{"hw", "pfa"},
+			},
+		},
+	}, body["query"].(map[string]interface{})["bool"].(map[string]interface{})["filter"])
+
+	assert.Equal(t, map[string]interface{}{
+		"terms": map[string]interface{}{
+			"field": "lpaType.keyword",
+			"size":  "20",
+		},
+	}, body["aggs"].(map[string]interface{})["lpaType"])
+}
+
+func TestPrepareQueryForPersonWithHighlight(t *testing.T) {
+	req := &Request{
+		Term:      "apples",
+		Highlight: true,
+	}
+
+	_, body := PrepareQueryForPerson(req)
+
+	assert.Equal(t, map[string]interface{}{
+		"require_field_match": false,
+		"fields": map[string]interface{}{
+			"uId":                      map[string]interface{}{},
+			"normalizedUid":            map[string]interface{}{},
+			"caseRecNumber":            map[string]interface{}{},
+			"deputyNumber":             map[string]interface{}{},
+			"dob":                      map[string]interface{}{},
+			"firstname":                map[string]interface{}{},
+			"middlenames":              map[string]interface{}{},
+			"surname":                  map[string]interface{}{},
+			"previousnames":            map[string]interface{}{},
+			"othernames":               map[string]interface{}{},
+			"companyName":              map[string]interface{}{},
+			"className":                map[string]interface{}{},
+			"organisationName":         map[string]interface{}{},
+			"phoneNumbers.phoneNumber": map[string]interface{}{},
+			"addresses.addressLines":   map[string]interface{}{},
+			"addresses.postcode":       map[string]interface{}{},
+			"cases.uId":                map[string]interface{}{},
+			"cases.normalizedUid":      map[string]interface{}{},
+			"cases.caseRecNumber":      map[string]interface{}{},
+			"cases.onlineLpaId":        map[string]interface{}{},
+			"cases.batchId":            map[string]interface{}{},
+			"cases.caseType":           map[string]interface{}{},
+			"cases.caseSubtype":        map[string]interface{}{},
+		},
+	}, body["highlight"])
+}
+
+func TestPrepareQueryForDeputyWithHighlight(t *testing.T) {
+	req := &Request{
+		Term:      "Niko",
+		Highlight: true,
+	}
+
+	_, body := PrepareQueryForDeputy(req)
+
+	assert.Equal(t, map[string]interface{}{
+		"require_field_match": false,
+		"fields": map[string]interface{}{
+			"firstname":        map[string]interface{}{},
+			"middlenames":      map[string]interface{}{},
+			"surname":          map[string]interface{}{},
+			"previousnames":    map[string]interface{}{},
+			"othernames":       map[string]interface{}{},
+			"organisationName": map[string]interface{}{},
+		},
+	}, body["highlight"])
+}
+
+func TestPrepareQueryForFirmWithHighlight(t *testing.T) {
+	req := &Request{
+		Term:      "apples",
+		Highlight: true,
+	}
+
+	_, body := PrepareQueryForFirm(req)
+
+	assert.Equal(t, map[string]interface{}{
+		"require_field_match": false,
+		"fields": map[string]interface{}{
+			"firmName":   map[string]interface{}{},
+			"firmNumber": map[string]interface{}{},
+		},
+	}, body["highlight"])
+}
+
+func TestPrepareQueryForDigitalLpaWithHighlight(t *testing.T) {
+	req := &Request{
+		Term:      "MMMoooossssa",
+		Highlight: true,
+	}
+
+	_, body := PrepareQueryForDigitalLpa(req)
+
+	assert.Equal(t, map[string]interface{}{
+		"require_field_match": false,
+		"fields": map[string]interface{}{
+			"uId":                            map[string]interface{}{},
+			"donor.firstNames":               map[string]interface{}{},
+			"donor.surname":                  map[string]interface{}{},
+			"donor.dob":                      map[string]interface{}{},
+			"donor.address.*":                map[string]interface{}{},
+			"certificateProvider.firstNames": map[string]interface{}{},
+			"certificateProvider.surname":    map[string]interface{}{},
+			"certificateProvider.address.*":  map[string]interface{}{},
+			"attorneys.firstNames":           map[string]interface{}{},
+			"attorneys.surname":              map[string]interface{}{},
+			"attorneys.dob":                  map[string]interface{}{},
+			"attorneys.address.*":            map[string]interface{}{},
+		},
+	}, body["highlight"])
+}
+
 func TestPrepareQueryForDigitalLpa(t *testing.T) {
 	req := &Request{
 		Term: "MMMoooossssa",
@@ -191,6 +601,8 @@ func TestPrepareQueryForDigitalLpa(t *testing.T) {
 						"query": "MMMoooossssa",
 						"fields": []string{
 							"searchable",
+							"donor.firstNames.synonym^0.5",
+							"attorneys.firstNames.synonym^0.5",
 						},
 						"default_operator": "AND",
 					},
@@ -216,22 +628,6 @@ func TestPrepareQueryForDigitalLpa(t *testing.T) {
 	assert.Equal(t, []string{digitallpa.AliasName}, indices)
 }
 
-func TestPrepareQueryForPersonAlreadyPrepared(t *testing.T) {
-	req := &Request{
-		Prepared: map[string]interface{}{
-			"query": "some prepared query",
-		},
-	}
-
-	indices, body := PrepareQueryForPerson(req)
-
-	assert.Equal(t, map[string]interface{}{
-		"query": "some prepared query",
-	}, body)
-
-	assert.Equal(t, []string{person.AliasName}, indices)
-}
-
 func TestPrepareQueryForDeputy(t *testing.T) {
 	req := &Request{
 		Term: "Niko",
@@ -248,6 +644,7 @@ func TestPrepareQueryForDeputy(t *testing.T) {
 						"query": "Niko",
 						"fields": []string{
 							"firstname", "middlenames", "surname", "previousnames", "othernames", "organisationName",
+							"firstname.synonym^0.5", "middlenames.synonym^0.5", "othernames.synonym^0.5",
 						},
 						"default_operator": "AND",
 					},
@@ -295,6 +692,17 @@ func TestPrepareQueryForAll(t *testing.T) {
 					"size":  "20",
 				},
 			},
+			"indices": map[string]interface{}{
+				"filter": map[string]interface{}{"bool": map[string]interface{}{"should": []interface{}{}}},
+				"aggs": map[string]interface{}{
+					"indices": map[string]interface{}{
+						"terms": map[string]interface{}{
+							"field": "_index",
+							"size":  "20",
+						},
+					},
+				},
+			},
 		},
 		"post_filter": map[string]interface{}{"bool": map[string]interface{}{"should": []interface{}{}}},
 		"sort": []interface{}{
@@ -330,6 +738,17 @@ func TestPrepareQueryForAllEmptyIndices(t *testing.T) {
 					"size":  "20",
 				},
 			},
+			"indices": map[string]interface{}{
+				"filter": map[string]interface{}{"bool": map[string]interface{}{"should": []interface{}{}}},
+				"aggs": map[string]interface{}{
+					"indices": map[string]interface{}{
+						"terms": map[string]interface{}{
+							"field": "_index",
+							"size":  "20",
+						},
+					},
+				},
+			},
 		},
 		"post_filter": map[string]interface{}{"bool": map[string]interface{}{"should": []interface{}{}}},
 		"sort": []interface{}{
@@ -367,6 +786,20 @@ func TestPrepareQueryForAllWithOptions(t *testing.T) {
 					"size":  "20",
 				},
 			},
+			"indices": map[string]interface{}{
+				"filter": map[string]interface{}{"bool": map[string]interface{}{"should": []interface{}{
+					map[string]interface{}{"term": map[string]string{"personType": "deputy"}},
+					map[string]interface{}{"term": map[string]string{"personType": "donor"}},
+				}}},
+				"aggs": map[string]interface{}{
+					"indices": map[string]interface{}{
+						"terms": map[string]interface{}{
+							"field": "_index",
+							"size":  "20",
+						},
+					},
+				},
+			},
 		},
 		"post_filter": map[string]interface{}{"bool": map[string]interface{}{"should": []interface{}{
 			map[string]interface{}{"term": map[string]string{"personType": "deputy"}},
@@ -382,3 +815,23 @@ func TestPrepareQueryForAllWithOptions(t *testing.T) {
 
 	assert.Equal(t, []string{firm.AliasName, person.AliasName}, indices)
 }
+
+func TestPrepareQueryForAllTotalsUseFilters(t *testing.T) {
+	req := &Request{
+		Term:        "apples",
+		PersonTypes: []string{"donor"},
+		Filters:     map[string][]string{"cases.caseType": {"order"}},
+	}
+
+	_, body := PrepareQueryForAll(req)
+
+	// filters in the query apply to aggregations already, the post_filter
+	// must be applied to the per-index totals for them to match the total
+	totals := body["aggs"].(map[string]interface{})["indices"].(map[string]interface{})
+	assert.Equal(t, body["post_filter"], totals["filter"])
+
+	query := body["query"].(map[string]interface{})["bool"].(map[string]interface{})
+	assert.Equal(t, []interface{}{
+		map[string]interface{}{"terms": map[string]interface{}{"cases.caseType": []string{"order"}}},
+	}, query["filter"])
+}
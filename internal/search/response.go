@@ -6,9 +6,13 @@ type Response struct {
 	Results      []json.RawMessage         `json:"results"`
 	Aggregations map[string]map[string]int `json:"aggregations,omitempty"`
 	Total        ResponseTotal             `json:"total"`
+	Next         string                    `json:"next,omitempty"`
 }
 
 type ResponseTotal struct {
 	Count int  `json:"count"`
 	Exact bool `json:"exact"`
+	// Indices breaks down Count by the index that results came from, before
+	// person types are filtered, when searching multiple indices
+	Indices map[string]int `json:"indices,omitempty"`
 }
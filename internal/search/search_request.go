@@ -4,6 +4,7 @@ import (
 	"bytes"
 	"encoding/json"
 	"errors"
+	"fmt"
 	"log"
 	"net/http"
 	"regexp"
@@ -11,12 +12,37 @@ import (
 )
 
 type Request struct {
-	Term        string                 `json:"term"`
-	Size        int                    `json:"size,omitempty"`
-	From        int                    `json:"from"`
-	PersonTypes []string               `json:"person_types"`
-	Prepared    map[string]interface{} `json:"prepared"`
-	Indices    []string                `json:"indices"`
+	Term        string   `json:"term"`
+	Size        int      `json:"size,omitempty"`
+	From        int      `json:"from"`
+	PersonTypes []string `json:"person_types"`
+	Indices     []string `json:"indices"`
+	// Template is the name of a query template to run instead of matching
+	// Term, with Params as its parameters
+	Template string                 `json:"template,omitempty"`
+	Params   map[string]interface{} `json:"params,omitempty"`
+	// Structured values are matched against the relevant fields of an entity
+	Structured *Structured `json:"structured,omitempty"`
+	// DobFrom and DobTo limit results to a range of dates of birth, they can
+	// be given as YYYY, YYYY-MM or YYYY-MM-DD
+	DobFrom string `json:"dobFrom,omitempty"`
+	DobTo   string `json:"dobTo,omitempty"`
+	// Filters limit results to those with one of the values given for each
+	// field, Facets are the fields to return counts of values for
+	Filters map[string][]string `json:"filters,omitempty"`
+	Facets  []string            `json:"facets,omitempty"`
+	// Fuzzy also matches person names that are spelled similarly to the term
+	Fuzzy bool `json:"fuzzy,omitempty"`
+	// Highlight requests the fragments of each result that matched the term
+	Highlight bool `json:"highlight,omitempty"`
+	// Paginate starts paging through results with a cursor, rather than From
+	Paginate bool `json:"paginate,omitempty"`
+	// Cursor is the "next" value from a previous response, the rest of the
+	// request should be the same as the one that returned it
+	Cursor string `json:"cursor,omitempty"`
+
+	cursor         *cursor
+	templateParams map[string]interface{}
 }
 
 func parseSearchRequest(r *http.Request) (*Request, error) {
@@ -35,18 +61,66 @@ func parseSearchRequest(r *http.Request) (*Request, error) {
 
 	req.sanitise()
 
-	if req.Term == "" && req.Prepared == nil {
+	if req.DobFrom != "" {
+		if req.DobFrom, err = parseDob(req.DobFrom, false); err != nil {
+			return nil, fmt.Errorf("dobFrom: %v", err)
+		}
+	}
+
+	if req.DobTo != "" {
+		if req.DobTo, err = parseDob(req.DobTo, true); err != nil {
+			return nil, fmt.Errorf("dobTo: %v", err)
+		}
+	}
+
+	if req.Template != "" {
+		if err := req.validateTemplate(); err != nil {
+			return nil, err
+		}
+	}
+
+	if err := validateIndices(req.Indices); err != nil {
+		return nil, err
+	}
+
+	if err := req.validateFacets(); err != nil {
+		return nil, err
+	}
+
+	if req.Cursor != "" {
+		req.cursor, err = decodeCursor(req.Cursor)
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	if req.Term == "" && req.Template == "" && len(req.Structured.values()) == 0 {
 		return nil, errors.New("search term is required and cannot be empty")
 	}
 
 	return &req, nil
 }
 
+var disallowedChars = regexp.MustCompile(`[^’'\p{L}\d\-.@ \/_]`)
+
+func clean(s string) string {
+	return strings.TrimSpace(disallowedChars.ReplaceAllString(s, ""))
+}
+
 func (sr *Request) sanitise() {
-	re := regexp.MustCompile(`[^’'\p{L}\d\-.@ \/_]`)
-	sr.Term = strings.TrimSpace(re.ReplaceAllString(sr.Term, ""))
+	sr.Term = clean(sr.Term)
 
 	for i, val := range sr.PersonTypes {
-		sr.PersonTypes[i] = strings.TrimSpace(re.ReplaceAllString(val, ""))
+		sr.PersonTypes[i] = clean(val)
+	}
+
+	for name, values := range sr.Filters {
+		for i, val := range values {
+			sr.Filters[name][i] = clean(val)
+		}
+	}
+
+	if sr.Structured != nil {
+		sr.Structured.sanitise(clean)
 	}
 }
@@ -42,13 +42,45 @@ func TestCreateSearchRequestFromRequest(t *testing.T) {
 			nil,
 		},
 		{
-			"create from a prepared request",
-			`{"prepared":{"query":"some prepared query"}}`,
+			"create from a template request",
+			`{"template":"deputy-by-deputy-number","params":{"deputyNumber":12345}}`,
 			nil,
 			&Request{
-				Prepared: map[string]interface{}{"query": "some prepared query"},
+				Template:       "deputy-by-deputy-number",
+				Params:         map[string]interface{}{"deputyNumber": float64(12345)},
+				templateParams: map[string]interface{}{"deputyNumber": 12345},
 			},
 		},
+		{
+			"create from a template that does not exist",
+			`{"template":"everything"}`,
+			errors.New("template everything does not exist"),
+			nil,
+		},
+		{
+			"create from a template with a missing parameter",
+			`{"template":"person-by-uid"}`,
+			errors.New("template person-by-uid requires parameter uid"),
+			nil,
+		},
+		{
+			"create from a template with an unknown parameter",
+			`{"template":"person-by-uid","params":{"uid":"7000-0000-0001","script":"x"}}`,
+			errors.New("template person-by-uid does not take parameter script"),
+			nil,
+		},
+		{
+			"create from a template with an invalid parameter",
+			`{"template":"person-by-uid","params":{"uid":"7000"}}`,
+			errors.New("parameter uid must be a UID in the format 0000-0000-0000"),
+			nil,
+		},
+		{
+			"create from a prepared request",
+			`{"prepared":{"query":"some prepared query"}}`,
+			errors.New("search term is required and cannot be empty"),
+			nil,
+		},
 		{
 			"created request is sanitised",
 			`{"term":"R'ené_8 D’!Eath-Smi/the()","size":1,"from":2,"person_types":["firm","person"]}`,
@@ -72,6 +104,94 @@ func TestCreateSearchRequestFromRequest(t *testing.T) {
 				Indices: []string{"person", "firm"},
 			},
 		},
+		{
+			"created request can include a cursor",
+			`{"term":"Vega","cursor":"eyJwaXQiOiJhYmMiLCJhZnRlciI6WzEsIjIiXX0"}`,
+			nil,
+			&Request{
+				Term:   "Vega",
+				Cursor: "eyJwaXQiOiJhYmMiLCJhZnRlciI6WzEsIjIiXX0",
+				cursor: &cursor{PitID: "abc", After: []interface{}{float64(1), "2"}},
+			},
+		},
+		{
+			"create from a structured request without a term",
+			`{"structured":{"surname":"Sm!ith","dob":"01/02/1990"}}`,
+			nil,
+			&Request{
+				Structured: &Structured{
+					Surname: "Smith",
+					Dob:     "01/02/1990",
+				},
+			},
+		},
+		{
+			"create from an empty structured request",
+			`{"structured":{"surname":" "}}`,
+			errors.New("search term is required and cannot be empty"),
+			nil,
+		},
+		{
+			"created request expands partial dates of birth",
+			`{"term":"Vega","dobFrom":"1940","dobTo":"1950-02"}`,
+			nil,
+			&Request{
+				Term:    "Vega",
+				DobFrom: "1940-01-01",
+				DobTo:   "1950-02-28",
+			},
+		},
+		{
+			"create with an invalid date of birth",
+			`{"term":"Vega","dobFrom":"01/02/1940"}`,
+			errors.New("dobFrom: 01/02/1940 is not a date in the format YYYY, YYYY-MM or YYYY-MM-DD"),
+			nil,
+		},
+		{
+			"created request can include filters and facets",
+			`{"term":"Vega","filters":{"cases.caseType":[" HW! "]},"facets":["className"]}`,
+			nil,
+			&Request{
+				Term:    "Vega",
+				Filters: map[string][]string{"cases.caseType": {"HW"}},
+				Facets:  []string{"className"},
+			},
+		},
+		{
+			"create with a filter that is not allowed",
+			`{"term":"Vega","filters":{"surname":["Smith"]}}`,
+			errors.New("cannot filter on surname"),
+			nil,
+		},
+		{
+			"create with a facet that is not allowed",
+			`{"term":"Vega","facets":["surname"]}`,
+			errors.New("cannot facet on surname"),
+			nil,
+		},
+		{
+			"create with unknown indices",
+			`{"term":"Vega","indices":["person","person_abc123","*"]}`,
+			validationError{
+				{Name: "indices[1]", Description: "person_abc123 is not a known index"},
+				{Name: "indices[2]", Description: "* is not a known index"},
+			},
+			nil,
+		},
+		{
+			"create with an empty index",
+			`{"term":"Vega","indices":["","person"]}`,
+			validationError{
+				{Name: "indices[0]", Description: "index cannot be empty"},
+			},
+			nil,
+		},
+		{
+			"create with an invalid cursor",
+			`{"term":"Vega","cursor":"e30"}`,
+			errors.New("cursor is invalid"),
+			nil,
+		},
 	}
 	for _, test := range tests {
 		req := http.Request{
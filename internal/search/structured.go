@@ -0,0 +1,130 @@
+package search
+
+// Structured holds search values that are matched against specific fields,
+// rather than against everything like Term
+type Structured struct {
+	Surname       string `json:"surname,omitempty"`
+	Firstname     string `json:"firstname,omitempty"`
+	Dob           string `json:"dob,omitempty"`
+	Postcode      string `json:"postcode,omitempty"`
+	CaseRecNumber string `json:"caseRecNumber,omitempty"`
+	UID           string `json:"uId,omitempty"`
+	PhoneNumber   string `json:"phoneNumber,omitempty"`
+	Email         string `json:"email,omitempty"`
+}
+
+type structuredValue struct {
+	key, value string
+}
+
+// values returns the set values in a fixed order, so that prepared queries
+// are stable
+func (s *Structured) values() []structuredValue {
+	var values []structuredValue
+	if s == nil {
+		return values
+	}
+
+	for _, v := range []structuredValue{
+		{"surname", s.Surname},
+		{"firstname", s.Firstname},
+		{"dob", s.Dob},
+		{"postcode", s.Postcode},
+		{"caseRecNumber", s.CaseRecNumber},
+		{"uId", s.UID},
+		{"phoneNumber", s.PhoneNumber},
+		{"email", s.Email},
+	} {
+		if v.value != "" {
+			values = append(values, v)
+		}
+	}
+
+	return values
+}
+
+func (s *Structured) sanitise(clean func(string) string) {
+	s.Surname = clean(s.Surname)
+	s.Firstname = clean(s.Firstname)
+	s.Dob = clean(s.Dob)
+	s.Postcode = clean(s.Postcode)
+	s.CaseRecNumber = clean(s.CaseRecNumber)
+	s.UID = clean(s.UID)
+	s.PhoneNumber = clean(s.PhoneNumber)
+	s.Email = clean(s.Email)
+}
+
+// the fields of each entity that a structured value is matched against, where
+// an entity has no fields for a value it cannot match
+var personStructuredFields = map[string][]string{
+	"surname":       {"surname"},
+	"firstname":     {"firstname", "firstname.synonym^0.5"},
+	"dob":           {"dob"},
+	"postcode":      {"addresses.postcode"},
+	"caseRecNumber": {"caseRecNumber", "cases.caseRecNumber"},
+	"uId":           {"uId", "cases.uId"},
+	"phoneNumber":   {"phoneNumbers.phoneNumber"},
+	"email":         {"email"},
+}
+
+var firmStructuredFields = map[string][]string{
+	"postcode":    {"postcode"},
+	"phoneNumber": {"phoneNumber"},
+	"email":       {"email"},
+}
+
+var digitalLpaStructuredFields = map[string][]string{
+	"surname":   {"donor.surname", "certificateProvider.surname", "attorneys.surname"},
+	"firstname": {"donor.firstNames", "certificateProvider.firstNames", "attorneys.firstNames", "donor.firstNames.synonym^0.5", "attorneys.firstNames.synonym^0.5"},
+	"dob":       {"donor.dob", "attorneys.dob"},
+	"postcode":  {"donor.address.postcode", "certificateProvider.address.postcode", "attorneys.address.postcode"},
+	"uId":       {"uId"},
+}
+
+var allStructuredFields = mergeStructuredFields(personStructuredFields, firmStructuredFields, digitalLpaStructuredFields)
+
+func mergeStructuredFields(xs ...map[string][]string) map[string][]string {
+	result := map[string][]string{}
+	for _, x := range xs {
+		for k, fields := range x {
+			result[k] = append(result[k], fields...)
+		}
+	}
+
+	return result
+}
+
+// withStructured combines the free text query with a clause for each of the
+// structured values, all of which must match
+func withStructured(req *Request, query map[string]interface{}, fields map[string][]string) map[string]interface{} {
+	values := req.Structured.values()
+	if len(values) == 0 {
+		return query
+	}
+
+	must := []interface{}{}
+	if req.Term != "" {
+		must = append(must, query)
+	}
+
+	for _, v := range values {
+		if len(fields[v.key]) == 0 {
+			must = append(must, map[string]interface{}{"match_none": map[string]interface{}{}})
+			continue
+		}
+
+		must = append(must, map[string]interface{}{
+			"multi_match": map[string]interface{}{
+				"query":    v.value,
+				"fields":   fields[v.key],
+				"operator": "AND",
+			},
+		})
+	}
+
+	return map[string]interface{}{
+		"bool": map[string]interface{}{
+			"must": must,
+		},
+	}
+}
@@ -0,0 +1,225 @@
+package search
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"log"
+	"net/http"
+	"strings"
+	"time"
+
+	"github.com/ministryofjustice/opg-search-service/internal/autocomplete"
+	"github.com/ministryofjustice/opg-search-service/internal/digitallpa"
+	"github.com/ministryofjustice/opg-search-service/internal/firm"
+	"github.com/ministryofjustice/opg-search-service/internal/person"
+	"github.com/sirupsen/logrus"
+)
+
+const (
+	defaultSuggestSize = 5
+	maxSuggestSize     = 20
+)
+
+// the fields needed to build a suggestion, so that the rest of the source is
+// not returned
+var suggestSourceFields = []string{
+	"id", "uId", "caseRecNumber", "firstname", "surname", "organisationName",
+	"firmName", "firmNumber",
+	"donor.firstNames", "donor.surname",
+}
+
+type SuggestRequest struct {
+	Term    string   `json:"term"`
+	Size    int      `json:"size,omitempty"`
+	Indices []string `json:"indices,omitempty"`
+}
+
+type SuggestResponse struct {
+	Suggestions []Suggestion `json:"suggestions"`
+}
+
+type Suggestion struct {
+	Type  string `json:"type"`
+	Label string `json:"label"`
+	ID    *int64 `json:"id,omitempty"`
+	UID   string `json:"uId,omitempty"`
+}
+
+// suggestSource is the union of the fields in suggestSourceFields, as a hit
+// may come from any index
+type suggestSource struct {
+	ID               *int64 `json:"id"`
+	UID              string `json:"uId"`
+	CaseRecNumber    string `json:"caseRecNumber"`
+	Firstname        string `json:"firstname"`
+	Surname          string `json:"surname"`
+	OrganisationName string `json:"organisationName"`
+	FirmName         string `json:"firmName"`
+	FirmNumber       string `json:"firmNumber"`
+	Donor            struct {
+		FirstNames string `json:"firstNames"`
+		Surname    string `json:"surname"`
+	} `json:"donor"`
+}
+
+type SuggestHandler struct {
+	logger *logrus.Logger
+	client SearchClient
+}
+
+func NewSuggestHandler(logger *logrus.Logger, client SearchClient) *SuggestHandler {
+	return &SuggestHandler{
+		logger: logger,
+		client: client,
+	}
+}
+
+func (h *SuggestHandler) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	start := time.Now()
+
+	req, err := parseSuggestRequest(r)
+	if err != nil {
+		writeRequestError(h.logger, w, err)
+		return
+	}
+
+	indices, requestBody := PrepareQueryForSuggest(req)
+
+	result, err := h.client.Search(r.Context(), indices, requestBody)
+	if err != nil {
+		writeSearchError(h.logger, w, err)
+		return
+	}
+
+	resp := SuggestResponse{Suggestions: []Suggestion{}}
+	for i, hit := range result.Hits {
+		var source suggestSource
+		if err := json.Unmarshal(hit, &source); err != nil {
+			h.logger.Println(err)
+			continue
+		}
+
+		// the _index of a hit has been replaced, and digital_lpa indices come
+		// back as "digital", so the concrete index is used to find its type
+		var index string
+		if i < len(result.Refs) {
+			index = result.Refs[i].Index
+		}
+
+		resp.Suggestions = append(resp.Suggestions, source.suggestion(index))
+	}
+
+	jsonResp, _ := json.Marshal(resp)
+	w.WriteHeader(http.StatusOK)
+	_, _ = w.Write(jsonResp)
+
+	h.logger.Printf("Request took: %d", time.Since(start))
+}
+
+func parseSuggestRequest(r *http.Request) (*SuggestRequest, error) {
+	buf := new(bytes.Buffer)
+	_, _ = buf.ReadFrom(r.Body)
+	if buf.Len() == 0 {
+		return nil, errors.New("request body is empty")
+	}
+
+	var req SuggestRequest
+	if err := json.Unmarshal(buf.Bytes(), &req); err != nil {
+		log.Println(err)
+		return nil, errors.New("unable to unmarshal JSON request")
+	}
+
+	req.Term = clean(req.Term)
+	if req.Term == "" {
+		return nil, errors.New("search term is required and cannot be empty")
+	}
+
+	if req.Size <= 0 {
+		req.Size = defaultSuggestSize
+	}
+	if req.Size > maxSuggestSize {
+		return nil, fmt.Errorf("size cannot be more than %d", maxSuggestSize)
+	}
+
+	if err := validateIndices(req.Indices); err != nil {
+		return nil, err
+	}
+
+	return &req, nil
+}
+
+func PrepareQueryForSuggest(req *SuggestRequest) ([]string, map[string]interface{}) {
+	indices := allIndices
+	if len(req.Indices) > 0 {
+		indices = req.Indices
+	}
+
+	return indices, map[string]interface{}{
+		"query": map[string]interface{}{
+			"match": map[string]interface{}{
+				autocomplete.Field: map[string]interface{}{
+					"query":    req.Term,
+					"operator": "AND",
+				},
+			},
+		},
+		"size":             req.Size,
+		"_source":          suggestSourceFields,
+		"track_total_hits": false,
+	}
+}
+
+func (s suggestSource) suggestion(index string) Suggestion {
+	switch aliasOf(index) {
+	case firm.AliasName:
+		return Suggestion{
+			Type:  firm.AliasName,
+			Label: withReference(s.FirmName, s.FirmNumber),
+			ID:    s.ID,
+		}
+
+	case digitallpa.AliasName:
+		return Suggestion{
+			Type:  digitallpa.AliasName,
+			Label: withReference(joinNames(s.Donor.FirstNames, s.Donor.Surname), s.UID),
+			UID:   s.UID,
+		}
+
+	default:
+		name := joinNames(s.Firstname, s.Surname)
+		if name == "" {
+			name = s.OrganisationName
+		}
+
+		return Suggestion{
+			Type:  person.AliasName,
+			Label: withReference(name, s.CaseRecNumber),
+			ID:    s.ID,
+			UID:   s.UID,
+		}
+	}
+}
+
+func joinNames(names ...string) string {
+	var parts []string
+	for _, name := range names {
+		if name = strings.TrimSpace(name); name != "" {
+			parts = append(parts, name)
+		}
+	}
+
+	return strings.Join(parts, " ")
+}
+
+func withReference(label, reference string) string {
+	if reference == "" {
+		return label
+	}
+	if label == "" {
+		return reference
+	}
+
+	return fmt.Sprintf("%s (%s)", label, reference)
+}
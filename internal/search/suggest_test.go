@@ -0,0 +1,134 @@
+package search
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/ministryofjustice/opg-search-service/internal/elasticsearch"
+	"github.com/sirupsen/logrus/hooks/test"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+)
+
+func TestSuggestHandler(t *testing.T) {
+	logger, _ := test.NewNullLogger()
+	client := new(elasticsearch.MockESClient)
+
+	_, body := PrepareQueryForSuggest(&SuggestRequest{Term: "smi", Size: 5})
+
+	client.
+		On("Search", mock.Anything, allIndices, body).
+		Return(&elasticsearch.SearchResult{
+			Hits: []json.RawMessage{
+				[]byte(`{"_index":"person","id":1,"uId":"7000-0000-0001","caseRecNumber":"12345678","firstname":"John","surname":"Smith"}`),
+				[]byte(`{"_index":"person","id":2,"uId":"7000-0000-0002","organisationName":"Smithson Ltd"}`),
+				[]byte(`{"_index":"firm","id":3,"firmName":"Smith & Co","firmNumber":"F123"}`),
+				[]byte(`{"_index":"digital","uId":"M-1234-5678-9012","donor":{"firstNames":"Jane","surname":"Smithers"}}`),
+			},
+			Refs: []elasticsearch.DocumentRef{
+				{Index: "person_abc", ID: "1"},
+				{Index: "person_abc", ID: "2"},
+				{Index: "firm_abc", ID: "3"},
+				{Index: "digital_lpa_abc", ID: "M-1234-5678-9012"},
+			},
+		}, nil)
+
+	w := httptest.NewRecorder()
+	r, _ := http.NewRequest(http.MethodPost, "/suggest", strings.NewReader(`{"term":"smi!"}`))
+
+	NewSuggestHandler(logger, client).ServeHTTP(w, r)
+
+	resp := w.Result()
+	respBody, _ := io.ReadAll(resp.Body)
+
+	assert.Equal(t, http.StatusOK, resp.StatusCode)
+	assert.JSONEq(t, `{"suggestions":[
+		{"type":"person","label":"John Smith (12345678)","id":1,"uId":"7000-0000-0001"},
+		{"type":"person","label":"Smithson Ltd","id":2,"uId":"7000-0000-0002"},
+		{"type":"firm","label":"Smith & Co (F123)","id":3},
+		{"type":"digital_lpa","label":"Jane Smithers (M-1234-5678-9012)","uId":"M-1234-5678-9012"}
+	]}`, string(respBody))
+}
+
+func TestSuggestHandlerBadRequest(t *testing.T) {
+	testCases := map[string]struct {
+		body     string
+		expected string
+	}{
+		"empty term": {
+			body:     `{"term":" "}`,
+			expected: `{"message":"request","errors":[{"name":"request","description":"search term is required and cannot be empty"}]}`,
+		},
+		"size too large": {
+			body:     `{"term":"smi","size":21}`,
+			expected: `{"message":"request","errors":[{"name":"request","description":"size cannot be more than 20"}]}`,
+		},
+		"unknown index": {
+			body:     `{"term":"smi","indices":["other"]}`,
+			expected: `{"message":"Some fields have failed validation","errors":[{"name":"indices[0]","description":"other is not a known index"}]}`,
+		},
+		"empty index": {
+			body:     `{"term":"smi","indices":["firm",""]}`,
+			expected: `{"message":"Some fields have failed validation","errors":[{"name":"indices[1]","description":"index cannot be empty"}]}`,
+		},
+	}
+
+	for name, tc := range testCases {
+		t.Run(name, func(t *testing.T) {
+			logger, _ := test.NewNullLogger()
+
+			w := httptest.NewRecorder()
+			r, _ := http.NewRequest(http.MethodPost, "/suggest", strings.NewReader(tc.body))
+
+			NewSuggestHandler(logger, nil).ServeHTTP(w, r)
+
+			resp := w.Result()
+			respBody, _ := io.ReadAll(resp.Body)
+
+			assert.Equal(t, http.StatusBadRequest, resp.StatusCode)
+			assert.JSONEq(t, tc.expected, string(respBody))
+		})
+	}
+}
+
+func TestSuggestHandlerSearchError(t *testing.T) {
+	logger, _ := test.NewNullLogger()
+	client := new(elasticsearch.MockESClient)
+
+	client.
+		On("Search", mock.Anything, []string{"firm"}, mock.Anything).
+		Return(&elasticsearch.SearchResult{}, errors.New("oops"))
+
+	w := httptest.NewRecorder()
+	r, _ := http.NewRequestWithContext(context.Background(), http.MethodPost, "/suggest", bytes.NewBufferString(`{"term":"smi","indices":["firm"]}`))
+
+	NewSuggestHandler(logger, client).ServeHTTP(w, r)
+
+	assert.Equal(t, http.StatusInternalServerError, w.Result().StatusCode)
+}
+
+func TestPrepareQueryForSuggest(t *testing.T) {
+	indices, body := PrepareQueryForSuggest(&SuggestRequest{Term: "smi", Size: 3, Indices: []string{"person"}})
+
+	assert.Equal(t, []string{"person"}, indices)
+	assert.Equal(t, map[string]interface{}{
+		"query": map[string]interface{}{
+			"match": map[string]interface{}{
+				"suggest": map[string]interface{}{
+					"query":    "smi",
+					"operator": "AND",
+				},
+			},
+		},
+		"size":             3,
+		"_source":          suggestSourceFields,
+		"track_total_hits": false,
+	}, body)
+}
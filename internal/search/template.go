@@ -0,0 +1,155 @@
+package search
+
+import (
+	"errors"
+	"fmt"
+	"math"
+	"regexp"
+	"sort"
+	"strings"
+)
+
+type paramType int
+
+const (
+	paramString paramType = iota
+	paramInt
+	paramUID
+)
+
+var uidPattern = regexp.MustCompile(`^\d{4}-\d{4}-\d{4}$`)
+
+// queryTemplate is a named query that can be run with validated parameters,
+// so that callers can make specific lookups without sending query DSL
+type queryTemplate struct {
+	indices []string
+	params  map[string]paramType
+	query   func(params map[string]interface{}) map[string]interface{}
+}
+
+var templates = map[string]queryTemplate{
+	"person-by-uid": {
+		indices: personIndices,
+		params:  map[string]paramType{"uid": paramUID},
+		query: func(params map[string]interface{}) map[string]interface{} {
+			return termFilters(map[string]interface{}{"uId": params["uid"]})
+		},
+	},
+	"person-by-case-uid": {
+		indices: personIndices,
+		params:  map[string]paramType{"uid": paramUID},
+		query: func(params map[string]interface{}) map[string]interface{} {
+			return termFilters(map[string]interface{}{"cases.uId": params["uid"]})
+		},
+	},
+	"person-by-case-rec-number": {
+		indices: personIndices,
+		params:  map[string]paramType{"caseRecNumber": paramString},
+		query: func(params map[string]interface{}) map[string]interface{} {
+			return termFilters(map[string]interface{}{"cases.caseRecNumber": params["caseRecNumber"]})
+		},
+	},
+	"deputy-by-deputy-number": {
+		indices: personIndices,
+		params:  map[string]paramType{"deputyNumber": paramInt},
+		query: func(params map[string]interface{}) map[string]interface{} {
+			return termFilters(map[string]interface{}{
+				"deputyNumber": params["deputyNumber"],
+				"personType":   "Deputy",
+			})
+		},
+	},
+}
+
+// termFilters matches documents where every field has the exact value given,
+// the fields are sorted so that prepared queries are stable
+func termFilters(terms map[string]interface{}) map[string]interface{} {
+	fields := make([]string, 0, len(terms))
+	for field := range terms {
+		fields = append(fields, field)
+	}
+	sort.Strings(fields)
+
+	filters := []interface{}{}
+	for _, field := range fields {
+		filters = append(filters, map[string]interface{}{
+			"term": map[string]interface{}{field: terms[field]},
+		})
+	}
+
+	return map[string]interface{}{
+		"bool": map[string]interface{}{
+			"filter": filters,
+		},
+	}
+}
+
+// validateTemplate checks that the requested template exists and that the
+// parameters given are exactly those it takes, with the expected types
+func (sr *Request) validateTemplate() error {
+	tmpl, ok := templates[sr.Template]
+	if !ok {
+		return fmt.Errorf("template %s does not exist", sr.Template)
+	}
+
+	for name := range sr.Params {
+		if _, ok := tmpl.params[name]; !ok {
+			return fmt.Errorf("template %s does not take parameter %s", sr.Template, name)
+		}
+	}
+
+	params := map[string]interface{}{}
+	for name, kind := range tmpl.params {
+		value, ok := sr.Params[name]
+		if !ok {
+			return fmt.Errorf("template %s requires parameter %s", sr.Template, name)
+		}
+
+		v, err := parseParam(kind, value)
+		if err != nil {
+			return fmt.Errorf("parameter %s %v", name, err)
+		}
+
+		params[name] = v
+	}
+
+	sr.templateParams = params
+	return nil
+}
+
+func parseParam(kind paramType, value interface{}) (interface{}, error) {
+	switch kind {
+	case paramInt:
+		f, ok := value.(float64)
+		if !ok || f != math.Trunc(f) {
+			return nil, errors.New("must be an integer")
+		}
+		return int(f), nil
+
+	case paramUID:
+		s, ok := value.(string)
+		if !ok || !uidPattern.MatchString(s) {
+			return nil, errors.New("must be a UID in the format 0000-0000-0000")
+		}
+		return s, nil
+
+	default:
+		s, ok := value.(string)
+		if !ok || strings.TrimSpace(s) == "" {
+			return nil, errors.New("must be a non-empty string")
+		}
+		return strings.TrimSpace(s), nil
+	}
+}
+
+// PrepareQueryForTemplate builds the query for the template in a request,
+// which must have already been validated
+func PrepareQueryForTemplate(req *Request) ([]string, map[string]interface{}) {
+	tmpl := templates[req.Template]
+
+	body := map[string]interface{}{
+		"query": tmpl.query(req.templateParams),
+	}
+
+	return tmpl.indices, withDefaults(req, body)
+}
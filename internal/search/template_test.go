@@ -0,0 +1,117 @@
+package search
+
+import (
+	"testing"
+
+	"github.com/ministryofjustice/opg-search-service/internal/person"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestPrepareQueryForTemplate(t *testing.T) {
+	testCases := map[string]struct {
+		params map[string]interface{}
+		query  map[string]interface{}
+	}{
+		"person-by-uid": {
+			params: map[string]interface{}{"uid": "7000-0000-0001"},
+			query: map[string]interface{}{
+				"bool": map[string]interface{}{
+					"filter": []interface{}{
+						map[string]interface{}{"term": map[string]interface{}{"uId": "7000-0000-0001"}},
+					},
+				},
+			},
+		},
+		"person-by-case-uid": {
+			params: map[string]interface{}{"uid": "7000-0000-0002"},
+			query: map[string]interface{}{
+				"bool": map[string]interface{}{
+					"filter": []interface{}{
+						map[string]interface{}{"term": map[string]interface{}{"cases.uId": "7000-0000-0002"}},
+					},
+				},
+			},
+		},
+		"person-by-case-rec-number": {
+			params: map[string]interface{}{"caseRecNumber": "12345678"},
+			query: map[string]interface{}{
+				"bool": map[string]interface{}{
+					"filter": []interface{}{
+						map[string]interface{}{"term": map[string]interface{}{"cases.caseRecNumber": "12345678"}},
+					},
+				},
+			},
+		},
+		"deputy-by-deputy-number": {
+			params: map[string]interface{}{"deputyNumber": 12345},
+			query: map[string]interface{}{
+				"bool": map[string]interface{}{
+					"filter": []interface{}{
+						map[string]interface{}{"term": map[string]interface{}{"deputyNumber": 12345}},
+						map[string]interface{}{"term": map[string]interface{}{"personType": "Deputy"}},
+					},
+				},
+			},
+		},
+	}
+
+	assert.Len(t, testCases, len(templates))
+
+	for name, tc := range testCases {
+		t.Run(name, func(t *testing.T) {
+			req := &Request{From: 5, Template: name, templateParams: tc.params}
+
+			indices, body := PrepareQueryForTemplate(req)
+
+			assert.Equal(t, []string{person.AliasName}, indices)
+			assert.Equal(t, map[string]interface{}{
+				"query": tc.query,
+				"aggs": map[string]interface{}{
+					"personType": map[string]interface{}{
+						"terms": map[string]interface{}{
+							"field": "personType",
+							"size":  "20",
+						},
+					},
+				},
+				"post_filter": map[string]interface{}{"bool": map[string]interface{}{"should": []interface{}{}}},
+				"sort": []interface{}{
+					map[string]interface{}{"_score": "desc"},
+					map[string]interface{}{"_id": "asc"},
+				},
+				"from": 5,
+			}, body)
+		})
+	}
+}
+
+func TestParseParam(t *testing.T) {
+	testCases := map[string]struct {
+		kind     paramType
+		in       interface{}
+		expected interface{}
+		err      string
+	}{
+		"string":           {kind: paramString, in: " abc ", expected: "abc"},
+		"empty string":     {kind: paramString, in: " ", err: "must be a non-empty string"},
+		"string not given": {kind: paramString, in: 1.0, err: "must be a non-empty string"},
+		"int":              {kind: paramInt, in: 12.0, expected: 12},
+		"fractional int":   {kind: paramInt, in: 1.5, err: "must be an integer"},
+		"int not given":    {kind: paramInt, in: "12", err: "must be an integer"},
+		"uid":              {kind: paramUID, in: "7000-0000-0001", expected: "7000-0000-0001"},
+		"invalid uid":      {kind: paramUID, in: "700000000001", err: "must be a UID in the format 0000-0000-0000"},
+	}
+
+	for name, tc := range testCases {
+		t.Run(name, func(t *testing.T) {
+			v, err := parseParam(tc.kind, tc.in)
+
+			if tc.err != "" {
+				assert.EqualError(t, err, tc.err)
+			} else {
+				assert.Nil(t, err)
+				assert.Equal(t, tc.expected, v)
+			}
+		})
+	}
+}
@@ -0,0 +1,46 @@
+package synonyms
+
+import (
+	_ "embed"
+	"strings"
+)
+
+// names of the token filter and analyzer to use in index settings
+const (
+	NameFilter   = "name_synonyms"
+	NameAnalyzer = "name_synonym_analyzer"
+)
+
+//go:embed names.txt
+var names string
+
+// Names returns groups of equivalent first names, in a format that can be used
+// by a synonym token filter
+func Names() []string {
+	var rules []string
+
+	for _, line := range strings.Split(names, "\n") {
+		line = strings.TrimSpace(line)
+		if line == "" || strings.HasPrefix(line, "#") {
+			continue
+		}
+
+		rules = append(rules, line)
+	}
+
+	return rules
+}
+
+func NameFilterConfig() map[string]interface{} {
+	return map[string]interface{}{
+		"type":     "synonym",
+		"synonyms": Names(),
+	}
+}
+
+func NameAnalyzerConfig() map[string]interface{} {
+	return map[string]interface{}{
+		"tokenizer": "whitespace",
+		"filter":    []string{"asciifolding", "lowercase", NameFilter},
+	}
+}
@@ -0,0 +1,19 @@
+package synonyms
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestNames(t *testing.T) {
+	names := Names()
+
+	assert.Contains(t, names, "william, bill, billy, will, willie, liam")
+	assert.Contains(t, names, "margaret, maggie, meg, peggy, peg, madge, marge, greta")
+
+	for _, name := range names {
+		assert.NotEmpty(t, name)
+		assert.NotContains(t, name, "#")
+	}
+}
@@ -306,9 +306,67 @@ CREATE TABLE supervision.firm
     piiexpiry date,
     piiamount numeric(12, 2) default NULL::numeric,
     piirequested date,
+    updateddate timestamp(0) without time zone DEFAULT NULL::timestamp without time zone,
     CONSTRAINT firm_pkey PRIMARY KEY (id)
 )
     WITH (
         OIDS = FALSE
     )
     TABLESPACE pg_default;
+
+-- Table: lpa_store.lpas
+
+DROP TABLE IF EXISTS lpa_store.attorneys;
+
+DROP TABLE IF EXISTS lpa_store.lpas;
+
+DROP SCHEMA IF EXISTS lpa_store;
+
+CREATE SCHEMA lpa_store;
+
+CREATE TABLE lpa_store.lpas
+(
+    id integer NOT NULL,
+    uid character varying(36) COLLATE pg_catalog."default" NOT NULL,
+    lpa_type character varying(255) COLLATE pg_catalog."default" default NULL::character varying,
+    donor_first_names character varying(255) COLLATE pg_catalog."default" default NULL::character varying,
+    donor_last_name character varying(255) COLLATE pg_catalog."default" default NULL::character varying,
+    donor_date_of_birth date,
+    donor_address_line1 character varying(255) COLLATE pg_catalog."default" default NULL::character varying,
+    donor_address_line2 character varying(255) COLLATE pg_catalog."default" default NULL::character varying,
+    donor_address_line3 character varying(255) COLLATE pg_catalog."default" default NULL::character varying,
+    donor_postcode character varying(255) COLLATE pg_catalog."default" default NULL::character varying,
+    certificate_provider_first_names character varying(255) COLLATE pg_catalog."default" default NULL::character varying,
+    certificate_provider_last_name character varying(255) COLLATE pg_catalog."default" default NULL::character varying,
+    certificate_provider_address_line1 character varying(255) COLLATE pg_catalog."default" default NULL::character varying,
+    certificate_provider_address_line2 character varying(255) COLLATE pg_catalog."default" default NULL::character varying,
+    certificate_provider_address_line3 character varying(255) COLLATE pg_catalog."default" default NULL::character varying,
+    certificate_provider_postcode character varying(255) COLLATE pg_catalog."default" default NULL::character varying,
+    updated_at timestamp(0) without time zone DEFAULT NULL::timestamp without time zone,
+    CONSTRAINT lpas_pkey PRIMARY KEY (id)
+)
+    WITH (
+        OIDS = FALSE
+    )
+    TABLESPACE pg_default;
+
+-- Table: lpa_store.attorneys
+
+CREATE TABLE lpa_store.attorneys
+(
+    id integer NOT NULL,
+    lpa_id integer NOT NULL,
+    first_names character varying(255) COLLATE pg_catalog."default" default NULL::character varying,
+    last_name character varying(255) COLLATE pg_catalog."default" default NULL::character varying,
+    date_of_birth date,
+    address_line1 character varying(255) COLLATE pg_catalog."default" default NULL::character varying,
+    address_line2 character varying(255) COLLATE pg_catalog."default" default NULL::character varying,
+    address_line3 character varying(255) COLLATE pg_catalog."default" default NULL::character varying,
+    postcode character varying(255) COLLATE pg_catalog."default" default NULL::character varying,
+    CONSTRAINT attorneys_pkey PRIMARY KEY (id),
+    CONSTRAINT attorneys_lpa_id_fkey FOREIGN KEY (lpa_id) REFERENCES lpa_store.lpas (id)
+)
+    WITH (
+        OIDS = FALSE
+    )
+    TABLESPACE pg_default;
@@ -21,6 +21,7 @@ import (
 	"github.com/ministryofjustice/opg-search-service/internal/index"
 	"github.com/ministryofjustice/opg-search-service/internal/middleware"
 	"github.com/ministryofjustice/opg-search-service/internal/person"
+	"github.com/ministryofjustice/opg-search-service/internal/purge"
 	"github.com/ministryofjustice/opg-search-service/internal/remove"
 	"github.com/ministryofjustice/opg-search-service/internal/search"
 	"github.com/sirupsen/logrus"
@@ -84,14 +85,25 @@ func main() {
 		cmd.NewHealthCheck(l),
 		cmd.NewCreateIndices(esClient, currentIndices),
 		cmd.NewIndex(l, esClient, secretsCache, currentIndices),
+		cmd.NewFollow(l, esClient, secretsCache, currentIndices),
+		cmd.NewReconcile(l, esClient, secretsCache),
+		cmd.NewMigrate(l, esClient, secretsCache, currentIndices),
 		cmd.NewUpdateAlias(l, esClient, currentIndices),
 		cmd.NewCleanupIndices(l, esClient, currentIndices),
+		cmd.NewPurge(l, purge.New(esClient)),
 	)
 
 	personIndices := createIndexAndAlias(esClient, personIndexConfig, l)
 	firmIndices := createIndexAndAlias(esClient, firmIndexConfig, l)
 	digitalLpaIndices := createIndexAndAlias(esClient, digitallpaIndexConfig, l)
 
+	// holds index requests made with async=true, so they can be polled
+	jobs := index.NewJobStore()
+
+	// replays the response to index and delete requests retried with the same
+	// Idempotency-Key, keeping up to 64MiB of responses
+	idempotent := middleware.Idempotency(middleware.NewIdempotencyStore(24*time.Hour, 64<<20))
+
 	// Create new serveMux
 	sm := mux.NewRouter().PathPrefix(os.Getenv("PATH_PREFIX")).Subrouter()
 
@@ -122,6 +134,16 @@ func main() {
 	// produces:
 	// - application/json
 	// parameters:
+	// - in: "header"
+	//   name: "Idempotency-Key"
+	//   description: "Replays the original response when a request is retried with the same key, a key reused for a different request is rejected with 422"
+	//   required: false
+	//   type: string
+	// - in: "query"
+	//   name: "async"
+	//   description: "When true the items are indexed after responding, and the job can be polled with GET /jobs/{id}"
+	//   required: false
+	//   type: boolean
 	// - in: "body"
 	//   name: "body"
 	//   description: ""
@@ -137,6 +159,10 @@ func main() {
 	//             id:
 	//               type: integer
 	//               format: int64
+	//             version:
+	//               type: integer
+	//               format: int64
+	//               description: Taken from nextval('public.search_version') after the change is committed, a person with a lower version than the indexed document is not written
 	//             uId:
 	//               type: string
 	//             normalizedUid:
@@ -299,7 +325,7 @@ func main() {
 	//               type: string
 	// responses:
 	//   '202':
-	//     description: The request has been handled and individual index responses are included in the response body
+	//     description: The request has been handled and individual index responses are included in the response body. With async=true the request has been accepted and the body is the job, as returned by GET /jobs/{id}
 	//     schema:
 	//       type: object
 	//       properties:
@@ -309,6 +335,9 @@ func main() {
 	//         failed:
 	//           type: integer
 	//           format: int64
+	//         conflicts:
+	//           type: integer
+	//           format: int64
 	//         errors:
 	//           type: array
 	//           items:
@@ -319,8 +348,10 @@ func main() {
 	//             type: object
 	//             properties:
 	//               id:
-	//                 type: integer
-	//                 format: int64
+	//                 type: string
+	//               index:
+	//                 type: string
+	//                 description: The concrete index the document was written to
 	//               statusCode:
 	//                 type: integer
 	//               message:
@@ -345,52 +376,992 @@ func main() {
 	//     description: Not found
 	//   '500':
 	//     description: Unexpected error occurred
-	postRouter.Handle("/persons", index.NewHandler(l, esClient, personIndices, person.ParseIndexRequest))
+	postRouter.Handle("/persons", idempotent(index.NewHandler(l, esClient, jobs, personIndices, person.ParseIndexRequest)))
 	postRouter.Handle("/persons/search", search.NewHandler(l, esClient, search.PrepareQueryForPerson))
 
 	postRouter.Handle("/deputies/search", search.NewHandler(l, esClient, search.PrepareQueryForDeputy))
 
-	postRouter.Handle("/digitalLpa", index.NewHandler(l, esClient, digitalLpaIndices, digitallpa.ParseIndexRequest))
+	postRouter.Handle("/digitalLpa", idempotent(index.NewHandler(l, esClient, jobs, digitalLpaIndices, digitallpa.ParseIndexRequest)))
 	postRouter.Handle("/digitalLpa/search", search.NewHandler(l, esClient, search.PrepareQueryForDigitalLpa))
 
-	postRouter.Handle("/firms", index.NewHandler(l, esClient, firmIndices, firm.ParseIndexRequest))
+	postRouter.Handle("/firms", idempotent(index.NewHandler(l, esClient, jobs, firmIndices, firm.ParseIndexRequest)))
 	postRouter.Handle("/firms/search", search.NewHandler(l, esClient, search.PrepareQueryForFirm))
 
 	postRouter.Handle("/searchAll", search.NewHandler(l, esClient, search.PrepareQueryForAll))
 
-	deleteRouter := sm.Methods(http.MethodDelete).Subrouter()
-	deleteRouter.Use(middleware.JwtVerify(secretsCache, l))
-	deleteRouter.Use(middleware.ContentType())
-
-	// swagger:operation DELETE /persons/:uid delete-person
-	// Delete a person
+	// swagger:operation POST /suggest suggest
+	// Suggest persons, firms and digital LPAs as a search term is typed
 	// ---
 	// consumes:
 	// - application/json
 	// produces:
 	// - application/json
 	// parameters:
-	// - in: path
-	//   name: uid
+	// - in: "body"
+	//   name: "body"
 	//   description: ""
 	//   required: true
 	//   schema:
-	//     type: integer
-	//     format: string
-	//     pattern: "^\\d{4}-\\d{4}-\\d{4}$"
+	//     type: object
+	//     required:
+	//     - term
+	//     properties:
+	//       term:
+	//         type: string
+	//       size:
+	//         description: "The number of suggestions to return, up to 20"
+	//         type: integer
+	//         default: 5
+	//       indices:
+	//         description: "The aliases to suggest from, defaults to all of them"
+	//         type: array
+	//         items:
+	//           type: string
+	//           enum: [person, firm, digital_lpa]
 	// responses:
 	//   '200':
-	//     description: The person has been deleted
+	//     description: The suggestions, most relevant first
+	//     schema:
+	//       type: object
+	//       properties:
+	//         suggestions:
+	//           type: array
+	//           items:
+	//             type: object
+	//             properties:
+	//               type:
+	//                 type: string
+	//                 enum: [person, firm, digital_lpa]
+	//               label:
+	//                 type: string
+	//               id:
+	//                 type: integer
+	//                 format: int64
+	//               uId:
+	//                 type: string
 	//   '400':
-	//     description: The person could not be found
+	//     description: Request failed validation
+	//     schema:
+	//       type: object
+	//       properties:
+	//         message:
+	//           type: string
+	//         errors:
+	//           type: array
+	//           items:
+	//             type: object
+	//             properties:
+	//               name:
+	//                 type: string
+	//               description:
+	//                 type: string
+	//   '500':
+	//     description: Unexpected error occurred
+	postRouter.Handle("/suggest", search.NewSuggestHandler(l, esClient))
+
+	// bulk deletes take the ids of documents in the index, which for persons is
+	// the id rather than the uid
+
+	// swagger:operation POST /persons/delete delete-persons
+	// Delete one or many Persons by id
+	// ---
+	// consumes:
+	// - application/json
+	// produces:
+	// - application/json
+	// parameters:
+	// - in: "header"
+	//   name: "Idempotency-Key"
+	//   description: "Replays the original response when a request is retried with the same key, a key reused for a different request is rejected with 422"
+	//   required: false
+	//   type: string
+	// - in: "query"
+	//   name: "async"
+	//   description: "When true the items are written after responding, and the job can be polled with GET /jobs/{id}"
+	//   required: false
+	//   type: boolean
+	// - in: "body"
+	//   name: "body"
+	//   description: ""
+	//   required: true
+	//   schema:
+	//     type: object
+	//     properties:
+	//       ids:
+	//         type: array
+	//         description: The ids of the persons to delete, as strings or numbers
+	//         items:
+	//           type: string
+	// responses:
+	//   '202':
+	//     description: The request has been handled and individual delete responses are included in the response body, an id that is not indexed has statusCode 404
+	//     schema:
+	//       type: object
+	//       properties:
+	//         successful:
+	//           type: integer
+	//           format: int64
+	//         failed:
+	//           type: integer
+	//           format: int64
+	//         conflicts:
+	//           type: integer
+	//           format: int64
+	//         errors:
+	//           type: array
+	//           items:
+	//             type: string
+	//         results:
+	//           type: array
+	//           items:
+	//             type: object
+	//             properties:
+	//               id:
+	//                 type: string
+	//               index:
+	//                 type: string
+	//                 description: The concrete index the document was written to
+	//               statusCode:
+	//                 type: integer
+	//               message:
+	//                 type: string
+	//   '400':
+	//     description: Request failed validation
+	//     schema:
+	//       type: object
+	//       properties:
+	//         message:
+	//           type: string
+	//         errors:
+	//           type: array
+	//           items:
+	//             type: object
+	//             properties:
+	//               name:
+	//                 type: string
+	//               description:
+	//                 type: string
+	//   '500':
+	//     description: Unexpected error occurred
+	postRouter.Handle("/persons/delete", idempotent(index.NewDeleteHandler(l, esClient, jobs, personIndices, index.ParseDeleteRequest)))
+	// swagger:operation POST /firms/delete delete-firms
+	// Delete one or many Firms by id
+	// ---
+	// consumes:
+	// - application/json
+	// produces:
+	// - application/json
+	// parameters:
+	// - in: "header"
+	//   name: "Idempotency-Key"
+	//   description: "Replays the original response when a request is retried with the same key, a key reused for a different request is rejected with 422"
+	//   required: false
+	//   type: string
+	// - in: "query"
+	//   name: "async"
+	//   description: "When true the items are written after responding, and the job can be polled with GET /jobs/{id}"
+	//   required: false
+	//   type: boolean
+	// - in: "body"
+	//   name: "body"
+	//   description: ""
+	//   required: true
+	//   schema:
+	//     type: object
+	//     properties:
+	//       ids:
+	//         type: array
+	//         description: The ids of the firms to delete, as strings or numbers
+	//         items:
+	//           type: string
+	// responses:
+	//   '202':
+	//     description: The request has been handled and individual delete responses are included in the response body, an id that is not indexed has statusCode 404
+	//     schema:
+	//       type: object
+	//       properties:
+	//         successful:
+	//           type: integer
+	//           format: int64
+	//         failed:
+	//           type: integer
+	//           format: int64
+	//         conflicts:
+	//           type: integer
+	//           format: int64
+	//         errors:
+	//           type: array
+	//           items:
+	//             type: string
+	//         results:
+	//           type: array
+	//           items:
+	//             type: object
+	//             properties:
+	//               id:
+	//                 type: string
+	//               index:
+	//                 type: string
+	//                 description: The concrete index the document was written to
+	//               statusCode:
+	//                 type: integer
+	//               message:
+	//                 type: string
+	//   '400':
+	//     description: Request failed validation
+	//     schema:
+	//       type: object
+	//       properties:
+	//         message:
+	//           type: string
+	//         errors:
+	//           type: array
+	//           items:
+	//             type: object
+	//             properties:
+	//               name:
+	//                 type: string
+	//               description:
+	//                 type: string
+	//   '500':
+	//     description: Unexpected error occurred
+	postRouter.Handle("/firms/delete", idempotent(index.NewDeleteHandler(l, esClient, jobs, firmIndices, index.ParseDeleteRequest)))
+	// swagger:operation POST /digitalLpa/delete delete-digital-lpas
+	// Delete one or many digital LPAs by uId
+	// ---
+	// consumes:
+	// - application/json
+	// produces:
+	// - application/json
+	// parameters:
+	// - in: "header"
+	//   name: "Idempotency-Key"
+	//   description: "Replays the original response when a request is retried with the same key, a key reused for a different request is rejected with 422"
+	//   required: false
+	//   type: string
+	// - in: "query"
+	//   name: "async"
+	//   description: "When true the items are written after responding, and the job can be polled with GET /jobs/{id}"
+	//   required: false
+	//   type: boolean
+	// - in: "body"
+	//   name: "body"
+	//   description: ""
+	//   required: true
+	//   schema:
+	//     type: object
+	//     properties:
+	//       ids:
+	//         type: array
+	//         description: The ids of the digital LPAs to delete, as strings or numbers
+	//         items:
+	//           type: string
+	// responses:
+	//   '202':
+	//     description: The request has been handled and individual delete responses are included in the response body, an id that is not indexed has statusCode 404
+	//     schema:
+	//       type: object
+	//       properties:
+	//         successful:
+	//           type: integer
+	//           format: int64
+	//         failed:
+	//           type: integer
+	//           format: int64
+	//         conflicts:
+	//           type: integer
+	//           format: int64
+	//         errors:
+	//           type: array
+	//           items:
+	//             type: string
+	//         results:
+	//           type: array
+	//           items:
+	//             type: object
+	//             properties:
+	//               id:
+	//                 type: string
+	//               index:
+	//                 type: string
+	//                 description: The concrete index the document was written to
+	//               statusCode:
+	//                 type: integer
+	//               message:
+	//                 type: string
+	//   '400':
+	//     description: Request failed validation
 	//     schema:
 	//       type: object
 	//       properties:
 	//         message:
 	//           type: string
+	//         errors:
+	//           type: array
+	//           items:
+	//             type: object
+	//             properties:
+	//               name:
+	//                 type: string
+	//               description:
+	//                 type: string
 	//   '500':
 	//     description: Unexpected error occurred
-	deleteRouter.Handle("/persons/{uid:\\d{4}-\\d{4}-\\d{4}}", remove.NewHandler(l, esClient, []string{person.AliasName}))
+	postRouter.Handle("/digitalLpa/delete", idempotent(index.NewDeleteHandler(l, esClient, jobs, digitalLpaIndices, index.ParseDeleteRequest)))
+
+	// erases a person from all indices, including those not yet cleaned up
+
+	// swagger:operation POST /persons/purge purge-person
+	// Erase a person from every person index and from the digital LPAs they are named on
+	// ---
+	// consumes:
+	// - application/json
+	// produces:
+	// - application/json
+	// parameters:
+	// - in: "body"
+	//   name: "body"
+	//   description: ""
+	//   required: true
+	//   schema:
+	//     type: object
+	//     required:
+	//     - uId
+	//     properties:
+	//       uId:
+	//         type: string
+	//         pattern: "^\\d{4}-\\d{4}-\\d{4}$"
+	//       explain:
+	//         type: boolean
+	//         description: When true the report lists what would be purged without changing anything
+	// responses:
+	//   '200':
+	//     description: The person has been purged, the report lists each document deleted or scrubbed
+	//     schema:
+	//       type: object
+	//       properties:
+	//         uId:
+	//           type: string
+	//         explain:
+	//           type: boolean
+	//         deleted:
+	//           type: array
+	//           items:
+	//             type: object
+	//             properties:
+	//               index:
+	//                 type: string
+	//                 description: The concrete index the document was in
+	//               id:
+	//                 type: string
+	//               fields:
+	//                 type: array
+	//                 description: The fields removed from a scrubbed document
+	//                 items:
+	//                   type: string
+	//               statusCode:
+	//                 type: integer
+	//                 description: Set when the document could not be deleted or scrubbed
+	//               message:
+	//                 type: string
+	//         scrubbed:
+	//           type: array
+	//           items:
+	//             type: object
+	//             properties:
+	//               index:
+	//                 type: string
+	//                 description: The concrete index the document was in
+	//               id:
+	//                 type: string
+	//               fields:
+	//                 type: array
+	//                 description: The fields removed from a scrubbed document
+	//                 items:
+	//                   type: string
+	//               statusCode:
+	//                 type: integer
+	//                 description: Set when the document could not be deleted or scrubbed
+	//               message:
+	//                 type: string
+	//         failed:
+	//           type: integer
+	//           format: int64
+	//   '400':
+	//     description: Request failed validation
+	//     schema:
+	//       type: object
+	//       properties:
+	//         message:
+	//           type: string
+	//         errors:
+	//           type: array
+	//           items:
+	//             type: object
+	//             properties:
+	//               name:
+	//                 type: string
+	//               description:
+	//                 type: string
+	//   '404':
+	//     description: The person could not be found
+	//     schema:
+	//       type: object
+	//       properties:
+	//         message:
+	//           type: string
+	//   '500':
+	//     description: Some documents could not be purged, the report lists what was and was not
+	//     schema:
+	//       type: object
+	//       properties:
+	//         uId:
+	//           type: string
+	//         explain:
+	//           type: boolean
+	//         deleted:
+	//           type: array
+	//           items:
+	//             type: object
+	//             properties:
+	//               index:
+	//                 type: string
+	//                 description: The concrete index the document was in
+	//               id:
+	//                 type: string
+	//               fields:
+	//                 type: array
+	//                 description: The fields removed from a scrubbed document
+	//                 items:
+	//                   type: string
+	//               statusCode:
+	//                 type: integer
+	//                 description: Set when the document could not be deleted or scrubbed
+	//               message:
+	//                 type: string
+	//         scrubbed:
+	//           type: array
+	//           items:
+	//             type: object
+	//             properties:
+	//               index:
+	//                 type: string
+	//                 description: The concrete index the document was in
+	//               id:
+	//                 type: string
+	//               fields:
+	//                 type: array
+	//                 description: The fields removed from a scrubbed document
+	//                 items:
+	//                   type: string
+	//               statusCode:
+	//                 type: integer
+	//                 description: Set when the document could not be deleted or scrubbed
+	//               message:
+	//                 type: string
+	//         failed:
+	//           type: integer
+	//           format: int64
+	postRouter.Handle("/persons/purge", purge.NewHandler(l, purge.New(esClient)))
+
+	patchRouter := sm.Methods(http.MethodPatch).Subrouter()
+	patchRouter.Use(middleware.JwtVerify(secretsCache, l))
+	patchRouter.Use(middleware.ContentType())
+	patchRouter.Use(idempotent)
+
+	// swagger:operation PATCH /persons patch-persons
+	// Update some fields of one or many Persons
+	// ---
+	// consumes:
+	// - application/json
+	// produces:
+	// - application/json
+	// parameters:
+	// - in: "header"
+	//   name: "Idempotency-Key"
+	//   description: "Replays the original response when a request is retried with the same key, a key reused for a different request is rejected with 422"
+	//   required: false
+	//   type: string
+	// - in: "query"
+	//   name: "async"
+	//   description: "When true the items are written after responding, and the job can be polled with GET /jobs/{id}"
+	//   required: false
+	//   type: boolean
+	// - in: "body"
+	//   name: "body"
+	//   description: "Only the fields given are changed. Cases replace the case with the same uId or are added, addresses and phone numbers are added unless the person has them, other arrays and fields are replaced. Any other field of a person can be given."
+	//   required: true
+	//   schema:
+	//     type: object
+	//     properties:
+	//       persons:
+	//         type: array
+	//         items:
+	//           type: object
+	//           required:
+	//           - id
+	//           properties:
+	//             id:
+	//               type: integer
+	//               format: int64
+	//             surname:
+	//               type: string
+	//             addresses:
+	//               type: array
+	//               items:
+	//                 type: object
+	//                 properties:
+	//                   addressLines:
+	//                     type: array
+	//                     items:
+	//                       type: string
+	//                   postcode:
+	//                     type: string
+	//             phoneNumbers:
+	//               type: array
+	//               items:
+	//                 type: object
+	//                 properties:
+	//                   phoneNumber:
+	//                     type: string
+	//             cases:
+	//               type: array
+	//               items:
+	//                 type: object
+	//                 properties:
+	//                   uId:
+	//                     type: string
+	//                   caseRecNumber:
+	//                     type: string
+	//                   caseType:
+	//                     type: string
+	// responses:
+	//   '202':
+	//     description: The request has been handled and individual update responses are included in the response body, an item whose document is not indexed has statusCode 404
+	//     schema:
+	//       type: object
+	//       properties:
+	//         successful:
+	//           type: integer
+	//           format: int64
+	//         failed:
+	//           type: integer
+	//           format: int64
+	//         conflicts:
+	//           type: integer
+	//           format: int64
+	//         errors:
+	//           type: array
+	//           items:
+	//             type: string
+	//         results:
+	//           type: array
+	//           items:
+	//             type: object
+	//             properties:
+	//               id:
+	//                 type: string
+	//               index:
+	//                 type: string
+	//                 description: The concrete index the document was written to
+	//               statusCode:
+	//                 type: integer
+	//               message:
+	//                 type: string
+	//   '400':
+	//     description: Request failed validation
+	//     schema:
+	//       type: object
+	//       properties:
+	//         message:
+	//           type: string
+	//         errors:
+	//           type: array
+	//           items:
+	//             type: object
+	//             properties:
+	//               name:
+	//                 type: string
+	//               description:
+	//                 type: string
+	//   '500':
+	//     description: Unexpected error occurred
+	patchRouter.Handle("/persons", index.NewUpdateHandler(l, esClient, jobs, personIndices, person.ParsePartialIndexRequest))
+	// swagger:operation PATCH /digitalLpa patch-digital-lpa
+	// Update some fields of one or many digital LPAs
+	// ---
+	// consumes:
+	// - application/json
+	// produces:
+	// - application/json
+	// parameters:
+	// - in: "header"
+	//   name: "Idempotency-Key"
+	//   description: "Replays the original response when a request is retried with the same key, a key reused for a different request is rejected with 422"
+	//   required: false
+	//   type: string
+	// - in: "query"
+	//   name: "async"
+	//   description: "When true the items are written after responding, and the job can be polled with GET /jobs/{id}"
+	//   required: false
+	//   type: boolean
+	// - in: "body"
+	//   name: "body"
+	//   description: "Only the fields given are changed. Objects are merged field by field, attorneys are added unless the LPA has them, other fields are replaced."
+	//   required: true
+	//   schema:
+	//     type: object
+	//     properties:
+	//       digitalLpaApplications:
+	//         type: array
+	//         items:
+	//           type: object
+	//           required:
+	//           - uId
+	//           properties:
+	//             uId:
+	//               type: string
+	//             lpaType:
+	//               type: string
+	//             donor:
+	//               type: object
+	//             certificateProvider:
+	//               type: object
+	//             attorneys:
+	//               type: array
+	//               items:
+	//                 type: object
+	// responses:
+	//   '202':
+	//     description: The request has been handled and individual update responses are included in the response body, an item whose document is not indexed has statusCode 404
+	//     schema:
+	//       type: object
+	//       properties:
+	//         successful:
+	//           type: integer
+	//           format: int64
+	//         failed:
+	//           type: integer
+	//           format: int64
+	//         conflicts:
+	//           type: integer
+	//           format: int64
+	//         errors:
+	//           type: array
+	//           items:
+	//             type: string
+	//         results:
+	//           type: array
+	//           items:
+	//             type: object
+	//             properties:
+	//               id:
+	//                 type: string
+	//               index:
+	//                 type: string
+	//                 description: The concrete index the document was written to
+	//               statusCode:
+	//                 type: integer
+	//               message:
+	//                 type: string
+	//   '400':
+	//     description: Request failed validation
+	//     schema:
+	//       type: object
+	//       properties:
+	//         message:
+	//           type: string
+	//         errors:
+	//           type: array
+	//           items:
+	//             type: object
+	//             properties:
+	//               name:
+	//                 type: string
+	//               description:
+	//                 type: string
+	//   '500':
+	//     description: Unexpected error occurred
+	patchRouter.Handle("/digitalLpa", index.NewUpdateHandler(l, esClient, jobs, digitalLpaIndices, digitallpa.ParsePartialIndexRequest))
+	// swagger:operation PATCH /firms patch-firms
+	// Update some fields of one or many Firms
+	// ---
+	// consumes:
+	// - application/json
+	// produces:
+	// - application/json
+	// parameters:
+	// - in: "header"
+	//   name: "Idempotency-Key"
+	//   description: "Replays the original response when a request is retried with the same key, a key reused for a different request is rejected with 422"
+	//   required: false
+	//   type: string
+	// - in: "query"
+	//   name: "async"
+	//   description: "When true the items are written after responding, and the job can be polled with GET /jobs/{id}"
+	//   required: false
+	//   type: boolean
+	// - in: "body"
+	//   name: "body"
+	//   description: "Only the fields given are changed. Any other field of a firm can be given, fields are replaced."
+	//   required: true
+	//   schema:
+	//     type: object
+	//     properties:
+	//       firms:
+	//         type: array
+	//         items:
+	//           type: object
+	//           required:
+	//           - id
+	//           properties:
+	//             id:
+	//               type: integer
+	//               format: int64
+	//             firmName:
+	//               type: string
+	//             firmNumber:
+	//               type: string
+	//             email:
+	//               type: string
+	// responses:
+	//   '202':
+	//     description: The request has been handled and individual update responses are included in the response body, an item whose document is not indexed has statusCode 404
+	//     schema:
+	//       type: object
+	//       properties:
+	//         successful:
+	//           type: integer
+	//           format: int64
+	//         failed:
+	//           type: integer
+	//           format: int64
+	//         conflicts:
+	//           type: integer
+	//           format: int64
+	//         errors:
+	//           type: array
+	//           items:
+	//             type: string
+	//         results:
+	//           type: array
+	//           items:
+	//             type: object
+	//             properties:
+	//               id:
+	//                 type: string
+	//               index:
+	//                 type: string
+	//                 description: The concrete index the document was written to
+	//               statusCode:
+	//                 type: integer
+	//               message:
+	//                 type: string
+	//   '400':
+	//     description: Request failed validation
+	//     schema:
+	//       type: object
+	//       properties:
+	//         message:
+	//           type: string
+	//         errors:
+	//           type: array
+	//           items:
+	//             type: object
+	//             properties:
+	//               name:
+	//                 type: string
+	//               description:
+	//                 type: string
+	//   '500':
+	//     description: Unexpected error occurred
+	patchRouter.Handle("/firms", index.NewUpdateHandler(l, esClient, jobs, firmIndices, firm.ParsePartialIndexRequest))
+
+	getRouter := sm.Methods(http.MethodGet).Subrouter()
+	getRouter.Use(middleware.JwtVerify(secretsCache, l))
+	getRouter.Use(middleware.ContentType())
+
+	// swagger:operation GET /jobs/:id get-job
+	// Get the progress of an index, update or delete request made with async=true
+	// ---
+	// produces:
+	// - application/json
+	// parameters:
+	// - in: path
+	//   name: id
+	//   description: The id returned when the job was accepted
+	//   required: true
+	//   type: string
+	//   pattern: "^[0-9a-f]{32}$"
+	// responses:
+	//   '200':
+	//     description: The progress of the job, with the results of the items processed so far
+	//     schema:
+	//       type: object
+	//       properties:
+	//         id:
+	//           type: string
+	//         status:
+	//           type: string
+	//           enum:
+	//           - running
+	//           - completed
+	//           - failed
+	//           description: A job is failed when it was stopped before all its items were processed, because it ran for more than 30 minutes or hit an unexpected error
+	//         total:
+	//           type: integer
+	//           description: The number of writes, which is the number of items times the number of indices written to
+	//         processed:
+	//           type: integer
+	//         createdAt:
+	//           type: string
+	//           format: date-time
+	//         completedAt:
+	//           type: string
+	//           format: date-time
+	//         successful:
+	//           type: integer
+	//           format: int64
+	//         failed:
+	//           type: integer
+	//           format: int64
+	//         conflicts:
+	//           type: integer
+	//           format: int64
+	//         errors:
+	//           type: array
+	//           items:
+	//             type: string
+	//         results:
+	//           type: array
+	//           items:
+	//             type: object
+	//             properties:
+	//               id:
+	//                 type: string
+	//               index:
+	//                 type: string
+	//                 description: The concrete index the document was written to
+	//               statusCode:
+	//                 type: integer
+	//               message:
+	//                 type: string
+	//   '404':
+	//     description: The job could not be found, it finished more than an hour ago or was accepted by another instance of the service
+	//     schema:
+	//       type: object
+	//       properties:
+	//         message:
+	//           type: string
+	//   '500':
+	//     description: Unexpected error occurred
+	getRouter.Handle("/jobs/{id:[0-9a-f]{32}}", index.NewJobHandler(l, jobs))
+
+	deleteRouter := sm.Methods(http.MethodDelete).Subrouter()
+	deleteRouter.Use(middleware.JwtVerify(secretsCache, l))
+	deleteRouter.Use(middleware.ContentType())
+	deleteRouter.Use(idempotent)
+
+	// swagger:operation DELETE /persons/:uid delete-person
+	// Delete a person
+	// ---
+	// consumes:
+	// - application/json
+	// produces:
+	// - application/json
+	// parameters:
+	// - in: path
+	//   name: uid
+	//   description: ""
+	//   required: true
+	//   schema:
+	//     type: integer
+	//     format: string
+	//     pattern: "^\\d{4}-\\d{4}-\\d{4}$"
+	// - in: "header"
+	//   name: "Idempotency-Key"
+	//   description: "Replays the original response when a request is retried with the same key, a key reused for a different request is rejected with 422"
+	//   required: false
+	//   type: string
+	// responses:
+	//   '200':
+	//     description: The person has been deleted
+	//   '400':
+	//     description: The person could not be found
+	//     schema:
+	//       type: object
+	//       properties:
+	//         message:
+	//           type: string
+	//   '500':
+	//     description: Unexpected error occurred
+	deleteRouter.Handle("/persons/{uid:\\d{4}-\\d{4}-\\d{4}}", remove.NewHandler(l, esClient, []string{person.AliasName}, remove.Key{Param: "uid", Field: "uId"}))
+	// swagger:operation DELETE /firms/:id delete-firm
+	// Delete a firm
+	// ---
+	// consumes:
+	// - application/json
+	// produces:
+	// - application/json
+	// parameters:
+	// - in: path
+	//   name: id
+	//   description: ""
+	//   required: true
+	//   schema:
+	//     type: string
+	//     pattern: "^\\d+$"
+	// - in: "header"
+	//   name: "Idempotency-Key"
+	//   description: "Replays the original response when a request is retried with the same key, a key reused for a different request is rejected with 422"
+	//   required: false
+	//   type: string
+	// responses:
+	//   '200':
+	//     description: The firm has been deleted
+	//   '404':
+	//     description: The firm could not be found
+	//     schema:
+	//       type: object
+	//       properties:
+	//         message:
+	//           type: string
+	// '500':
+	//   description: Unexpected error occurred
+	deleteRouter.Handle("/firms/{id:\\d+}", remove.NewHandler(l, esClient, []string{firm.AliasName}, remove.Key{Param: "id", Field: "_id"}))
+	// swagger:operation DELETE /digitalLpa/:uid delete-digital-lpa
+	// Delete a digital LPA
+	// ---
+	// consumes:
+	// - application/json
+	// produces:
+	// - application/json
+	// parameters:
+	// - in: path
+	//   name: uid
+	//   description: ""
+	//   required: true
+	//   schema:
+	//     type: string
+	//     pattern: "^M(-[0-9A-Z]{4}){3}$"
+	// - in: "header"
+	//   name: "Idempotency-Key"
+	//   description: "Replays the original response when a request is retried with the same key, a key reused for a different request is rejected with 422"
+	//   required: false
+	//   type: string
+	// responses:
+	//   '200':
+	//     description: The digital LPA has been deleted
+	//   '404':
+	//     description: The digital LPA could not be found
+	//     schema:
+	//       type: object
+	//       properties:
+	//         message:
+	//           type: string
+	// '500':
+	//   description: Unexpected error occurred
+	deleteRouter.Handle("/digitalLpa/{uid:M(?:-[0-9A-Z]{4}){3}}", remove.NewHandler(l, esClient, []string{digitallpa.AliasName}, remove.Key{Param: "uid", Field: "_id"}))
 
 	w := l.Writer()
 	defer w.Close() //nolint:errcheck // no need to check error when closing logger
@@ -427,6 +427,83 @@ func (suite *EndToEndTestSuite) TestIndexAndSearchFirm() {
 	}
 }
 
+func (suite *EndToEndTestSuite) TestPatchPersonKeepsCases() {
+	patched := person.Person{
+		ID:         i64(100),
+		Firstname:  "Patch",
+		Surname:    "Casesonperson",
+		Persontype: "Type0",
+		Cases: []person.PersonCase{
+			{UID: "7000-0000-0101"},
+			{UID: "7000-0000-0102"},
+		},
+	}
+
+	resp, err := doRequest(suite.authHeader, "/persons", person.IndexRequest{Persons: []person.Person{patched}})
+	if err != nil {
+		suite.Fail("Error indexing person", err)
+	}
+	_ = resp.Body.Close()
+	suite.Equal(http.StatusAccepted, resp.StatusCode)
+
+	resp, err = doMethodRequest(http.MethodPatch, suite.authHeader, "/persons", map[string]interface{}{
+		"persons": []interface{}{
+			map[string]interface{}{"id": 100, "cases": []interface{}{map[string]string{"uId": "7000-0000-0103"}}},
+			map[string]interface{}{"id": 999999, "surname": "Missing"},
+		},
+	})
+	if err != nil {
+		suite.Fail("Error patching person", err)
+	}
+	defer resp.Body.Close() //nolint:errcheck // no need to check error when closing body
+
+	suite.Equal(http.StatusAccepted, resp.StatusCode)
+
+	var result struct {
+		Results []elasticsearch.IndexResult `json:"results"`
+	}
+	suite.Nil(json.NewDecoder(resp.Body).Decode(&result))
+	suite.NotEmpty(result.Results)
+	for _, item := range result.Results {
+		if item.Id == "999999" {
+			suite.Equal(http.StatusNotFound, item.StatusCode, item.Index)
+		} else {
+			suite.Equal(http.StatusOK, item.StatusCode, item.Index)
+		}
+	}
+
+	var uids []string
+
+	// wait up to 2s for the patched record to become searchable
+	for i := 0; i < 20; i++ {
+		resp, err := doRequest(suite.authHeader, "/persons/search", map[string]string{"term": patched.Surname})
+		if err != nil {
+			suite.Fail("Error searching for a person", err)
+		}
+
+		var found search.Response
+		_ = json.NewDecoder(resp.Body).Decode(&found)
+		_ = resp.Body.Close()
+
+		uids = nil
+		if len(found.Results) == 1 {
+			var p person.Person
+			_ = json.Unmarshal(found.Results[0], &p)
+			for _, c := range p.Cases {
+				uids = append(uids, c.UID)
+			}
+		}
+
+		if len(uids) == 3 {
+			break
+		}
+
+		time.Sleep(time.Millisecond * 100)
+	}
+
+	suite.Equal([]string{"7000-0000-0101", "7000-0000-0102", "7000-0000-0103"}, uids)
+}
+
 func TestEndToEnd(t *testing.T) {
 	if testing.Short() {
 		t.Skip("skipping end to end tests")
@@ -442,13 +519,17 @@ func i64(i int) *int64 {
 }
 
 func doRequest(authHeader, path string, data interface{}) (*http.Response, error) {
+	return doMethodRequest(http.MethodPost, authHeader, path, data)
+}
+
+func doMethodRequest(method, authHeader, path string, data interface{}) (*http.Response, error) {
 	var buf bytes.Buffer
 	err := json.NewEncoder(&buf).Encode(data)
 	if err != nil {
 		return nil, err
 	}
 
-	req, err := http.NewRequest(http.MethodPost, "http://localhost:8000"+os.Getenv("PATH_PREFIX")+path, &buf) //nolint:gosec // this test intentionally trusts the env var
+	req, err := http.NewRequest(method, "http://localhost:8000"+os.Getenv("PATH_PREFIX")+path, &buf) //nolint:gosec // this test intentionally trusts the env var
 	if err != nil {
 		return nil, err
 	}
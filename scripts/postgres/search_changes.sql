@@ -0,0 +1,118 @@
+-- Records changes to the records that are indexed, so that the follow command
+-- can index them, and creates the sequence that indexed persons are versioned
+-- from. Safe to run more than once.
+
+-- Sequence: public.search_version
+-- Persons read from the database take their version from this sequence, and
+-- Sirius sends nextval('public.search_version') as the version of a person it
+-- indexes, taken after its change is committed
+
+CREATE SEQUENCE IF NOT EXISTS public.search_version;
+
+-- Table: public.search_changes
+-- A row is deleted by the follow command once its change has been indexed
+
+CREATE TABLE IF NOT EXISTS public.search_changes
+(
+    id bigserial NOT NULL,
+    entity character varying(32) NOT NULL,
+    entity_id integer NOT NULL,
+    deleted boolean NOT NULL DEFAULT false,
+    created_at timestamp(0) without time zone NOT NULL DEFAULT now(),
+    CONSTRAINT search_changes_pkey PRIMARY KEY (id)
+)
+    WITH (
+        OIDS = FALSE
+    )
+    TABLESPACE pg_default;
+
+-- Queues a change to an entity and notifies the follow command of it
+
+CREATE OR REPLACE FUNCTION public.search_queue_change(changed_entity character varying, changed_id integer, is_deleted boolean) RETURNS void AS $$
+DECLARE
+    change_id bigint;
+BEGIN
+    INSERT INTO public.search_changes (entity, entity_id, deleted)
+    VALUES (changed_entity, changed_id, is_deleted)
+    RETURNING id INTO change_id;
+
+    PERFORM pg_notify('search_changes', change_id::text);
+END;
+$$ LANGUAGE plpgsql;
+
+-- TG_ARGV[0] is the entity that changed and TG_ARGV[1] the column holding its
+-- id. A row updated to belong to another entity changes both, and the old one
+-- is only deleted when it is the entity's own row.
+
+CREATE OR REPLACE FUNCTION public.search_record_change() RETURNS trigger AS $$
+DECLARE
+    old_id integer;
+    new_id integer;
+BEGIN
+    IF TG_OP <> 'INSERT' THEN
+        old_id := (to_jsonb(OLD) ->> TG_ARGV[1])::integer;
+    END IF;
+
+    IF TG_OP <> 'DELETE' THEN
+        new_id := (to_jsonb(NEW) ->> TG_ARGV[1])::integer;
+    END IF;
+
+    IF old_id IS NOT NULL AND old_id IS DISTINCT FROM new_id THEN
+        PERFORM public.search_queue_change(TG_ARGV[0], old_id, TG_ARGV[1] = 'id');
+    END IF;
+
+    IF new_id IS NOT NULL THEN
+        PERFORM public.search_queue_change(TG_ARGV[0], new_id, false);
+    END IF;
+
+    RETURN NULL;
+END;
+$$ LANGUAGE plpgsql;
+
+-- A case is indexed on each person linked to it by person_caseitem, so a
+-- change to the case changes each of those persons
+
+CREATE OR REPLACE FUNCTION public.search_record_case_change() RETURNS trigger AS $$
+DECLARE
+    changed_case_id integer;
+    linked_person_id integer;
+BEGIN
+    IF TG_OP = 'DELETE' THEN
+        changed_case_id := OLD.id;
+    ELSE
+        changed_case_id := NEW.id;
+    END IF;
+
+    FOR linked_person_id IN
+        SELECT pc.person_id FROM public.person_caseitem pc WHERE pc.caseitem_id = changed_case_id ORDER BY pc.person_id
+    LOOP
+        PERFORM public.search_queue_change('person', linked_person_id, false);
+    END LOOP;
+
+    RETURN NULL;
+END;
+$$ LANGUAGE plpgsql;
+
+DROP TRIGGER IF EXISTS persons_search_change ON public.persons;
+CREATE TRIGGER persons_search_change AFTER INSERT OR UPDATE OR DELETE ON public.persons
+    FOR EACH ROW EXECUTE PROCEDURE public.search_record_change('person', 'id');
+
+DROP TRIGGER IF EXISTS addresses_search_change ON public.addresses;
+CREATE TRIGGER addresses_search_change AFTER INSERT OR UPDATE OR DELETE ON public.addresses
+    FOR EACH ROW EXECUTE PROCEDURE public.search_record_change('person', 'person_id');
+
+DROP TRIGGER IF EXISTS phonenumbers_search_change ON public.phonenumbers;
+CREATE TRIGGER phonenumbers_search_change AFTER INSERT OR UPDATE OR DELETE ON public.phonenumbers
+    FOR EACH ROW EXECUTE PROCEDURE public.search_record_change('person', 'person_id');
+
+DROP TRIGGER IF EXISTS person_caseitem_search_change ON public.person_caseitem;
+CREATE TRIGGER person_caseitem_search_change AFTER INSERT OR UPDATE OR DELETE ON public.person_caseitem
+    FOR EACH ROW EXECUTE PROCEDURE public.search_record_change('person', 'person_id');
+
+DROP TRIGGER IF EXISTS cases_search_change ON public.cases;
+CREATE TRIGGER cases_search_change AFTER UPDATE OR DELETE ON public.cases
+    FOR EACH ROW EXECUTE PROCEDURE public.search_record_case_change();
+
+DROP TRIGGER IF EXISTS firm_search_change ON supervision.firm;
+CREATE TRIGGER firm_search_change AFTER INSERT OR UPDATE OR DELETE ON supervision.firm
+    FOR EACH ROW EXECUTE PROCEDURE public.search_record_change('firm', 'id');